@@ -20,8 +20,7 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	serverApp, err := server.New(cfg.LogLevel, cfg.ServerEndPoint, cfg.DataBaseEndPoint,
-		cfg.DefaultLimit, cfg.DefaultPage, cfg.DefaultVerse)
+	serverApp, err := server.New(cfg)
 	if err != nil {
 		panic(err)
 	}
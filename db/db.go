@@ -0,0 +1,10 @@
+// Package db embeds the SQL migrations so the service does not depend on its working directory.
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql
+var PostgresMigrations embed.FS
+
+//go:embed migrations_sqlite/*.sql
+var SQLiteMigrations embed.FS
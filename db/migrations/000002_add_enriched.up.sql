@@ -0,0 +1 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS enriched BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS song_verses;
+
+ALTER TABLE songs DROP COLUMN IF EXISTS verse_count;
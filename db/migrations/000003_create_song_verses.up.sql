@@ -0,0 +1,22 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS verse_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS song_verses(
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    PRIMARY KEY (song_id, position)
+);
+
+INSERT INTO song_verses(song_id, position, text)
+SELECT id, position, verse
+FROM (
+    SELECT s.id, v.verse, row_number() OVER (PARTITION BY s.id ORDER BY v.ord) AS position
+    FROM songs s,
+        regexp_split_to_table(
+            btrim(regexp_replace(replace(replace(coalesce(s.text, ''), E'\r\n', E'\n'), E'\r', E'\n'), E'[ \t]+(\n|$)', '\1', 'g'), E'\n'),
+            E'\n{2,}'
+        ) WITH ORDINALITY AS v(verse, ord)
+    WHERE v.verse <> ''
+) AS split;
+
+UPDATE songs SET verse_count = (SELECT count(*) FROM song_verses WHERE song_verses.song_id = songs.id);
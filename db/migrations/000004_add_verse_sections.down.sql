@@ -0,0 +1,2 @@
+ALTER TABLE song_verses DROP COLUMN IF EXISTS label;
+ALTER TABLE song_verses DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,36 @@
+ALTER TABLE song_verses ADD COLUMN IF NOT EXISTS kind VARCHAR(32) NOT NULL DEFAULT 'verse';
+ALTER TABLE song_verses ADD COLUMN IF NOT EXISTS label VARCHAR(255) NOT NULL DEFAULT '';
+
+-- verses starting with a "[Chorus]"-style line get it as their label
+UPDATE song_verses
+SET label = btrim(substr(btrim(split_part(text, E'\n', 1)), 2, length(btrim(split_part(text, E'\n', 1))) - 2)),
+    text = btrim(substr(text, length(split_part(text, E'\n', 1)) + 2), E'\n')
+WHERE btrim(split_part(text, E'\n', 1)) LIKE '[%]';
+
+UPDATE song_verses
+SET kind = CASE
+        WHEN lower(label) LIKE 'pre%chorus%' OR label LIKE 'Предприпев%' OR label LIKE 'предприпев%' THEN 'pre-chorus'
+        WHEN lower(label) LIKE 'chorus%' OR lower(label) LIKE 'refrain%' OR label LIKE 'Припев%' OR label LIKE 'припев%' THEN 'chorus'
+        WHEN lower(label) LIKE 'verse%' OR label LIKE 'Куплет%' OR label LIKE 'куплет%' THEN 'verse'
+        WHEN lower(label) LIKE 'bridge%' OR label LIKE 'Бридж%' OR label LIKE 'бридж%' THEN 'bridge'
+        WHEN lower(label) LIKE 'intro%' OR label LIKE 'Вступление%' OR label LIKE 'вступление%' THEN 'intro'
+        WHEN lower(label) LIKE 'outro%' OR label LIKE 'Концовка%' OR label LIKE 'концовка%' OR label LIKE 'Аутро%' OR label LIKE 'аутро%' THEN 'outro'
+        WHEN lower(label) LIKE 'hook%' THEN 'hook'
+        ELSE 'other'
+    END
+WHERE label <> '';
+
+-- a header alone in its block leaves an empty verse behind
+DELETE FROM song_verses WHERE text = '';
+
+UPDATE song_verses
+SET position = -renumbered.position
+FROM (
+    SELECT song_id, position AS old_position, row_number() OVER (PARTITION BY song_id ORDER BY position) AS position
+    FROM song_verses
+) AS renumbered
+WHERE song_verses.song_id = renumbered.song_id AND song_verses.position = renumbered.old_position;
+
+UPDATE song_verses SET position = -position;
+
+UPDATE songs SET verse_count = (SELECT count(*) FROM song_verses WHERE song_verses.song_id = songs.id);
@@ -0,0 +1,7 @@
+ALTER TABLE songs ALTER COLUMN release_date TYPE TEXT USING to_char(release_date, 'YYYY-MM-DD');
+
+UPDATE songs SET release_date = raw.release_date
+FROM songs_release_date_raw raw
+WHERE raw.song_id = songs.id AND songs.release_date IS NULL;
+
+DROP TABLE IF EXISTS songs_release_date_raw;
@@ -0,0 +1,27 @@
+CREATE FUNCTION pg_temp.parse_release_date(value TEXT) RETURNS DATE AS $$
+BEGIN
+    IF value ~ '^\d{4}-\d{2}-\d{2}$' THEN
+        RETURN to_date(value, 'YYYY-MM-DD');
+    END IF;
+    IF value ~ '^\d{2}\.\d{2}\.\d{4}$' THEN
+        RETURN to_date(value, 'DD.MM.YYYY');
+    END IF;
+    RETURN NULL;
+EXCEPTION WHEN OTHERS THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- release dates that are not dates are cleared by the conversion, their text is kept here so it can be fixed by hand.
+-- There is no foreign key, so the rows outlive a rebuild of songs.
+CREATE TABLE IF NOT EXISTS songs_release_date_raw(
+    song_id INTEGER PRIMARY KEY,
+    release_date TEXT NOT NULL
+);
+
+INSERT INTO songs_release_date_raw (song_id, release_date)
+SELECT id, release_date FROM songs
+WHERE btrim(release_date) <> '' AND pg_temp.parse_release_date(btrim(release_date)) IS NULL
+ON CONFLICT (song_id) DO NOTHING;
+
+ALTER TABLE songs ALTER COLUMN release_date TYPE DATE USING pg_temp.parse_release_date(btrim(release_date));
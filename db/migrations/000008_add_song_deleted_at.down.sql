@@ -0,0 +1,6 @@
+DELETE FROM songs WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_songs_deleted_at;
+DROP INDEX IF EXISTS idx_songs_live_group_song;
+ALTER TABLE songs ADD CONSTRAINT songs_group_name_song_key UNIQUE (group_name, song);
+ALTER TABLE songs DROP COLUMN IF EXISTS deleted_at;
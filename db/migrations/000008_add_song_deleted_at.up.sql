@@ -0,0 +1,6 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- a song in the trash does not take its name, restoring it checks the name is still free
+ALTER TABLE songs DROP CONSTRAINT IF EXISTS songs_group_name_song_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_live_group_song ON songs(group_name, song) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_songs_deleted_at ON songs(deleted_at) WHERE deleted_at IS NOT NULL;
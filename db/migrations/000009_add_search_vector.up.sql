@@ -0,0 +1,17 @@
+-- lyrics can be in either language, so every column is indexed with both configurations.
+-- Weights follow importance: title A, group B, lyrics C.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(song, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(song, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(group_name, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(group_name, '')), 'B') ||
+    setweight(to_tsvector('russian', coalesce(text, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(text, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
+
+-- verses are only searched within the songs that matched, the primary key narrows them down
+ALTER TABLE song_verses ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('russian', text) || to_tsvector('english', text)
+) STORED;
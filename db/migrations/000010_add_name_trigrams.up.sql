@@ -0,0 +1,5 @@
+-- trigram indexes serve both fuzzy (%) and case-insensitive prefix (ILIKE) matches of the names
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_songs_group_name_trgm ON songs USING GIN (group_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_songs_song_trgm ON songs USING GIN (song gin_trgm_ops);
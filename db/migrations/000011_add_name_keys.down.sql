@@ -0,0 +1,20 @@
+DROP INDEX IF EXISTS idx_songs_search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE songs ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(song, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(song, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(group_name, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(group_name, '')), 'B') ||
+    setweight(to_tsvector('russian', coalesce(text, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(text, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
+
+DROP INDEX IF EXISTS idx_songs_song_key_trgm;
+DROP INDEX IF EXISTS idx_songs_group_key_trgm;
+CREATE INDEX IF NOT EXISTS idx_songs_group_name_trgm ON songs USING GIN (group_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_songs_song_trgm ON songs USING GIN (song gin_trgm_ops);
+
+DROP INDEX IF EXISTS idx_songs_keys;
+ALTER TABLE songs DROP COLUMN IF EXISTS song_key;
+ALTER TABLE songs DROP COLUMN IF EXISTS group_key;
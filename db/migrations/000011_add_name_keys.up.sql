@@ -0,0 +1,49 @@
+-- group and song are looked up by keys: the names with Cyrillic letters transliterated, so "Кино" finds "Kino".
+-- The application maintains the keys, see nameKey, the backfill below applies the same table in SQL.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS group_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS song_key TEXT NOT NULL DEFAULT '';
+
+UPDATE songs SET group_key = group_name, song_key = song;
+UPDATE songs SET group_key = replace(group_key, 'ж', 'zh'), song_key = replace(song_key, 'ж', 'zh');
+UPDATE songs SET group_key = replace(group_key, 'х', 'kh'), song_key = replace(song_key, 'х', 'kh');
+UPDATE songs SET group_key = replace(group_key, 'ц', 'ts'), song_key = replace(song_key, 'ц', 'ts');
+UPDATE songs SET group_key = replace(group_key, 'ч', 'ch'), song_key = replace(song_key, 'ч', 'ch');
+UPDATE songs SET group_key = replace(group_key, 'ш', 'sh'), song_key = replace(song_key, 'ш', 'sh');
+UPDATE songs SET group_key = replace(group_key, 'щ', 'shch'), song_key = replace(song_key, 'щ', 'shch');
+UPDATE songs SET group_key = replace(group_key, 'ю', 'yu'), song_key = replace(song_key, 'ю', 'yu');
+UPDATE songs SET group_key = replace(group_key, 'я', 'ya'), song_key = replace(song_key, 'я', 'ya');
+UPDATE songs SET group_key = replace(group_key, 'Ж', 'Zh'), song_key = replace(song_key, 'Ж', 'Zh');
+UPDATE songs SET group_key = replace(group_key, 'Х', 'Kh'), song_key = replace(song_key, 'Х', 'Kh');
+UPDATE songs SET group_key = replace(group_key, 'Ц', 'Ts'), song_key = replace(song_key, 'Ц', 'Ts');
+UPDATE songs SET group_key = replace(group_key, 'Ч', 'Ch'), song_key = replace(song_key, 'Ч', 'Ch');
+UPDATE songs SET group_key = replace(group_key, 'Ш', 'Sh'), song_key = replace(song_key, 'Ш', 'Sh');
+UPDATE songs SET group_key = replace(group_key, 'Щ', 'Shch'), song_key = replace(song_key, 'Щ', 'Shch');
+UPDATE songs SET group_key = replace(group_key, 'Ю', 'Yu'), song_key = replace(song_key, 'Ю', 'Yu');
+UPDATE songs SET group_key = replace(group_key, 'Я', 'Ya'), song_key = replace(song_key, 'Я', 'Ya');
+-- the single letters at once, translate drops the signs that have no counterpart
+UPDATE songs SET
+    group_key = translate(group_key, 'абвгдеёзийклмнопрстуфыэАБВГДЕЁЗИЙКЛМНОПРСТУФЫЭъьЪЬ', 'abvgdeeziyklmnoprstufyeABVGDEEZIYKLMNOPRSTUFYE'),
+    song_key = translate(song_key, 'абвгдеёзийклмнопрстуфыэАБВГДЕЁЗИЙКЛМНОПРСТУФЫЭъьЪЬ', 'abvgdeeziyklmnoprstufyeABVGDEEZIYKLMNOPRSTUFYE');
+
+CREATE INDEX IF NOT EXISTS idx_songs_keys ON songs (group_key, song_key) WHERE deleted_at IS NULL;
+
+-- prefix and fuzzy matches go through the keys now
+DROP INDEX IF EXISTS idx_songs_song_trgm;
+DROP INDEX IF EXISTS idx_songs_group_name_trgm;
+CREATE INDEX IF NOT EXISTS idx_songs_group_key_trgm ON songs USING GIN (group_key gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_songs_song_key_trgm ON songs USING GIN (song_key gin_trgm_ops);
+
+-- the full-text search finds titles and groups by their keys as well, a generated column can only be redefined by adding it again
+DROP INDEX IF EXISTS idx_songs_search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE songs ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(song, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(song, '')), 'A') ||
+    setweight(to_tsvector('simple', song_key), 'A') ||
+    setweight(to_tsvector('russian', coalesce(group_name, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(group_name, '')), 'B') ||
+    setweight(to_tsvector('simple', group_key), 'B') ||
+    setweight(to_tsvector('russian', coalesce(text, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(text, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
@@ -0,0 +1 @@
+DROP COLLATION IF EXISTS songs_ru;
@@ -0,0 +1,2 @@
+-- names are sorted by the rules of Russian whatever the locale of the database is
+CREATE COLLATION IF NOT EXISTS songs_ru (provider = icu, locale = 'ru-RU');
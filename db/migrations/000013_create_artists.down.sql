@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_songs_artist_id;
+ALTER TABLE song_revisions DROP COLUMN IF EXISTS artist_id;
+ALTER TABLE songs DROP COLUMN IF EXISTS artist_id;
+DROP TABLE IF EXISTS artists;
@@ -0,0 +1,22 @@
+-- groups become artists songs refer to, one per distinct group name.
+-- group_name and group_key stay on songs as copies of the artist name: the search vector, the name keys
+-- and the uniqueness of live songs are built on them. The application keeps them in sync when an artist
+-- is renamed or merged.
+CREATE TABLE IF NOT EXISTS artists(
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE
+);
+
+INSERT INTO artists (name)
+SELECT DISTINCT group_name FROM songs ORDER BY group_name
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS artist_id INTEGER REFERENCES artists(id);
+UPDATE songs SET artist_id = artists.id FROM artists WHERE artists.name = songs.group_name;
+ALTER TABLE songs ALTER COLUMN artist_id SET NOT NULL;
+
+-- revisions keep the artist the song had, it may since have been merged away so there is no reference
+ALTER TABLE song_revisions ADD COLUMN IF NOT EXISTS artist_id INTEGER;
+UPDATE song_revisions SET artist_id = artists.id FROM artists WHERE artists.name = song_revisions.group_name;
+
+CREATE INDEX IF NOT EXISTS idx_songs_artist_id ON songs(artist_id);
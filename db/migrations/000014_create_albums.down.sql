@@ -0,0 +1,6 @@
+ALTER TABLE song_revisions DROP COLUMN IF EXISTS track_number;
+ALTER TABLE song_revisions DROP COLUMN IF EXISTS album_id;
+DROP INDEX IF EXISTS idx_songs_album_id;
+ALTER TABLE songs DROP COLUMN IF EXISTS track_number;
+ALTER TABLE songs DROP COLUMN IF EXISTS album_id;
+DROP TABLE IF EXISTS albums;
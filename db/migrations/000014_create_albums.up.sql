@@ -0,0 +1,17 @@
+-- albums of an artist, songs may belong to one under a track number
+CREATE TABLE IF NOT EXISTS albums(
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    artist_id INTEGER NOT NULL REFERENCES artists(id),
+    release_date DATE,
+    cover_link TEXT NOT NULL DEFAULT '',
+    UNIQUE (artist_id, title)
+);
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS album_id INTEGER REFERENCES albums(id);
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS track_number INTEGER;
+CREATE INDEX IF NOT EXISTS idx_songs_album_id ON songs(album_id);
+
+-- like artist_id, revisions keep the album of the song without a reference, the album may be deleted since
+ALTER TABLE song_revisions ADD COLUMN IF NOT EXISTS album_id INTEGER;
+ALTER TABLE song_revisions ADD COLUMN IF NOT EXISTS track_number INTEGER;
@@ -0,0 +1,5 @@
+ALTER TABLE song_revisions DROP COLUMN IF EXISTS cover_of;
+DROP INDEX IF EXISTS idx_songs_cover_of;
+ALTER TABLE songs DROP COLUMN IF EXISTS cover_of;
+DROP INDEX IF EXISTS idx_song_artists_artist_id;
+DROP TABLE IF EXISTS song_artists;
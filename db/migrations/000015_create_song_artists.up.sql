@@ -0,0 +1,13 @@
+-- artists credited on a song besides its group, under a role like featuring or producer
+CREATE TABLE IF NOT EXISTS song_artists(
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    artist_id INTEGER NOT NULL REFERENCES artists(id),
+    role VARCHAR(32) NOT NULL,
+    PRIMARY KEY (song_id, artist_id, role)
+);
+CREATE INDEX IF NOT EXISTS idx_song_artists_artist_id ON song_artists(artist_id);
+
+-- a cover version refers to its original song
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS cover_of INTEGER REFERENCES songs(id);
+CREATE INDEX IF NOT EXISTS idx_songs_cover_of ON songs(cover_of);
+ALTER TABLE song_revisions ADD COLUMN IF NOT EXISTS cover_of INTEGER;
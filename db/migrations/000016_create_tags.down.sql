@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_song_tags_tag_id;
+DROP TABLE IF EXISTS song_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,12 @@
+-- tags label songs with genres, moods or anything else; names are stored lower case
+CREATE TABLE IF NOT EXISTS tags(
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS song_tags(
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_song_tags_tag_id ON song_tags(tag_id);
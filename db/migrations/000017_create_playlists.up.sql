@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS playlists(
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- a song may be in a playlist more than once, entries are ordered by position then id.
+-- positions only order the entries and may have gaps: the positions the API shows are counted
+-- over the entries of songs not in the trash. Purging a song removes its entries.
+CREATE TABLE IF NOT EXISTS playlist_entries(
+    id SERIAL PRIMARY KEY,
+    playlist_id INTEGER NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_playlist_entries_playlist_position ON playlist_entries(playlist_id, position);
+CREATE INDEX IF NOT EXISTS idx_playlist_entries_song_id ON playlist_entries(song_id);
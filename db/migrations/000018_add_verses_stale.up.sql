@@ -0,0 +1,6 @@
+-- 000004 only parsed a "[Label]" header on the first line of a verse and dropped header-only verses,
+-- while the service starts a section at any header line and carries a lone header onto the next block.
+-- verses_stale marks every existing song so the service splits its lyrics again with its own rules on startup
+-- and clears the flag, which brings songs stored before and after section parsing in line.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS verses_stale BOOLEAN NOT NULL DEFAULT false;
+UPDATE songs SET verses_stale = true;
@@ -0,0 +1,6 @@
+-- songs_release_date_raw is created by 000005 since it started keeping the release dates it could not convert,
+-- databases migrated past 000005 before that get the table empty so every database has the same schema.
+CREATE TABLE IF NOT EXISTS songs_release_date_raw(
+    song_id INTEGER PRIMARY KEY,
+    release_date TEXT NOT NULL
+);
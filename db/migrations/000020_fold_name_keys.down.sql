@@ -0,0 +1,22 @@
+-- the keys are built again as migration 000011 did, keeping the case of the names
+UPDATE songs SET group_key = group_name, song_key = song;
+UPDATE songs SET group_key = replace(group_key, 'ж', 'zh'), song_key = replace(song_key, 'ж', 'zh');
+UPDATE songs SET group_key = replace(group_key, 'х', 'kh'), song_key = replace(song_key, 'х', 'kh');
+UPDATE songs SET group_key = replace(group_key, 'ц', 'ts'), song_key = replace(song_key, 'ц', 'ts');
+UPDATE songs SET group_key = replace(group_key, 'ч', 'ch'), song_key = replace(song_key, 'ч', 'ch');
+UPDATE songs SET group_key = replace(group_key, 'ш', 'sh'), song_key = replace(song_key, 'ш', 'sh');
+UPDATE songs SET group_key = replace(group_key, 'щ', 'shch'), song_key = replace(song_key, 'щ', 'shch');
+UPDATE songs SET group_key = replace(group_key, 'ю', 'yu'), song_key = replace(song_key, 'ю', 'yu');
+UPDATE songs SET group_key = replace(group_key, 'я', 'ya'), song_key = replace(song_key, 'я', 'ya');
+UPDATE songs SET group_key = replace(group_key, 'Ж', 'Zh'), song_key = replace(song_key, 'Ж', 'Zh');
+UPDATE songs SET group_key = replace(group_key, 'Х', 'Kh'), song_key = replace(song_key, 'Х', 'Kh');
+UPDATE songs SET group_key = replace(group_key, 'Ц', 'Ts'), song_key = replace(song_key, 'Ц', 'Ts');
+UPDATE songs SET group_key = replace(group_key, 'Ч', 'Ch'), song_key = replace(song_key, 'Ч', 'Ch');
+UPDATE songs SET group_key = replace(group_key, 'Ш', 'Sh'), song_key = replace(song_key, 'Ш', 'Sh');
+UPDATE songs SET group_key = replace(group_key, 'Щ', 'Shch'), song_key = replace(song_key, 'Щ', 'Shch');
+UPDATE songs SET group_key = replace(group_key, 'Ю', 'Yu'), song_key = replace(song_key, 'Ю', 'Yu');
+UPDATE songs SET group_key = replace(group_key, 'Я', 'Ya'), song_key = replace(song_key, 'Я', 'Ya');
+-- the single letters at once, translate drops the signs that have no counterpart
+UPDATE songs SET
+    group_key = translate(group_key, 'абвгдеёзийклмнопрстуфыэАБВГДЕЁЗИЙКЛМНОПРСТУФЫЭъьЪЬ', 'abvgdeeziyklmnoprstufyeABVGDEEZIYKLMNOPRSTUFYE'),
+    song_key = translate(song_key, 'абвгдеёзийклмнопрстуфыэАБВГДЕЁЗИЙКЛМНОПРСТУФЫЭъьЪЬ', 'abvgdeeziyklmnoprstufyeABVGDEEZIYKLMNOPRSTUFYE');
@@ -0,0 +1,3 @@
+-- name keys ignore case since nameKey lower-cases them, so "kino" finds "Kino" in every match mode.
+-- The search vector is generated from the keys and follows on its own.
+UPDATE songs SET group_key = lower(group_key), song_key = lower(song_key);
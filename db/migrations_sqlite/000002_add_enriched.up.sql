@@ -0,0 +1 @@
+ALTER TABLE songs ADD COLUMN enriched BOOLEAN NOT NULL DEFAULT FALSE;
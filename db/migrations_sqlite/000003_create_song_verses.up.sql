@@ -0,0 +1,38 @@
+ALTER TABLE songs ADD COLUMN verse_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS song_verses(
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    PRIMARY KEY (song_id, position)
+);
+
+WITH RECURSIVE
+stripped(id, body) AS (
+    SELECT id, replace(replace(coalesce(text, ''), char(13) || char(10), char(10)), char(13), char(10)) || char(10)
+    FROM songs
+    UNION ALL
+    SELECT id, replace(replace(body, ' ' || char(10), char(10)), char(9) || char(10), char(10))
+    FROM stripped
+    WHERE instr(body, ' ' || char(10)) > 0 OR instr(body, char(9) || char(10)) > 0
+),
+normalized(id, rest) AS (
+    SELECT id, trim(body, char(10)) || char(10) || char(10)
+    FROM stripped
+    WHERE instr(body, ' ' || char(10)) = 0 AND instr(body, char(9) || char(10)) = 0
+),
+split(id, ord, verse, rest) AS (
+    SELECT id, 0, NULL, rest FROM normalized
+    UNION ALL
+    SELECT id, ord + 1,
+        trim(substr(rest, 1, instr(rest, char(10) || char(10)) - 1), char(10)),
+        substr(rest, instr(rest, char(10) || char(10)) + 2)
+    FROM split
+    WHERE rest <> '' AND instr(rest, char(10) || char(10)) > 0
+)
+INSERT INTO song_verses(song_id, position, text)
+SELECT id, row_number() OVER (PARTITION BY id ORDER BY ord), verse
+FROM split
+WHERE verse IS NOT NULL AND verse <> '';
+
+UPDATE songs SET verse_count = (SELECT count(*) FROM song_verses WHERE song_verses.song_id = songs.id);
@@ -0,0 +1,2 @@
+ALTER TABLE song_verses DROP COLUMN label;
+ALTER TABLE song_verses DROP COLUMN kind;
@@ -0,0 +1,6 @@
+-- release dates stay in YYYY-MM-DD text, only the cleared ones are put back
+UPDATE songs
+SET release_date = (SELECT release_date FROM songs_release_date_raw WHERE song_id = songs.id)
+WHERE release_date IS NULL AND id IN (SELECT song_id FROM songs_release_date_raw);
+
+DROP TABLE IF EXISTS songs_release_date_raw;
@@ -0,0 +1,25 @@
+-- SQLite has no DATE type, release dates are kept as YYYY-MM-DD text which sorts chronologically
+
+-- release dates that are not dates are cleared below, their text is kept here, see the Postgres migration.
+CREATE TABLE IF NOT EXISTS songs_release_date_raw(
+    song_id INTEGER PRIMARY KEY,
+    release_date TEXT NOT NULL
+);
+
+INSERT OR IGNORE INTO songs_release_date_raw (song_id, release_date)
+SELECT id, release_date FROM songs WHERE trim(release_date) <> '';
+
+UPDATE songs SET release_date = trim(release_date);
+
+UPDATE songs
+SET release_date = substr(release_date, 7, 4) || '-' || substr(release_date, 4, 2) || '-' || substr(release_date, 1, 2)
+WHERE release_date GLOB '[0-9][0-9].[0-9][0-9].[0-9][0-9][0-9][0-9]';
+
+UPDATE songs
+SET release_date = NULL
+WHERE release_date IS NOT NULL
+    AND (release_date NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]' OR date(release_date, '+0 days') IS NOT release_date);
+
+-- only the dates that were cleared are kept
+DELETE FROM songs_release_date_raw
+WHERE song_id NOT IN (SELECT id FROM songs WHERE release_date IS NULL);
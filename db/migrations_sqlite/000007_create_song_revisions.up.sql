@@ -0,0 +1,22 @@
+-- the history outlives the song, so there is no foreign key to songs
+CREATE TABLE IF NOT EXISTS song_revisions(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    operation VARCHAR(16) NOT NULL,
+    group_name VARCHAR(255) NOT NULL,
+    song VARCHAR(255) NOT NULL,
+    release_date TEXT,
+    text TEXT,
+    link VARCHAR(255) NOT NULL,
+    enriched BOOLEAN NOT NULL,
+    verse_count INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (song_id, revision)
+);
+
+-- existing songs start their history with their current state
+INSERT INTO song_revisions (song_id, revision, operation, group_name, song, release_date, text, link, enriched, verse_count, version)
+SELECT id, 1, 'create', group_name, song, release_date, text, link, enriched, verse_count, version FROM songs;
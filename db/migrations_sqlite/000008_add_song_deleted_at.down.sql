@@ -0,0 +1,7 @@
+DELETE FROM songs WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_songs_deleted_at;
+DROP INDEX IF EXISTS idx_songs_live_group_song;
+ALTER TABLE songs DROP COLUMN deleted_at;
+-- equivalent to the UNIQUE (group_name, song) constraint the table was created with
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_group_song ON songs(group_name, song);
@@ -0,0 +1,43 @@
+-- SQLite cannot drop the UNIQUE (group_name, song) constraint in place, so songs is rebuilt.
+-- song_verses is rebuilt along with it: dropping the old songs table would otherwise cascade to the verses.
+CREATE TABLE songs_new(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_name VARCHAR(255) NOT NULL,
+    song    VARCHAR(255) NOT NULL,
+    release_date TEXT,
+    text TEXT,
+    link VARCHAR(255) NOT NULL,
+    enriched BOOLEAN NOT NULL DEFAULT FALSE,
+    verse_count INTEGER NOT NULL DEFAULT 0,
+    version INTEGER NOT NULL DEFAULT 1,
+    deleted_at DATETIME
+);
+INSERT INTO songs_new (id, group_name, song, release_date, text, link, enriched, verse_count, version)
+SELECT id, group_name, song, release_date, text, link, enriched, verse_count, version FROM songs;
+-- keep ids of deleted songs from being handed out again, their revisions are still around
+UPDATE sqlite_sequence SET seq = (SELECT seq FROM sqlite_sequence WHERE name = 'songs') WHERE name = 'songs_new';
+
+CREATE TABLE song_verses_new(
+    song_id INTEGER NOT NULL REFERENCES songs_new(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    kind VARCHAR(32) NOT NULL DEFAULT 'verse',
+    label VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (song_id, position)
+);
+INSERT INTO song_verses_new (song_id, position, text, kind, label)
+SELECT song_id, position, text, kind, label FROM song_verses;
+
+DROP TABLE song_verses;
+DROP TABLE songs;
+-- renaming also points the song_verses_new foreign key at songs
+ALTER TABLE songs_new RENAME TO songs;
+ALTER TABLE song_verses_new RENAME TO song_verses;
+
+CREATE INDEX IF NOT EXISTS idx_group ON songs(group_name);
+CREATE INDEX IF NOT EXISTS idx_song ON songs(song);
+CREATE INDEX IF NOT EXISTS idx_release_date ON songs(release_date);
+
+-- a song in the trash does not take its name, restoring it checks the name is still free
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_live_group_song ON songs(group_name, song) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_songs_deleted_at ON songs(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS verse_search_delete;
+DROP TRIGGER IF EXISTS verse_search_insert;
+DROP TRIGGER IF EXISTS song_search_delete;
+DROP TRIGGER IF EXISTS song_search_update;
+DROP TRIGGER IF EXISTS song_search_insert;
+DROP TABLE IF EXISTS verse_search;
+DROP TABLE IF EXISTS song_search;
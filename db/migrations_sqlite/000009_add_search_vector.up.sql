@@ -0,0 +1,26 @@
+-- SQLite has no tsvector, FTS5 tables kept in sync by triggers serve the same purpose.
+-- rowid of song_search is the song id.
+CREATE VIRTUAL TABLE song_search USING fts5(group_name, song, text, tokenize = 'porter unicode61 remove_diacritics 2');
+CREATE VIRTUAL TABLE verse_search USING fts5(song_id UNINDEXED, position UNINDEXED, text, tokenize = 'porter unicode61 remove_diacritics 2');
+
+INSERT INTO song_search (rowid, group_name, song, text)
+SELECT id, group_name, song, coalesce(text, '') FROM songs;
+INSERT INTO verse_search (song_id, position, text)
+SELECT song_id, position, text FROM song_verses;
+
+CREATE TRIGGER song_search_insert AFTER INSERT ON songs BEGIN
+    INSERT INTO song_search (rowid, group_name, song, text) VALUES (new.id, new.group_name, new.song, coalesce(new.text, ''));
+END;
+CREATE TRIGGER song_search_update AFTER UPDATE OF group_name, song, text ON songs BEGIN
+    UPDATE song_search SET group_name = new.group_name, song = new.song, text = coalesce(new.text, '') WHERE rowid = new.id;
+END;
+CREATE TRIGGER song_search_delete AFTER DELETE ON songs BEGIN
+    DELETE FROM song_search WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER verse_search_insert AFTER INSERT ON song_verses BEGIN
+    INSERT INTO verse_search (song_id, position, text) VALUES (new.song_id, new.position, new.text);
+END;
+CREATE TRIGGER verse_search_delete AFTER DELETE ON song_verses BEGIN
+    DELETE FROM verse_search WHERE song_id = old.song_id AND position = old.position;
+END;
@@ -0,0 +1,3 @@
+-- SQLite has no trigram support, prefix and fuzzy name matches are done in process.
+-- The migration is kept so both migration sets share their numbering.
+SELECT 1;
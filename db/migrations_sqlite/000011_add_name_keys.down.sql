@@ -0,0 +1,14 @@
+DROP TRIGGER IF EXISTS song_search_insert;
+DROP TRIGGER IF EXISTS song_search_update;
+CREATE TRIGGER song_search_insert AFTER INSERT ON songs BEGIN
+    INSERT INTO song_search (rowid, group_name, song, text) VALUES (new.id, new.group_name, new.song, coalesce(new.text, ''));
+END;
+CREATE TRIGGER song_search_update AFTER UPDATE OF group_name, song, text ON songs BEGIN
+    UPDATE song_search SET group_name = new.group_name, song = new.song, text = coalesce(new.text, '') WHERE rowid = new.id;
+END;
+UPDATE song_search SET group_name = (SELECT group_name FROM songs WHERE id = song_search.rowid),
+    song = (SELECT song FROM songs WHERE id = song_search.rowid);
+
+DROP INDEX IF EXISTS idx_songs_keys;
+ALTER TABLE songs DROP COLUMN song_key;
+ALTER TABLE songs DROP COLUMN group_key;
@@ -0,0 +1,3 @@
+-- SQLite sorts names by the songs_collation_key function the application registers with the driver.
+-- The migration is kept so both migration sets share their numbering.
+SELECT 1;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_songs_artist_id;
+ALTER TABLE song_revisions DROP COLUMN artist_id;
+ALTER TABLE songs DROP COLUMN artist_id;
+DROP TABLE IF EXISTS artists;
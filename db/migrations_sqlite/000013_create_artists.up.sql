@@ -0,0 +1,18 @@
+-- groups become artists songs refer to, one per distinct group name.
+-- group_name and group_key stay on songs as copies of the artist name, see the Postgres migration.
+CREATE TABLE IF NOT EXISTS artists(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL UNIQUE
+);
+
+INSERT OR IGNORE INTO artists (name)
+SELECT DISTINCT group_name FROM songs ORDER BY group_name;
+
+-- SQLite cannot add a NOT NULL column without a default, the application always sets it
+ALTER TABLE songs ADD COLUMN artist_id INTEGER REFERENCES artists(id);
+UPDATE songs SET artist_id = (SELECT id FROM artists WHERE artists.name = songs.group_name);
+
+ALTER TABLE song_revisions ADD COLUMN artist_id INTEGER;
+UPDATE song_revisions SET artist_id = (SELECT id FROM artists WHERE artists.name = song_revisions.group_name);
+
+CREATE INDEX IF NOT EXISTS idx_songs_artist_id ON songs(artist_id);
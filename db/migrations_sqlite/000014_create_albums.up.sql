@@ -0,0 +1,16 @@
+-- albums of an artist, songs may belong to one under a track number, see the Postgres migration.
+CREATE TABLE IF NOT EXISTS albums(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(255) NOT NULL,
+    artist_id INTEGER NOT NULL REFERENCES artists(id),
+    release_date DATE,
+    cover_link TEXT NOT NULL DEFAULT '',
+    UNIQUE (artist_id, title)
+);
+
+ALTER TABLE songs ADD COLUMN album_id INTEGER REFERENCES albums(id);
+ALTER TABLE songs ADD COLUMN track_number INTEGER;
+CREATE INDEX IF NOT EXISTS idx_songs_album_id ON songs(album_id);
+
+ALTER TABLE song_revisions ADD COLUMN album_id INTEGER;
+ALTER TABLE song_revisions ADD COLUMN track_number INTEGER;
@@ -0,0 +1,12 @@
+-- artists credited on a song besides its group, see the Postgres migration.
+CREATE TABLE IF NOT EXISTS song_artists(
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    artist_id INTEGER NOT NULL REFERENCES artists(id),
+    role VARCHAR(32) NOT NULL,
+    PRIMARY KEY (song_id, artist_id, role)
+);
+CREATE INDEX IF NOT EXISTS idx_song_artists_artist_id ON song_artists(artist_id);
+
+ALTER TABLE songs ADD COLUMN cover_of INTEGER REFERENCES songs(id);
+CREATE INDEX IF NOT EXISTS idx_songs_cover_of ON songs(cover_of);
+ALTER TABLE song_revisions ADD COLUMN cover_of INTEGER;
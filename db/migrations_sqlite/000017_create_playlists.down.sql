@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_playlist_entries_song_id;
+DROP INDEX IF EXISTS idx_playlist_entries_playlist_position;
+DROP TABLE IF EXISTS playlist_entries;
+DROP TABLE IF EXISTS playlists;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS playlists(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- entries are ordered by position then id, see the Postgres migration.
+CREATE TABLE IF NOT EXISTS playlist_entries(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    playlist_id INTEGER NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    added_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_playlist_entries_playlist_position ON playlist_entries(playlist_id, position);
+CREATE INDEX IF NOT EXISTS idx_playlist_entries_song_id ON playlist_entries(song_id);
@@ -0,0 +1,3 @@
+-- verses_stale marks the songs to split again on startup, see the Postgres migration.
+ALTER TABLE songs ADD COLUMN verses_stale BOOLEAN NOT NULL DEFAULT false;
+UPDATE songs SET verses_stale = true;
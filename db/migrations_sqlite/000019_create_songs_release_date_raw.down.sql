@@ -0,0 +1,2 @@
+-- the table belongs to 000005, see the up migration
+SELECT 1;
@@ -0,0 +1,5 @@
+-- songs_release_date_raw is created by 000005, see the Postgres migration.
+CREATE TABLE IF NOT EXISTS songs_release_date_raw(
+    song_id INTEGER PRIMARY KEY,
+    release_date TEXT NOT NULL
+);
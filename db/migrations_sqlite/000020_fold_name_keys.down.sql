@@ -0,0 +1,86 @@
+-- the triggers and keys of migration 000011 come back, keeping the case of the names
+DROP TRIGGER IF EXISTS song_search_insert;
+DROP TRIGGER IF EXISTS song_search_update;
+CREATE TRIGGER song_search_insert AFTER INSERT ON songs BEGIN
+    INSERT INTO song_search (rowid, group_name, song, text) VALUES (
+        new.id,
+        CASE WHEN new.group_key = new.group_name THEN new.group_name ELSE new.group_name || ' ' || new.group_key END,
+        CASE WHEN new.song_key = new.song THEN new.song ELSE new.song || ' ' || new.song_key END,
+        coalesce(new.text, ''));
+END;
+CREATE TRIGGER song_search_update AFTER UPDATE OF group_name, song, group_key, song_key, text ON songs BEGIN
+    UPDATE song_search SET
+        group_name = CASE WHEN new.group_key = new.group_name THEN new.group_name ELSE new.group_name || ' ' || new.group_key END,
+        song = CASE WHEN new.song_key = new.song THEN new.song ELSE new.song || ' ' || new.song_key END,
+        text = coalesce(new.text, '')
+    WHERE rowid = new.id;
+END;
+
+-- SQLite has no translate(), the backfill replaces one letter at a time and the update trigger refreshes song_search
+UPDATE songs SET group_key = group_name, song_key = song;
+UPDATE songs SET group_key = replace(group_key, 'а', 'a'), song_key = replace(song_key, 'а', 'a');
+UPDATE songs SET group_key = replace(group_key, 'б', 'b'), song_key = replace(song_key, 'б', 'b');
+UPDATE songs SET group_key = replace(group_key, 'в', 'v'), song_key = replace(song_key, 'в', 'v');
+UPDATE songs SET group_key = replace(group_key, 'г', 'g'), song_key = replace(song_key, 'г', 'g');
+UPDATE songs SET group_key = replace(group_key, 'д', 'd'), song_key = replace(song_key, 'д', 'd');
+UPDATE songs SET group_key = replace(group_key, 'е', 'e'), song_key = replace(song_key, 'е', 'e');
+UPDATE songs SET group_key = replace(group_key, 'ё', 'e'), song_key = replace(song_key, 'ё', 'e');
+UPDATE songs SET group_key = replace(group_key, 'ж', 'zh'), song_key = replace(song_key, 'ж', 'zh');
+UPDATE songs SET group_key = replace(group_key, 'з', 'z'), song_key = replace(song_key, 'з', 'z');
+UPDATE songs SET group_key = replace(group_key, 'и', 'i'), song_key = replace(song_key, 'и', 'i');
+UPDATE songs SET group_key = replace(group_key, 'й', 'y'), song_key = replace(song_key, 'й', 'y');
+UPDATE songs SET group_key = replace(group_key, 'к', 'k'), song_key = replace(song_key, 'к', 'k');
+UPDATE songs SET group_key = replace(group_key, 'л', 'l'), song_key = replace(song_key, 'л', 'l');
+UPDATE songs SET group_key = replace(group_key, 'м', 'm'), song_key = replace(song_key, 'м', 'm');
+UPDATE songs SET group_key = replace(group_key, 'н', 'n'), song_key = replace(song_key, 'н', 'n');
+UPDATE songs SET group_key = replace(group_key, 'о', 'o'), song_key = replace(song_key, 'о', 'o');
+UPDATE songs SET group_key = replace(group_key, 'п', 'p'), song_key = replace(song_key, 'п', 'p');
+UPDATE songs SET group_key = replace(group_key, 'р', 'r'), song_key = replace(song_key, 'р', 'r');
+UPDATE songs SET group_key = replace(group_key, 'с', 's'), song_key = replace(song_key, 'с', 's');
+UPDATE songs SET group_key = replace(group_key, 'т', 't'), song_key = replace(song_key, 'т', 't');
+UPDATE songs SET group_key = replace(group_key, 'у', 'u'), song_key = replace(song_key, 'у', 'u');
+UPDATE songs SET group_key = replace(group_key, 'ф', 'f'), song_key = replace(song_key, 'ф', 'f');
+UPDATE songs SET group_key = replace(group_key, 'х', 'kh'), song_key = replace(song_key, 'х', 'kh');
+UPDATE songs SET group_key = replace(group_key, 'ц', 'ts'), song_key = replace(song_key, 'ц', 'ts');
+UPDATE songs SET group_key = replace(group_key, 'ч', 'ch'), song_key = replace(song_key, 'ч', 'ch');
+UPDATE songs SET group_key = replace(group_key, 'ш', 'sh'), song_key = replace(song_key, 'ш', 'sh');
+UPDATE songs SET group_key = replace(group_key, 'щ', 'shch'), song_key = replace(song_key, 'щ', 'shch');
+UPDATE songs SET group_key = replace(group_key, 'ъ', ''), song_key = replace(song_key, 'ъ', '');
+UPDATE songs SET group_key = replace(group_key, 'ы', 'y'), song_key = replace(song_key, 'ы', 'y');
+UPDATE songs SET group_key = replace(group_key, 'ь', ''), song_key = replace(song_key, 'ь', '');
+UPDATE songs SET group_key = replace(group_key, 'э', 'e'), song_key = replace(song_key, 'э', 'e');
+UPDATE songs SET group_key = replace(group_key, 'ю', 'yu'), song_key = replace(song_key, 'ю', 'yu');
+UPDATE songs SET group_key = replace(group_key, 'я', 'ya'), song_key = replace(song_key, 'я', 'ya');
+UPDATE songs SET group_key = replace(group_key, 'А', 'A'), song_key = replace(song_key, 'А', 'A');
+UPDATE songs SET group_key = replace(group_key, 'Б', 'B'), song_key = replace(song_key, 'Б', 'B');
+UPDATE songs SET group_key = replace(group_key, 'В', 'V'), song_key = replace(song_key, 'В', 'V');
+UPDATE songs SET group_key = replace(group_key, 'Г', 'G'), song_key = replace(song_key, 'Г', 'G');
+UPDATE songs SET group_key = replace(group_key, 'Д', 'D'), song_key = replace(song_key, 'Д', 'D');
+UPDATE songs SET group_key = replace(group_key, 'Е', 'E'), song_key = replace(song_key, 'Е', 'E');
+UPDATE songs SET group_key = replace(group_key, 'Ё', 'E'), song_key = replace(song_key, 'Ё', 'E');
+UPDATE songs SET group_key = replace(group_key, 'Ж', 'Zh'), song_key = replace(song_key, 'Ж', 'Zh');
+UPDATE songs SET group_key = replace(group_key, 'З', 'Z'), song_key = replace(song_key, 'З', 'Z');
+UPDATE songs SET group_key = replace(group_key, 'И', 'I'), song_key = replace(song_key, 'И', 'I');
+UPDATE songs SET group_key = replace(group_key, 'Й', 'Y'), song_key = replace(song_key, 'Й', 'Y');
+UPDATE songs SET group_key = replace(group_key, 'К', 'K'), song_key = replace(song_key, 'К', 'K');
+UPDATE songs SET group_key = replace(group_key, 'Л', 'L'), song_key = replace(song_key, 'Л', 'L');
+UPDATE songs SET group_key = replace(group_key, 'М', 'M'), song_key = replace(song_key, 'М', 'M');
+UPDATE songs SET group_key = replace(group_key, 'Н', 'N'), song_key = replace(song_key, 'Н', 'N');
+UPDATE songs SET group_key = replace(group_key, 'О', 'O'), song_key = replace(song_key, 'О', 'O');
+UPDATE songs SET group_key = replace(group_key, 'П', 'P'), song_key = replace(song_key, 'П', 'P');
+UPDATE songs SET group_key = replace(group_key, 'Р', 'R'), song_key = replace(song_key, 'Р', 'R');
+UPDATE songs SET group_key = replace(group_key, 'С', 'S'), song_key = replace(song_key, 'С', 'S');
+UPDATE songs SET group_key = replace(group_key, 'Т', 'T'), song_key = replace(song_key, 'Т', 'T');
+UPDATE songs SET group_key = replace(group_key, 'У', 'U'), song_key = replace(song_key, 'У', 'U');
+UPDATE songs SET group_key = replace(group_key, 'Ф', 'F'), song_key = replace(song_key, 'Ф', 'F');
+UPDATE songs SET group_key = replace(group_key, 'Х', 'Kh'), song_key = replace(song_key, 'Х', 'Kh');
+UPDATE songs SET group_key = replace(group_key, 'Ц', 'Ts'), song_key = replace(song_key, 'Ц', 'Ts');
+UPDATE songs SET group_key = replace(group_key, 'Ч', 'Ch'), song_key = replace(song_key, 'Ч', 'Ch');
+UPDATE songs SET group_key = replace(group_key, 'Ш', 'Sh'), song_key = replace(song_key, 'Ш', 'Sh');
+UPDATE songs SET group_key = replace(group_key, 'Щ', 'Shch'), song_key = replace(song_key, 'Щ', 'Shch');
+UPDATE songs SET group_key = replace(group_key, 'Ъ', ''), song_key = replace(song_key, 'Ъ', '');
+UPDATE songs SET group_key = replace(group_key, 'Ы', 'Y'), song_key = replace(song_key, 'Ы', 'Y');
+UPDATE songs SET group_key = replace(group_key, 'Ь', ''), song_key = replace(song_key, 'Ь', '');
+UPDATE songs SET group_key = replace(group_key, 'Э', 'E'), song_key = replace(song_key, 'Э', 'E');
+UPDATE songs SET group_key = replace(group_key, 'Ю', 'Yu'), song_key = replace(song_key, 'Ю', 'Yu');
+UPDATE songs SET group_key = replace(group_key, 'Я', 'Ya'), song_key = replace(song_key, 'Я', 'Ya');
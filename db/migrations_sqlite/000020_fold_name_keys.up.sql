@@ -0,0 +1,22 @@
+-- name keys ignore case, see the Postgres migration. The built-in lower() only folds ASCII,
+-- songs_lower is the function the service registers for the full Unicode folding.
+-- song_search keeps a key beside its name only when they differ by more than case, the full-text search ignores case anyway.
+DROP TRIGGER IF EXISTS song_search_insert;
+DROP TRIGGER IF EXISTS song_search_update;
+CREATE TRIGGER song_search_insert AFTER INSERT ON songs BEGIN
+    INSERT INTO song_search (rowid, group_name, song, text) VALUES (
+        new.id,
+        CASE WHEN new.group_key = songs_lower(new.group_name) THEN new.group_name ELSE new.group_name || ' ' || new.group_key END,
+        CASE WHEN new.song_key = songs_lower(new.song) THEN new.song ELSE new.song || ' ' || new.song_key END,
+        coalesce(new.text, ''));
+END;
+CREATE TRIGGER song_search_update AFTER UPDATE OF group_name, song, group_key, song_key, text ON songs BEGIN
+    UPDATE song_search SET
+        group_name = CASE WHEN new.group_key = songs_lower(new.group_name) THEN new.group_name ELSE new.group_name || ' ' || new.group_key END,
+        song = CASE WHEN new.song_key = songs_lower(new.song) THEN new.song ELSE new.song || ' ' || new.song_key END,
+        text = coalesce(new.text, '')
+    WHERE rowid = new.id;
+END;
+
+-- the update trigger refreshes song_search
+UPDATE songs SET group_key = songs_lower(group_key), song_key = songs_lower(song_key);
@@ -17,11 +17,13 @@ const docTemplate = `{
         }
     ],
     "paths": {
-		"/info": {
+        "/info": {
             "get": {
-				"summary": "Получить информацию о песне",
-                "tags": ["info"],
+                "summary": "Получить информацию о песне",
                 "description": "Получение информации о песни по названию и автору",
+                "tags": [
+                    "info"
+                ],
                 "parameters": [
                     {
                         "name": "group",
@@ -38,6 +40,9 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    {
+                        "$ref": "#/components/parameters/Match"
                     }
                 ],
                 "responses": {
@@ -52,7 +57,17 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Ошибка при получении списка песен",
+                        "description": "Bad request",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Song not found",
                         "content": {
                             "application/json": {
                                 "schema": {
@@ -62,7 +77,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка при получении списка песен",
+                        "description": "Internal server error",
                         "content": {
                             "application/json": {
                                 "schema": {
@@ -77,8 +92,10 @@ const docTemplate = `{
         "/songs": {
             "get": {
                 "summary": "Получить список песен",
-                "description": "Получение данных библиотеки с возможностью фильтрации по полям и пагинацией. Обязательно заполнить хотя бы 1 поле",
-                "tags": ["songs"],
+                "description": "Получение данных библиотеки с возможностью фильтрации по полям и пагинацией. Обязательно заполнить хотя бы 1 поле. Песни идут в порядке sort. Следующая страница запрашивается с cursor из next_cursor ответа или по ссылке rel=\"next\" заголовка Link; на последней странице next_cursor нет.\n",
+                "tags": [
+                    "songs"
+                ],
                 "parameters": [
                     {
                         "name": "group",
@@ -96,49 +113,176 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    {
+                        "$ref": "#/components/parameters/Match"
+                    },
                     {
                         "name": "release_date",
                         "in": "query",
-                        "description": "Фильтрация по дате выпуска",
+                        "description": "Фильтрация по дате выпуска (YYYY-MM-DD или DD.MM.YYYY)",
                         "schema": {
                             "type": "string",
-                            "format": "date"
+                            "example": "2006-07-16"
                         }
                     },
                     {
-                        "name": "page",
+                        "name": "release_from",
                         "in": "query",
-                        "description": "Номер страницы",
+                        "description": "Выпущены не раньше этой даты включительно (YYYY-MM-DD или DD.MM.YYYY)",
+                        "schema": {
+                            "type": "string",
+                            "example": "2006-01-01"
+                        }
+                    },
+                    {
+                        "name": "release_to",
+                        "in": "query",
+                        "description": "Выпущены не позже этой даты включительно (YYYY-MM-DD или DD.MM.YYYY)",
+                        "schema": {
+                            "type": "string",
+                            "example": "2009-12-31"
+                        }
+                    },
+                    {
+                        "name": "year",
+                        "in": "query",
+                        "description": "Год выпуска",
                         "schema": {
                             "type": "integer",
-                            "default": 1
+                            "example": 2006
+                        }
+                    },
+                    {
+                        "name": "album",
+                        "in": "query",
+                        "description": "ID альбома",
+                        "schema": {
+                            "type": "integer",
+                            "example": 1
+                        }
+                    },
+                    {
+                        "name": "artist",
+                        "in": "query",
+                        "description": "ID группы, совпадают песни группы и песни, где она указана в любой роли",
+                        "schema": {
+                            "type": "integer",
+                            "example": 1
+                        }
+                    },
+                    {
+                        "name": "tag",
+                        "in": "query",
+                        "description": "Теги, параметр повторяется для нескольких тегов. Регистр не учитывается",
+                        "style": "form",
+                        "explode": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            },
+                            "example": [
+                                "rock",
+                                "90s"
+                            ]
+                        }
+                    },
+                    {
+                        "name": "tag_match",
+                        "in": "query",
+                        "description": "all — у песни есть все теги из tag, any — хотя бы один",
+                        "schema": {
+                            "type": "string",
+                            "enum": [
+                                "all",
+                                "any"
+                            ],
+                            "default": "all"
+                        }
+                    },
+                    {
+                        "name": "sort",
+                        "in": "query",
+                        "description": "Сортировка по полям через запятую, \"-\" перед полем — по убыванию: id, group, song, release_date. Названия сравниваются по правилам русского языка, песни без даты выпуска идут раньше датированных, при равенстве — по id. По умолчанию по id, при match=fuzzy — по score.\n",
+                        "schema": {
+                            "type": "string",
+                            "example": "-release_date,group,song"
+                        }
+                    },
+                    {
+                        "$ref": "#/components/parameters/Facets"
+                    },
+                    {
+                        "name": "fields",
+                        "in": "query",
+                        "description": "Поля песен в ответе через запятую: id, group, artistId, song, albumId, track, coverOf, releaseDate, text, link, enriched, verseCount, version. По умолчанию все, кроме text.\n",
+                        "schema": {
+                            "type": "string",
+                            "example": "id,group,song,releaseDate"
+                        }
+                    },
+                    {
+                        "name": "q",
+                        "in": "query",
+                        "description": "Выражение запроса, применяется вместе с остальными фильтрами. Условие — поле, оператор и значение: \":\" или \"=\" равно, \"!=\", \"<\", \"<=\", \">\", \">=\", \"~\" содержит (без учёта регистра), \"значение*\" — префикс, \"от..до\" — диапазон включительно, \"поле IN (a, b)\" — одно из значений. Условия объединяются AND, OR, NOT и скобками; значения с пробелами берутся в кавычки. Поля: id, group, song, text, link, release_date, year, version, enriched. Песня без даты выпуска не подходит ни под одно условие на release_date и year.\n",
+                        "schema": {
+                            "type": "string",
+                            "example": "group:Muse AND year>=2005 AND text~\"black hole\""
+                        }
+                    },
+                    {
+                        "name": "cursor",
+                        "in": "query",
+                        "description": "Непрозрачный курсор из next_cursor предыдущей страницы, без него возвращается первая страница. Курсор действителен только для той же сортировки",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     {
                         "name": "limit",
                         "in": "query",
-                        "description": "Количество элементов на странице",
+                        "description": "Количество элементов на странице, по умолчанию DEFAULT_LIMIT, не больше MAX_LIMIT",
                         "schema": {
                             "type": "integer",
-                            "default": 10
+                            "default": 5,
+                            "maximum": 100
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Список песен",
+                        "description": "Страница песен",
+                        "headers": {
+                            "Link": {
+                                "$ref": "#/components/headers/Link"
+                            }
+                        },
                         "content": {
                             "application/json": {
                                 "schema": {
-                                    "type": "array",
-                                    "items": {
-                                        "$ref": "#/components/schemas/Song"
-                                    }
+                                    "$ref": "#/components/schemas/SongPage"
                                 }
                             }
                         }
                     },
                     "400": {
+                        "description": "Некорректные фильтры, facets, курсор или q. Для ошибок в q указаны позиция и токен",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "oneOf": [
+                                        {
+                                            "$ref": "#/components/schemas/Error"
+                                        },
+                                        {
+                                            "$ref": "#/components/schemas/QueryError"
+                                        }
+                                    ]
+                                }
+                            }
+                        }
+                    },
+                    "500": {
                         "description": "Ошибка при получении списка песен",
                         "content": {
                             "application/json": {
@@ -152,10 +296,12 @@ const docTemplate = `{
             },
             "post": {
                 "summary": "Добавить новую песню",
-                "description": "Добавление новой песни в базу данных.",
-                "tags": ["songs"],
+                "description": "Добавление новой песни в базу данных. Незаполненные releaseDate, text и link запрашиваются во внешнем API (GET /info). Если внешний API недоступен, песня сохраняется с enriched=false.\n",
+                "tags": [
+                    "songs"
+                ],
                 "requestBody": {
-                    "description": "Данные новой песни",
+                    "description": "Данные новой песни. releaseDate принимается в формате YYYY-MM-DD или DD.MM.YYYY и возвращается как YYYY-MM-DD",
                     "required": true,
                     "content": {
                         "application/json": {
@@ -186,6 +332,16 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "409": {
+                        "description": "Песня с такими group и song уже есть",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Ошибка при добавлении песни",
                         "content": {
@@ -199,11 +355,132 @@ const docTemplate = `{
                 }
             }
         },
+        "/songs/search": {
+            "get": {
+                "summary": "Полнотекстовый поиск песен",
+                "description": "Поиск по названию, группе и тексту песни на русском и английском, лучшие совпадения первыми. Названия и группы находятся и по транслитерации запроса: «звезда» находит «Zvezda». Для каждой песни возвращается фрагмент куплета, в котором нашлись слова запроса, найденные слова выделены тегом b. Пагинация по page и limit, limit не больше MAX_LIMIT.\n",
+                "tags": [
+                    "songs"
+                ],
+                "parameters": [
+                    {
+                        "name": "q",
+                        "in": "query",
+                        "required": true,
+                        "description": "Слова для поиска, например строчка из песни",
+                        "schema": {
+                            "type": "string",
+                            "example": "dead of night"
+                        }
+                    },
+                    {
+                        "name": "group",
+                        "in": "query",
+                        "description": "Только песни группы",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "name": "release_from",
+                        "in": "query",
+                        "description": "Выпущены не раньше этой даты включительно (YYYY-MM-DD или DD.MM.YYYY)",
+                        "schema": {
+                            "type": "string",
+                            "example": "2000-01-01"
+                        }
+                    },
+                    {
+                        "name": "release_to",
+                        "in": "query",
+                        "description": "Выпущены не позже этой даты включительно (YYYY-MM-DD или DD.MM.YYYY)",
+                        "schema": {
+                            "type": "string",
+                            "example": "2009-12-31"
+                        }
+                    },
+                    {
+                        "name": "year",
+                        "in": "query",
+                        "description": "Год выпуска",
+                        "schema": {
+                            "type": "integer",
+                            "example": 2006
+                        }
+                    },
+                    {
+                        "$ref": "#/components/parameters/Facets"
+                    },
+                    {
+                        "name": "page",
+                        "in": "query",
+                        "description": "Номер страницы",
+                        "schema": {
+                            "type": "integer",
+                            "default": 1
+                        }
+                    },
+                    {
+                        "name": "limit",
+                        "in": "query",
+                        "description": "Количество элементов на странице",
+                        "schema": {
+                            "type": "integer",
+                            "default": 5
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Найденные песни, с параметром facets — вместе со счётчиками",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "oneOf": [
+                                        {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/components/schemas/SearchResult"
+                                            }
+                                        },
+                                        {
+                                            "$ref": "#/components/schemas/SearchPage"
+                                        }
+                                    ]
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Не указан параметр q или неверные фильтры",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при поиске песен",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/songs/{id}": {
             "get": {
                 "summary": "Получить песню по ID",
                 "description": "Извлечение песни по ее уникальному идентификатору.",
-                "tags": ["songs"],
+                "tags": [
+                    "songs"
+                ],
                 "parameters": [
                     {
                         "name": "id",
@@ -216,11 +493,31 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Информация о песне",
+                        "description": "Информация о песне и все указанные в ней группы",
+                        "headers": {
+                            "ETag": {
+                                "$ref": "#/components/headers/ETag"
+                            }
+                        },
                         "content": {
                             "application/json": {
                                 "schema": {
-                                    "$ref": "#/components/schemas/Song"
+                                    "allOf": [
+                                        {
+                                            "$ref": "#/components/schemas/Song"
+                                        },
+                                        {
+                                            "type": "object",
+                                            "properties": {
+                                                "artists": {
+                                                    "type": "array",
+                                                    "items": {
+                                                        "$ref": "#/components/schemas/Credit"
+                                                    }
+                                                }
+                                            }
+                                        }
+                                    ]
                                 }
                             }
                         }
@@ -239,8 +536,10 @@ const docTemplate = `{
             },
             "put": {
                 "summary": "Обновить песню",
-                "description": "Обновление существующей песни по ее ID.",
-                "tags": ["songs"],
+                "description": "Полная замена данных существующей песни по ее ID. Поля group и song обязательны. С заголовком If-Match песня обновляется, только если ее версия не изменилась.\n",
+                "tags": [
+                    "songs"
+                ],
                 "parameters": [
                     {
                         "name": "id",
@@ -249,6 +548,9 @@ const docTemplate = `{
                         "schema": {
                             "type": "integer"
                         }
+                    },
+                    {
+                        "$ref": "#/components/parameters/IfMatch"
                     }
                 ],
                 "requestBody": {
@@ -265,6 +567,11 @@ const docTemplate = `{
                 "responses": {
                     "200": {
                         "description": "Обновленная песня",
+                        "headers": {
+                            "ETag": {
+                                "$ref": "#/components/headers/ETag"
+                            }
+                        },
                         "content": {
                             "application/json": {
                                 "schema": {
@@ -292,13 +599,28 @@ const docTemplate = `{
                                 }
                             }
                         }
+                    },
+                    "409": {
+                        "description": "Название песни занято другой песней",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "412": {
+                        "$ref": "#/components/responses/PreconditionFailed"
                     }
                 }
             },
-            "delete": {
-                "summary": "Удалить песню",
-                "description": "Удаление песни по ее ID.",
-                "tags": ["songs"],
+            "patch": {
+                "summary": "Частично обновить песню",
+                "description": "JSON merge patch (RFC 7396): меняются только переданные поля, null очищает необязательное поле. group и song нельзя очистить. С заголовком If-Match песня обновляется, только если ее версия не изменилась.\n",
+                "tags": [
+                    "songs"
+                ],
                 "parameters": [
                     {
                         "name": "id",
@@ -307,11 +629,52 @@ const docTemplate = `{
                         "schema": {
                             "type": "integer"
                         }
+                    },
+                    {
+                        "$ref": "#/components/parameters/IfMatch"
                     }
                 ],
+                "requestBody": {
+                    "description": "Изменяемые поля песни",
+                    "required": true,
+                    "content": {
+                        "application/merge-patch+json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/SongPatch"
+                            }
+                        },
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/SongPatch"
+                            }
+                        }
+                    }
+                },
                 "responses": {
-                    "204": {
-                        "description": "Песня успешно удалена"
+                    "200": {
+                        "description": "Обновленная песня",
+                        "headers": {
+                            "ETag": {
+                                "$ref": "#/components/headers/ETag"
+                            }
+                        },
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Song"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
                     },
                     "404": {
                         "description": "Песня не найдена",
@@ -323,8 +686,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Ошибка при удалении песни",
+                    "409": {
+                        "description": "Название песни занято другой песней",
                         "content": {
                             "application/json": {
                                 "schema": {
@@ -332,15 +695,68 @@ const docTemplate = `{
                                 }
                             }
                         }
+                    },
+                    "412": {
+                        "$ref": "#/components/responses/PreconditionFailed"
                     }
                 }
-            }
-        },
-        "/songs/{id}/verse": {
-            "get": {
-                "summary": "Получить куплет песни по ее ID",
-                "description": "Извлекает определенный куплет из песни по ее идентификатору. Если параметр verse не указан, будет возвращен первый куплет по умолчанию.",
-                "tags": ["songs"],
+            },
+            "delete": {
+                "summary": "Удалить песню",
+                "description": "Перемещение песни в корзину (GET /trash). Из корзины песню можно восстановить, пока не истек срок хранения TRASH_RETENTION. С заголовком If-Match песня удаляется, только если ее версия не изменилась.\n",
+                "tags": [
+                    "songs"
+                ],
+                "parameters": [
+                    {
+                        "name": "id",
+                        "in": "path",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "$ref": "#/components/parameters/IfMatch"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Песня успешно удалена"
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "412": {
+                        "$ref": "#/components/responses/PreconditionFailed"
+                    },
+                    "500": {
+                        "description": "Ошибка при удалении песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/verse": {
+            "get": {
+                "summary": "Получить куплет песни по ее ID",
+                "description": "Извлекает определенный куплет из песни по его идентификатору. Если параметр verse не указан, будет возвращен первый куплет по умолчанию.\n",
+                "tags": [
+                    "songs"
+                ],
                 "parameters": [
                     {
                         "name": "id",
@@ -380,174 +796,3093 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Неправильное ID песни",
-                        "content": {
-                            "application/json": {
-                                "schema": {
-                                    "$ref": "#/components/schemas/Error"
-                                }
+                    "400": {
+                        "description": "Неправильное ID песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "type": "string",
+                                            "example": "Invalid song ID"
+                                        }
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "type": "string",
+                                            "example": "Song not found"
+                                        }
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка обработки запроса",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "type": "string",
+                                            "example": "Failed to retrieve verse"
+                                        }
+                                    }
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/text": {
+            "get": {
+                "summary": "Получить текст песни постранично",
+                "description": "Возвращает страницу куплетов песни, общее количество куплетов и ссылки на соседние страницы. Заголовки вида [Verse 1], [Chorus], [Bridge] в тексте размечают части песни: у каждой части есть тип и метка. Если page или limit не указаны, используются DEFAULT_PAGE и DEFAULT_VERSE_LIMIT.\n",
+                "tags": [
+                    "songs"
+                ],
+                "parameters": [
+                    {
+                        "name": "id",
+                        "in": "path",
+                        "description": "ID песни",
+                        "required": true,
+                        "schema": {
+                            "type": "integer",
+                            "example": 1
+                        }
+                    },
+                    {
+                        "name": "page",
+                        "in": "query",
+                        "description": "Номер страницы",
+                        "schema": {
+                            "type": "integer",
+                            "default": 1
+                        }
+                    },
+                    {
+                        "name": "limit",
+                        "in": "query",
+                        "description": "Количество куплетов на странице",
+                        "schema": {
+                            "type": "integer",
+                            "default": 2
+                        }
+                    },
+                    {
+                        "name": "type",
+                        "in": "query",
+                        "description": "Вернуть только части указанного типа (например, только припевы)",
+                        "schema": {
+                            "type": "string",
+                            "enum": [
+                                "verse",
+                                "chorus",
+                                "pre-chorus",
+                                "bridge",
+                                "intro",
+                                "outro",
+                                "hook",
+                                "other"
+                            ]
+                        }
+                    },
+                    {
+                        "name": "collapse",
+                        "in": "query",
+                        "description": "Пропускать припевы, повторяющие текст предыдущего припева",
+                        "schema": {
+                            "type": "boolean",
+                            "default": false
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница куплетов",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/VersePage"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка обработки запроса",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/revisions": {
+            "get": {
+                "summary": "История изменений песни",
+                "description": "Снимки песни после каждого добавления, изменения, удаления и отката, от старых к новым. История сохраняется и после удаления песни. Автор изменения берется из заголовка X-Actor.\n",
+                "tags": [
+                    "revisions"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/SongID"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список ревизий",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/SongRevision"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/revisions/diff": {
+            "get": {
+                "summary": "Сравнить две ревизии песни",
+                "description": "Поля песни, отличающиеся между ревизиями from и to. По умолчанию to - последняя ревизия, from - предыдущая перед to.\n",
+                "tags": [
+                    "revisions"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/SongID"
+                    },
+                    {
+                        "name": "from",
+                        "in": "query",
+                        "schema": {
+                            "type": "integer",
+                            "example": 1
+                        }
+                    },
+                    {
+                        "name": "to",
+                        "in": "query",
+                        "schema": {
+                            "type": "integer",
+                            "example": 3
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изменения полей",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/RevisionDiff"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня или ревизия не найдены",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/revisions/{rev}": {
+            "get": {
+                "summary": "Получить ревизию песни",
+                "tags": [
+                    "revisions"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/SongID"
+                    },
+                    {
+                        "$ref": "#/components/parameters/Revision"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ревизия",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/SongRevision"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Ревизия не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/revisions/{rev}/revert": {
+            "post": {
+                "summary": "Откатить песню к ревизии",
+                "description": "Записывает состояние ревизии как новую версию песни. Песня из корзины восстанавливается, окончательно удаленная создается заново с прежним ID. С заголовком If-Match откат выполняется, только если версия песни не изменилась.\n",
+                "tags": [
+                    "revisions"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/SongID"
+                    },
+                    {
+                        "$ref": "#/components/parameters/Revision"
+                    },
+                    {
+                        "$ref": "#/components/parameters/IfMatch"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Песня после отката",
+                        "headers": {
+                            "ETag": {
+                                "$ref": "#/components/headers/ETag"
+                            }
+                        },
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Song"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Ревизия не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Название песни занято другой песней",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "412": {
+                        "$ref": "#/components/responses/PreconditionFailed"
+                    },
+                    "500": {
+                        "description": "Ошибка при откате песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/artists": {
+            "get": {
+                "summary": "Группы песни",
+                "description": "Все группы, указанные в песне: сначала группа песни с ролью main, затем остальные по роли и названию.\n",
+                "tags": [
+                    "songs"
+                ],
+                "parameters": [
+                    {
+                        "name": "id",
+                        "in": "path",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Группы песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/Credit"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении групп песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "summary": "Указать группы песни",
+                "description": "Заменяет группы, указанные в песне помимо ее группы, с ролями featuring или producer. Группы указываются по названию, недостающие создаются. Группа песни меняется вместе с песней.\n",
+                "tags": [
+                    "songs"
+                ],
+                "parameters": [
+                    {
+                        "name": "id",
+                        "in": "path",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/components/schemas/NewCredit"
+                                }
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Группы песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/Credit"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни, пустое название или неизвестная роль",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при сохранении групп песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/covers": {
+            "get": {
+                "summary": "Каверы песни",
+                "description": "Песни, у которых coverOf указывает на эту песню, по id.",
+                "tags": [
+                    "songs"
+                ],
+                "parameters": [
+                    {
+                        "name": "id",
+                        "in": "path",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Каверы песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/Song"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении каверов песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/tags": {
+            "get": {
+                "summary": "Теги песни",
+                "description": "Теги песни по названию.",
+                "tags": [
+                    "tags"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/SongID"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Теги песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    },
+                                    "example": [
+                                        "90s",
+                                        "rock"
+                                    ]
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении тегов песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "summary": "Добавить теги песне",
+                "description": "Добавляет песне теги, недостающие теги создаются. Названия тегов приводятся к нижнему регистру, теги, которые уже есть у песни, остаются в одном экземпляре.\n",
+                "tags": [
+                    "tags"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/SongID"
+                    }
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/SongTags"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Все теги песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    },
+                                    "example": [
+                                        "90s",
+                                        "rock"
+                                    ]
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни, пустой или слишком длинный тег",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при добавлении тегов",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}/tags/{tag}": {
+            "delete": {
+                "summary": "Снять тег с песни",
+                "tags": [
+                    "tags"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/SongID"
+                    },
+                    {
+                        "name": "tag",
+                        "in": "path",
+                        "required": true,
+                        "schema": {
+                            "type": "string",
+                            "example": "rock"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Тег снят"
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена или у нее нет такого тега",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при снятии тега",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "summary": "Список тегов",
+                "description": "Теги с количеством песен, не считая песен в корзине; сначала самые частые, затем по названию.",
+                "tags": [
+                    "tags"
+                ],
+                "parameters": [
+                    {
+                        "name": "page",
+                        "in": "query",
+                        "description": "Номер страницы",
+                        "schema": {
+                            "type": "integer",
+                            "default": 1
+                        }
+                    },
+                    {
+                        "name": "limit",
+                        "in": "query",
+                        "description": "Количество элементов на странице",
+                        "schema": {
+                            "type": "integer",
+                            "default": 5
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Теги",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/Tag"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении тегов",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/trash": {
+            "get": {
+                "summary": "Корзина",
+                "description": "Удаленные песни, которые еще можно восстановить, сначала удаленные последними.",
+                "tags": [
+                    "trash"
+                ],
+                "parameters": [
+                    {
+                        "name": "page",
+                        "in": "query",
+                        "description": "Номер страницы",
+                        "schema": {
+                            "type": "integer",
+                            "default": 1
+                        }
+                    },
+                    {
+                        "name": "limit",
+                        "in": "query",
+                        "description": "Количество элементов на странице",
+                        "schema": {
+                            "type": "integer",
+                            "default": 5
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Песни в корзине",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/TrashedSong"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении корзины",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "summary": "Очистить корзину",
+                "description": "Окончательно удаляет песни, пролежавшие в корзине дольше TRASH_RETENTION. Сервер также делает это каждые TRASH_PURGE_INTERVAL. История изменений песен сохраняется.\n",
+                "tags": [
+                    "trash"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Количество удаленных песен",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "object",
+                                    "properties": {
+                                        "purged": {
+                                            "type": "integer",
+                                            "example": 3
+                                        }
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при очистке корзины",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/trash/{id}/restore": {
+            "post": {
+                "summary": "Восстановить песню из корзины",
+                "tags": [
+                    "trash"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/SongID"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Восстановленная песня",
+                        "headers": {
+                            "ETag": {
+                                "$ref": "#/components/headers/ETag"
+                            }
+                        },
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Song"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Песни нет в корзине",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "После удаления была добавлена другая песня с теми же группой и названием",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при восстановлении песни",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/groups": {
+            "get": {
+                "summary": "Группы",
+                "description": "Группы с количеством их песен, не считая песен в корзине, по алфавиту. Группа удаляется, когда на неё не ссылаются ни песни, включая песни в корзине, ни альбомы, ни участие в чужих песнях.",
+                "tags": [
+                    "groups"
+                ],
+                "parameters": [
+                    {
+                        "name": "page",
+                        "in": "query",
+                        "description": "Номер страницы",
+                        "schema": {
+                            "type": "integer",
+                            "default": 1
+                        }
+                    },
+                    {
+                        "name": "limit",
+                        "in": "query",
+                        "description": "Количество элементов на странице",
+                        "schema": {
+                            "type": "integer",
+                            "default": 5
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Группы",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/Artist"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении групп",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{id}": {
+            "get": {
+                "summary": "Получить группу",
+                "tags": [
+                    "groups"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/ArtistID"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Группа",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Artist"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID группы",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Группа не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении группы",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "summary": "Переименовать группу",
+                "description": "Новое название получают и все песни группы, включая песни в корзине, каждая — как новая версия с ревизией.\n",
+                "tags": [
+                    "groups"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/ArtistID"
+                    }
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/ArtistRename"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Переименованная группа",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Artist"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID группы или пустое название",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Группа не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Название занято другой группой, группы можно объединить",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при переименовании группы",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{id}/merge": {
+            "post": {
+                "summary": "Объединить группы",
+                "description": "Переносит песни и альбомы группы from, включая песни в корзине, в эту группу под ее названием и удаляет группу from. Каждая перенесенная песня получает новую версию с ревизией.\n",
+                "tags": [
+                    "groups"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/ArtistID"
+                    }
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/ArtistMerge"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Группа после объединения",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Artist"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID группы или объединение группы с самой собой",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Одной из групп нет",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "У обеих групп есть песня или альбом с одним названием",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при объединении групп",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/albums": {
+            "get": {
+                "summary": "Альбомы",
+                "description": "Альбомы с количеством их песен, не считая песен в корзине, по дате выпуска, сначала альбомы без даты.",
+                "tags": [
+                    "albums"
+                ],
+                "parameters": [
+                    {
+                        "name": "artistId",
+                        "in": "query",
+                        "description": "ID группы, только ее альбомы",
+                        "schema": {
+                            "type": "integer",
+                            "example": 1
+                        }
+                    },
+                    {
+                        "name": "page",
+                        "in": "query",
+                        "description": "Номер страницы",
+                        "schema": {
+                            "type": "integer",
+                            "default": 1
+                        }
+                    },
+                    {
+                        "name": "limit",
+                        "in": "query",
+                        "description": "Количество элементов на странице",
+                        "schema": {
+                            "type": "integer",
+                            "default": 5
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Альбомы",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/Album"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID группы",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении альбомов",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "summary": "Добавить альбом",
+                "description": "Группа альбома задается названием, как у песен, и создается, если ее еще нет.",
+                "tags": [
+                    "albums"
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/NewAlbum"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Добавленный альбом",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Album"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильные данные альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "У группы уже есть альбом с этим названием",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при добавлении альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/albums/{id}": {
+            "get": {
+                "summary": "Получить альбом",
+                "tags": [
+                    "albums"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/AlbumID"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Альбом",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Album"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Альбом не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "summary": "Обновить альбом",
+                "description": "При смене группы альбома его песни остаются со своей группой.",
+                "tags": [
+                    "albums"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/AlbumID"
+                    }
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/NewAlbum"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Обновленный альбом",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Album"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID или данные альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Альбом не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "У группы уже есть альбом с этим названием",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при обновлении альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "summary": "Удалить альбом",
+                "description": "Песни альбома, включая песни в корзине, остаются без альбома и номера трека, каждая — как новая версия с ревизией.\n",
+                "tags": [
+                    "albums"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/AlbumID"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Альбом удален"
+                    },
+                    "400": {
+                        "description": "Неправильное ID альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Альбом не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при удалении альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/albums/{id}/songs": {
+            "get": {
+                "summary": "Песни альбома",
+                "description": "Песни альбома по номеру трека, песни без номера в конце.",
+                "tags": [
+                    "albums"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/AlbumID"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Песни альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/Song"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Альбом не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении песен альбома",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists": {
+            "get": {
+                "summary": "Плейлисты",
+                "description": "Плейлисты в порядке создания с количеством записей, не считая песен в корзине.",
+                "tags": [
+                    "playlists"
+                ],
+                "parameters": [
+                    {
+                        "name": "page",
+                        "in": "query",
+                        "description": "Номер страницы",
+                        "schema": {
+                            "type": "integer",
+                            "default": 1
+                        }
+                    },
+                    {
+                        "name": "limit",
+                        "in": "query",
+                        "description": "Количество элементов на странице",
+                        "schema": {
+                            "type": "integer",
+                            "default": 5
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Плейлисты",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/Playlist"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении плейлистов",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "summary": "Создать плейлист",
+                "tags": [
+                    "playlists"
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/PlaylistName"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Созданный плейлист",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Playlist"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Пустое название",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при создании плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}": {
+            "get": {
+                "summary": "Получить плейлист",
+                "tags": [
+                    "playlists"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/PlaylistID"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Плейлист",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Playlist"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "summary": "Переименовать плейлист",
+                "tags": [
+                    "playlists"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/PlaylistID"
+                    }
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/PlaylistName"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Переименованный плейлист",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Playlist"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID плейлиста или пустое название",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при переименовании плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "summary": "Удалить плейлист",
+                "description": "Удаляет плейлист с его записями, песни остаются.",
+                "tags": [
+                    "playlists"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/PlaylistID"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Плейлист удален"
+                    },
+                    "400": {
+                        "description": "Неправильное ID плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при удалении плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}/entries": {
+            "get": {
+                "summary": "Записи плейлиста",
+                "description": "Записи плейлиста по порядку. Песни в корзине не показываются, позиции считаются с 1 по показанным записям; после восстановления песни ее записи возвращаются на свои места, при очистке корзины удаляются.\n",
+                "tags": [
+                    "playlists"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/PlaylistID"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Записи плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/components/schemas/PlaylistEntry"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при получении записей плейлиста",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "summary": "Добавить песню в плейлист",
+                "description": "Вставляет песню на позицию position, записи с этой позиции сдвигаются вниз. Без position или с позицией за концом плейлиста песня добавляется в конец. Одна песня может быть в плейлисте несколько раз.\n",
+                "tags": [
+                    "playlists"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/PlaylistID"
+                    }
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/NewPlaylistEntry"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Добавленная запись",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/PlaylistEntry"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID плейлиста, позиция или песня не найдена",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист не найден",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при добавлении песни в плейлист",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}/entries/{entryId}": {
+            "patch": {
+                "summary": "Переместить запись плейлиста",
+                "description": "Ставит запись на позицию position, остальные записи сохраняют свой порядок. Позиция за концом плейлиста перемещает запись в конец.\n",
+                "tags": [
+                    "playlists"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/PlaylistID"
+                    },
+                    {
+                        "name": "entryId",
+                        "in": "path",
+                        "description": "ID записи плейлиста",
+                        "required": true,
+                        "schema": {
+                            "type": "integer",
+                            "example": 7
+                        }
+                    }
+                ],
+                "requestBody": {
+                    "required": true,
+                    "content": {
+                        "application/json": {
+                            "schema": {
+                                "$ref": "#/components/schemas/PlaylistEntryMove"
+                            }
+                        }
+                    }
+                },
+                "responses": {
+                    "200": {
+                        "description": "Перемещенная запись",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/PlaylistEntry"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неправильное ID или позиция",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист или запись не найдены",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при перемещении записи",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "summary": "Убрать запись из плейлиста",
+                "description": "Записи после нее поднимаются на одну позицию.",
+                "tags": [
+                    "playlists"
+                ],
+                "parameters": [
+                    {
+                        "$ref": "#/components/parameters/PlaylistID"
+                    },
+                    {
+                        "name": "entryId",
+                        "in": "path",
+                        "description": "ID записи плейлиста",
+                        "required": true,
+                        "schema": {
+                            "type": "integer",
+                            "example": 7
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Запись убрана"
+                    },
+                    "400": {
+                        "description": "Неправильное ID",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист или запись не найдены",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при удалении записи",
+                        "content": {
+                            "application/json": {
+                                "schema": {
+                                    "$ref": "#/components/schemas/Error"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "components": {
+        "parameters": {
+            "Facets": {
+                "name": "facets",
+                "in": "query",
+                "description": "Счётчики песен по значениям через запятую: group, year, decade (десятилетие, значение — его первый год). Считаются все песни, подходящие под фильтры, не больше 20 самых частых значений каждого. Ссылка filter значения — тот же запрос, суженный до него параметрами group, year или release_from и release_to.\n",
+                "schema": {
+                    "type": "string",
+                    "example": "group,year"
+                }
+            },
+            "Match": {
+                "name": "match",
+                "in": "query",
+                "description": "Сравнение group и song: exact — полное совпадение, prefix — начало названия, регистр не учитывается,\nfuzzy — похожие названия с опечатками (триграммы), лучшие совпадения первыми и с оценкой score.\nКириллица сравнивается по транслитерации: «Кино» находит «Kino» и наоборот\n",
+                "schema": {
+                    "type": "string",
+                    "enum": [
+                        "exact",
+                        "prefix",
+                        "fuzzy"
+                    ],
+                    "default": "exact"
+                }
+            },
+            "SongID": {
+                "name": "id",
+                "in": "path",
+                "description": "ID песни",
+                "required": true,
+                "schema": {
+                    "type": "integer",
+                    "example": 1
+                }
+            },
+            "AlbumID": {
+                "name": "id",
+                "in": "path",
+                "description": "ID альбома",
+                "required": true,
+                "schema": {
+                    "type": "integer",
+                    "example": 1
+                }
+            },
+            "PlaylistID": {
+                "name": "id",
+                "in": "path",
+                "description": "ID плейлиста",
+                "required": true,
+                "schema": {
+                    "type": "integer",
+                    "example": 1
+                }
+            },
+            "ArtistID": {
+                "name": "id",
+                "in": "path",
+                "description": "ID группы",
+                "required": true,
+                "schema": {
+                    "type": "integer",
+                    "example": 1
+                }
+            },
+            "Revision": {
+                "name": "rev",
+                "in": "path",
+                "description": "Номер ревизии",
+                "required": true,
+                "schema": {
+                    "type": "integer",
+                    "example": 2
+                }
+            },
+            "IfMatch": {
+                "name": "If-Match",
+                "in": "header",
+                "description": "ETag песни, полученный ранее. Изменение выполняется, только если версия песни совпадает",
+                "required": false,
+                "schema": {
+                    "type": "string",
+                    "example": "\"3\""
+                }
+            }
+        },
+        "headers": {
+            "Link": {
+                "description": "Ссылки на первую (rel=\"first\") и следующую (rel=\"next\") страницы по RFC 8288",
+                "schema": {
+                    "type": "string",
+                    "example": "</songs?group=Muse&limit=5>; rel=\"first\", </songs?cursor=eyJpZCI6NX0&group=Muse&limit=5>; rel=\"next\""
+                }
+            },
+            "ETag": {
+                "description": "Версия песни",
+                "schema": {
+                    "type": "string",
+                    "example": "\"3\""
+                }
+            }
+        },
+        "responses": {
+            "PreconditionFailed": {
+                "description": "Песня была изменена после получения ETag",
+                "content": {
+                    "application/json": {
+                        "schema": {
+                            "$ref": "#/components/schemas/Error"
+                        }
+                    }
+                }
+            }
+        },
+        "schemas": {
+            "Song": {
+                "type": "object",
+                "required": [
+                    "group",
+                    "song"
+                ],
+                "properties": {
+                    "id": {
+                        "type": "integer",
+                        "example": 1
+                    },
+                    "group": {
+                        "type": "string",
+                        "example": "Muse"
+                    },
+                    "artistId": {
+                        "type": "integer",
+                        "description": "ID группы песни, задается по group",
+                        "readOnly": true,
+                        "example": 1
+                    },
+                    "song": {
+                        "type": "string",
+                        "example": "Supermassive Black Hole"
+                    },
+                    "albumId": {
+                        "type": "integer",
+                        "description": "ID альбома",
+                        "example": 1
+                    },
+                    "track": {
+                        "type": "integer",
+                        "description": "Номер трека в альбоме, только вместе с albumId",
+                        "example": 2
+                    },
+                    "coverOf": {
+                        "type": "integer",
+                        "description": "ID оригинальной песни, если песня — кавер",
+                        "example": 3
+                    },
+                    "releaseDate": {
+                        "type": "string",
+                        "description": "Дата выпуска в формате YYYY-MM-DD",
+                        "format": "date",
+                        "example": "2006-07-16"
+                    },
+                    "text": {
+                        "type": "string",
+                        "example": "Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?\n"
+                    },
+                    "link": {
+                        "type": "string",
+                        "example": "https://www.youtube.com/watch?v=Xsp3_a-PMTw"
+                    },
+                    "enriched": {
+                        "type": "boolean",
+                        "description": "Были ли данные песни дополнены из внешнего API",
+                        "example": true
+                    },
+                    "verseCount": {
+                        "type": "integer",
+                        "description": "Количество куплетов в тексте песни",
+                        "example": 4
+                    },
+                    "version": {
+                        "type": "integer",
+                        "description": "Версия песни, увеличивается при каждом изменении. Возвращается также в заголовке ETag",
+                        "example": 3
+                    },
+                    "score": {
+                        "type": "number",
+                        "description": "Сходство с фильтрами group и song от 0 до 1, только при match=fuzzy",
+                        "example": 0.57
+                    }
+                }
+            },
+            "SongPage": {
+                "type": "object",
+                "properties": {
+                    "items": {
+                        "type": "array",
+                        "description": "Песни с полями из fields, по умолчанию без text",
+                        "items": {
+                            "$ref": "#/components/schemas/Song"
+                        }
+                    },
+                    "next_cursor": {
+                        "type": "string",
+                        "description": "Курсор следующей страницы, отсутствует на последней",
+                        "example": "eyJpZCI6NX0"
+                    },
+                    "total": {
+                        "type": "integer",
+                        "description": "Количество всех песен, подходящих под фильтры",
+                        "example": 42
+                    },
+                    "facets": {
+                        "$ref": "#/components/schemas/Facets"
+                    }
+                }
+            },
+            "Facets": {
+                "type": "object",
+                "description": "Счётчики по запрошенным в facets полям",
+                "additionalProperties": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/components/schemas/FacetCount"
+                    }
+                }
+            },
+            "FacetCount": {
+                "type": "object",
+                "properties": {
+                    "value": {
+                        "type": "string",
+                        "example": "Muse"
+                    },
+                    "count": {
+                        "type": "integer",
+                        "example": 12
+                    },
+                    "filter": {
+                        "type": "string",
+                        "description": "Ссылка на список, суженный до этого значения",
+                        "example": "/songs?facets=group&group=Muse"
+                    }
+                }
+            },
+            "SearchPage": {
+                "type": "object",
+                "properties": {
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/components/schemas/SearchResult"
+                        }
+                    },
+                    "facets": {
+                        "$ref": "#/components/schemas/Facets"
+                    }
+                }
+            },
+            "NewSong": {
+                "type": "object",
+                "required": [
+                    "group",
+                    "song"
+                ],
+                "properties": {
+                    "group": {
+                        "type": "string",
+                        "example": "Radiohead"
+                    },
+                    "song": {
+                        "type": "string",
+                        "example": "Creep"
+                    },
+                    "albumId": {
+                        "type": "integer",
+                        "description": "ID альбома",
+                        "example": 1
+                    },
+                    "track": {
+                        "type": "integer",
+                        "description": "Номер трека в альбоме, только вместе с albumId",
+                        "example": 2
+                    },
+                    "coverOf": {
+                        "type": "integer",
+                        "description": "ID оригинальной песни, если песня — кавер",
+                        "example": 3
+                    },
+                    "releaseDate": {
+                        "type": "string",
+                        "format": "date",
+                        "example": "1992-09-21"
+                    },
+                    "text": {
+                        "type": "string",
+                        "example": "When you were here before\nCouldn't look you in the eye\n"
+                    },
+                    "link": {
+                        "type": "string",
+                        "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
+                    }
+                }
+            },
+            "info": {
+                "type": "object",
+                "required": [
+                    "group",
+                    "song"
+                ],
+                "properties": {
+                    "releaseDate": {
+                        "type": "string",
+                        "format": "date",
+                        "example": "1992-09-21"
+                    },
+                    "text": {
+                        "type": "string",
+                        "example": "When you were here before\nCouldn't look you in the eye\n"
+                    },
+                    "link": {
+                        "type": "string",
+                        "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
+                    }
+                }
+            },
+            "UpdateReq": {
+                "type": "object",
+                "required": [
+                    "group",
+                    "song"
+                ],
+                "properties": {
+                    "group": {
+                        "type": "string",
+                        "example": "Radiohead"
+                    },
+                    "song": {
+                        "type": "string",
+                        "example": "Creep"
+                    },
+                    "albumId": {
+                        "type": "integer",
+                        "description": "ID альбома",
+                        "example": 1
+                    },
+                    "track": {
+                        "type": "integer",
+                        "description": "Номер трека в альбоме, только вместе с albumId",
+                        "example": 2
+                    },
+                    "coverOf": {
+                        "type": "integer",
+                        "description": "ID оригинальной песни, если песня — кавер",
+                        "example": 3
+                    },
+                    "releaseDate": {
+                        "type": "string",
+                        "format": "date",
+                        "example": "1992-09-21"
+                    },
+                    "text": {
+                        "type": "string",
+                        "example": "When you were here before\nCouldn't look you in the eye\n"
+                    },
+                    "link": {
+                        "type": "string",
+                        "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
+                    }
+                }
+            },
+            "SongPatch": {
+                "type": "object",
+                "properties": {
+                    "group": {
+                        "type": "string",
+                        "example": "Radiohead"
+                    },
+                    "song": {
+                        "type": "string",
+                        "example": "Creep"
+                    },
+                    "albumId": {
+                        "type": "integer",
+                        "nullable": true,
+                        "description": "ID альбома, null убирает песню из альбома вместе с номером трека",
+                        "example": 1
+                    },
+                    "track": {
+                        "type": "integer",
+                        "nullable": true,
+                        "example": 2
+                    },
+                    "coverOf": {
+                        "type": "integer",
+                        "nullable": true,
+                        "description": "ID оригинальной песни, null убирает ссылку на оригинал",
+                        "example": 3
+                    },
+                    "releaseDate": {
+                        "type": "string",
+                        "nullable": true,
+                        "example": "1992-09-21"
+                    },
+                    "text": {
+                        "type": "string",
+                        "nullable": true
+                    },
+                    "link": {
+                        "type": "string",
+                        "nullable": true,
+                        "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
+                    }
+                }
+            },
+            "UpdatedSong": {
+                "type": "object",
+                "required": [
+                    "group",
+                    "song"
+                ],
+                "properties": {
+                    "id": {
+                        "type": "integer",
+                        "example": 1
+                    },
+                    "group": {
+                        "type": "string",
+                        "example": "Radiohead"
+                    },
+                    "song": {
+                        "type": "string",
+                        "example": "Creep"
+                    },
+                    "albumId": {
+                        "type": "integer",
+                        "description": "ID альбома",
+                        "example": 1
+                    },
+                    "track": {
+                        "type": "integer",
+                        "description": "Номер трека в альбоме, только вместе с albumId",
+                        "example": 2
+                    },
+                    "coverOf": {
+                        "type": "integer",
+                        "description": "ID оригинальной песни, если песня — кавер",
+                        "example": 3
+                    },
+                    "releaseDate": {
+                        "type": "string",
+                        "format": "date",
+                        "example": "1992-09-21"
+                    },
+                    "text": {
+                        "type": "string",
+                        "example": "When you were here before\nCouldn't look you in the eye\n"
+                    },
+                    "link": {
+                        "type": "string",
+                        "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
+                    },
+                    "version": {
+                        "type": "integer",
+                        "example": 4
+                    }
+                }
+            },
+            "SearchResult": {
+                "allOf": [
+                    {
+                        "$ref": "#/components/schemas/Song"
+                    },
+                    {
+                        "type": "object",
+                        "properties": {
+                            "rank": {
+                                "type": "number",
+                                "description": "Релевантность, больше - лучше",
+                                "example": 0.61
+                            },
+                            "match": {
+                                "$ref": "#/components/schemas/VerseMatch"
                             }
                         }
+                    }
+                ]
+            },
+            "VerseMatch": {
+                "type": "object",
+                "description": "Куплет, лучше всего совпавший с запросом. Отсутствует, если совпали только название или группа",
+                "properties": {
+                    "number": {
+                        "type": "integer",
+                        "example": 2
                     },
-                    "404": {
-                        "description": "Песня не найдена",
-                        "content": {
-                            "application/json": {
-                                "schema": {
-                                    "$ref": "#/components/schemas/Error"
-                                }
+                    "type": {
+                        "type": "string",
+                        "example": "chorus"
+                    },
+                    "label": {
+                        "type": "string",
+                        "example": "Chorus"
+                    },
+                    "snippet": {
+                        "type": "string",
+                        "description": "HTML: текст песни экранирован, совпадения выделены тегами <b>",
+                        "example": "Glaciers melting in the <b>dead</b> of <b>night</b>"
+                    }
+                }
+            },
+            "TrashedSong": {
+                "allOf": [
+                    {
+                        "$ref": "#/components/schemas/Song"
+                    },
+                    {
+                        "type": "object",
+                        "properties": {
+                            "deletedAt": {
+                                "type": "string",
+                                "format": "date-time",
+                                "example": "2024-05-01T12:00:00Z"
                             }
                         }
+                    }
+                ]
+            },
+            "SongRevision": {
+                "type": "object",
+                "properties": {
+                    "revision": {
+                        "type": "integer",
+                        "example": 2
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка обработки запроса",
-                        "content": {
-                            "application/json": {
-                                "schema": {
-                                    "$ref": "#/components/schemas/Error"
+                    "operation": {
+                        "type": "string",
+                        "enum": [
+                            "create",
+                            "update",
+                            "delete",
+                            "revert",
+                            "restore"
+                        ],
+                        "example": "update"
+                    },
+                    "actor": {
+                        "type": "string",
+                        "description": "Значение заголовка X-Actor запроса, изменившего песню",
+                        "example": "editor@example.com"
+                    },
+                    "createdAt": {
+                        "type": "string",
+                        "format": "date-time",
+                        "example": "2024-05-01T12:00:00Z"
+                    },
+                    "song": {
+                        "$ref": "#/components/schemas/Song"
+                    }
+                }
+            },
+            "RevisionDiff": {
+                "type": "object",
+                "properties": {
+                    "song_id": {
+                        "type": "integer",
+                        "example": 1
+                    },
+                    "from": {
+                        "type": "integer",
+                        "example": 1
+                    },
+                    "to": {
+                        "type": "integer",
+                        "example": 3
+                    },
+                    "changes": {
+                        "type": "array",
+                        "items": {
+                            "type": "object",
+                            "properties": {
+                                "field": {
+                                    "type": "string",
+                                    "example": "link"
+                                },
+                                "from": {
+                                    "example": "https://www.youtube.com/watch?v=Xsp3_a-PMTw"
+                                },
+                                "to": {
+                                    "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
                                 }
                             }
                         }
                     }
                 }
-            }
-        }
-    },
-    "components": {
-        "schemas": {
-            "Song": {
+            },
+            "Verse": {
                 "type": "object",
-                "required": ["group", "song"],
                 "properties": {
-                    "id": {
+                    "number": {
                         "type": "integer",
-                        "example": 1
+                        "example": 3
                     },
-                    "group": {
+                    "type": {
                         "type": "string",
-                        "example": "Muse"
+                        "enum": [
+                            "verse",
+                            "chorus",
+                            "pre-chorus",
+                            "bridge",
+                            "intro",
+                            "outro",
+                            "hook",
+                            "other"
+                        ],
+                        "example": "chorus"
                     },
-                    "song": {
+                    "label": {
                         "type": "string",
-                        "example": "Supermassive Black Hole"
+                        "example": "Chorus"
                     },
-                    "releaseDate": {
+                    "text": {
                         "type": "string",
-                        "format": "date",
-                        "example": "2006-07-16"
+                        "example": "Ooh baby, don't you know I suffer?"
+                    }
+                }
+            },
+            "VersePage": {
+                "type": "object",
+                "properties": {
+                    "song_id": {
+                        "type": "integer",
+                        "example": 1
                     },
-                    "text": {
+                    "verses": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/components/schemas/Verse"
+                        }
+                    },
+                    "total_verses": {
+                        "type": "integer",
+                        "example": 7
+                    },
+                    "page": {
+                        "type": "integer",
+                        "example": 2
+                    },
+                    "limit": {
+                        "type": "integer",
+                        "example": 2
+                    },
+                    "next": {
                         "type": "string",
-                        "example": "Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?"
+                        "example": "/songs/1/text?page=3&limit=2"
                     },
-                    "link": {
+                    "prev": {
                         "type": "string",
-                        "example": "https://www.youtube.com/watch?v=Xsp3_a-PMTw"
+                        "example": "/songs/1/text?page=1&limit=2"
                     }
                 }
             },
-			"info": {
+            "Artist": {
                 "type": "object",
-                "required": ["group", "song"],
                 "properties": {
-                    "releaseDate": {
-                        "type": "string",
-                        "format": "date",
-                        "example": "1992-09-21"
+                    "id": {
+                        "type": "integer",
+                        "example": 1
                     },
-                    "text": {
+                    "name": {
                         "type": "string",
-                        "example": "When you were here before\nCouldn't look you in the eye"
+                        "example": "Muse"
                     },
-                    "link": {
+                    "songCount": {
+                        "type": "integer",
+                        "description": "Количество песен группы, не считая песен в корзине",
+                        "example": 12
+                    }
+                }
+            },
+            "ArtistRename": {
+                "type": "object",
+                "required": [
+                    "name"
+                ],
+                "properties": {
+                    "name": {
                         "type": "string",
-                        "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
+                        "example": "Muse"
                     }
                 }
             },
-			"NewSong": {
+            "ArtistMerge": {
+                "type": "object",
+                "required": [
+                    "from"
+                ],
+                "properties": {
+                    "from": {
+                        "type": "integer",
+                        "description": "ID группы, песни которой переносятся",
+                        "example": 2
+                    }
+                }
+            },
+            "Album": {
                 "type": "object",
-                "required": ["group", "song"],
                 "properties": {
+                    "id": {
+                        "type": "integer",
+                        "example": 1
+                    },
+                    "title": {
+                        "type": "string",
+                        "example": "Black Holes and Revelations"
+                    },
                     "group": {
                         "type": "string",
                         "example": "Muse"
                     },
-                    "song": {
-                        "type": "string",
-                        "example": "Supermassive Black Hole"
+                    "artistId": {
+                        "type": "integer",
+                        "description": "ID группы альбома",
+                        "example": 1
                     },
                     "releaseDate": {
                         "type": "string",
                         "format": "date",
-                        "example": "2006-07-16"
+                        "example": "2006-07-03"
                     },
-                    "text": {
+                    "coverLink": {
                         "type": "string",
-                        "example": "Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?"
+                        "example": "https://example.com/covers/bhar.jpg"
                     },
-                    "link": {
-                        "type": "string",
-                        "example": "https://www.youtube.com/watch?v=Xsp3_a-PMTw"
+                    "songCount": {
+                        "type": "integer",
+                        "description": "Количество песен альбома, не считая песен в корзине",
+                        "example": 11
                     }
                 }
             },
-            "UpdateReq": {
+            "NewAlbum": {
                 "type": "object",
-                "required": ["group", "song"],
+                "required": [
+                    "title",
+                    "group"
+                ],
                 "properties": {
-                    "group": {
+                    "title": {
                         "type": "string",
-                        "example": "Radiohead"
+                        "example": "Black Holes and Revelations"
                     },
-                    "song": {
+                    "group": {
                         "type": "string",
-                        "example": "Creep"
+                        "example": "Muse"
                     },
                     "releaseDate": {
                         "type": "string",
                         "format": "date",
-                        "example": "1992-09-21"
+                        "example": "2006-07-03"
                     },
-                    "text": {
+                    "coverLink": {
+                        "type": "string",
+                        "example": "https://example.com/covers/bhar.jpg"
+                    }
+                }
+            },
+            "Credit": {
+                "type": "object",
+                "properties": {
+                    "artistId": {
+                        "type": "integer",
+                        "example": 2
+                    },
+                    "name": {
                         "type": "string",
-                        "example": "When you were here before\nCouldn't look you in the eye"
+                        "example": "Rihanna"
                     },
-                    "link": {
+                    "role": {
                         "type": "string",
-                        "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
+                        "enum": [
+                            "main",
+                            "featuring",
+                            "producer"
+                        ],
+                        "example": "featuring"
                     }
                 }
             },
-            "UpdatedSong": {
+            "NewCredit": {
+                "type": "object",
+                "required": [
+                    "name",
+                    "role"
+                ],
+                "properties": {
+                    "name": {
+                        "type": "string",
+                        "example": "Rihanna"
+                    },
+                    "role": {
+                        "type": "string",
+                        "enum": [
+                            "featuring",
+                            "producer"
+                        ],
+                        "example": "featuring"
+                    }
+                }
+            },
+            "Tag": {
+                "type": "object",
+                "properties": {
+                    "name": {
+                        "type": "string",
+                        "example": "rock"
+                    },
+                    "songCount": {
+                        "type": "integer",
+                        "description": "Количество песен с тегом, не считая песен в корзине",
+                        "example": 12
+                    }
+                }
+            },
+            "SongTags": {
+                "type": "object",
+                "required": [
+                    "tags"
+                ],
+                "properties": {
+                    "tags": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "maxLength": 64,
+                        "example": [
+                            "rock",
+                            "90s"
+                        ]
+                    }
+                }
+            },
+            "Playlist": {
                 "type": "object",
-                "required": ["group", "song"],
                 "properties": {
                     "id": {
                         "type": "integer",
                         "example": 1
                     },
-                    "group": {
+                    "name": {
                         "type": "string",
-                        "example": "Radiohead"
+                        "example": "Road trip"
                     },
-                    "song": {
-                        "type": "string",
-                        "example": "Creep"
+                    "entryCount": {
+                        "type": "integer",
+                        "description": "Количество записей, не считая песен в корзине",
+                        "example": 12
                     },
-                    "releaseDate": {
+                    "createdAt": {
                         "type": "string",
-                        "format": "date",
-                        "example": "1992-09-21"
+                        "format": "date-time",
+                        "example": "2024-05-01T12:00:00Z"
                     },
-                    "text": {
+                    "updatedAt": {
+                        "type": "string",
+                        "format": "date-time",
+                        "example": "2024-05-02T08:30:00Z"
+                    }
+                }
+            },
+            "PlaylistName": {
+                "type": "object",
+                "required": [
+                    "name"
+                ],
+                "properties": {
+                    "name": {
                         "type": "string",
-                        "example": "When you were here before\nCouldn't look you in the eye"
+                        "example": "Road trip"
+                    }
+                }
+            },
+            "PlaylistEntry": {
+                "type": "object",
+                "properties": {
+                    "id": {
+                        "type": "integer",
+                        "description": "ID записи, различает записи одной песни",
+                        "example": 7
                     },
-                    "link": {
+                    "position": {
+                        "type": "integer",
+                        "description": "Позиция в плейлисте, с 1",
+                        "example": 3
+                    },
+                    "addedAt": {
                         "type": "string",
-                        "example": "https://www.youtube.com/watch?v=XFkzRNyygfk"
+                        "format": "date-time",
+                        "example": "2024-05-01T12:00:00Z"
+                    },
+                    "song": {
+                        "$ref": "#/components/schemas/Song"
+                    }
+                }
+            },
+            "NewPlaylistEntry": {
+                "type": "object",
+                "required": [
+                    "songId"
+                ],
+                "properties": {
+                    "songId": {
+                        "type": "integer",
+                        "example": 1
+                    },
+                    "position": {
+                        "type": "integer",
+                        "description": "Позиция с 1, без нее песня добавляется в конец",
+                        "example": 2
+                    }
+                }
+            },
+            "PlaylistEntryMove": {
+                "type": "object",
+                "required": [
+                    "position"
+                ],
+                "properties": {
+                    "position": {
+                        "type": "integer",
+                        "description": "Новая позиция с 1",
+                        "example": 1
                     }
                 }
             },
@@ -559,6 +3894,25 @@ const docTemplate = `{
                         "example": "Ошибка сервера"
                     }
                 }
+            },
+            "QueryError": {
+                "type": "object",
+                "properties": {
+                    "error": {
+                        "type": "string",
+                        "example": "q: unknown field, expected one of enriched, group, id, link, release_date, song, text, version, year at position 16 near \"yaer\""
+                    },
+                    "position": {
+                        "type": "integer",
+                        "description": "Позиция токена в q, считая символы с 1",
+                        "example": 16
+                    },
+                    "token": {
+                        "type": "string",
+                        "description": "Токен, на котором разбор остановился, пустой в конце выражения",
+                        "example": "yaer"
+                    }
+                }
             }
         }
     }
@@ -0,0 +1,72 @@
+package enrichment
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("closed breaker: allow() = %v", err)
+	}
+	b.failure()
+	if err := b.allow(); err != nil {
+		t.Fatalf("one failure below the threshold: allow() = %v", err)
+	}
+	b.failure()
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("threshold reached: allow() = %v, want ErrCircuitOpen", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("cooldown elapsed: allow() = %v, want a probe", err)
+	}
+	if b.state != stateHalfOpen {
+		t.Fatalf("state = %v, want half-open", b.state)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("half-open with a probe in flight: allow() = %v, want ErrCircuitOpen", err)
+	}
+
+	// a failed probe opens the breaker again for a full cooldown
+	b.failure()
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("failed probe: allow() = %v, want ErrCircuitOpen", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("second cooldown elapsed: allow() = %v", err)
+	}
+	b.success()
+	if b.state != stateClosed || b.failures != 0 {
+		t.Fatalf("successful probe: state = %v, failures = %d, want closed with no failures", b.state, b.failures)
+	}
+	b.failure()
+	if err := b.allow(); err != nil {
+		t.Fatalf("failure count restarted after closing: allow() = %v", err)
+	}
+}
+
+func TestBreakerAbandonedProbe(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("cooldown elapsed: allow() = %v", err)
+	}
+	// the caller gave up, so the next call may probe right away
+	b.abandon()
+	if err := b.allow(); err != nil {
+		t.Fatalf("after an abandoned probe: allow() = %v, want a new probe", err)
+	}
+}
@@ -0,0 +1,154 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrNotFound = errors.New("song info not found upstream")
+
+type Options struct {
+	Timeout          time.Duration
+	Retries          int
+	BackoffBase      time.Duration
+	BackoffMax       time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	HTTPClient       *http.Client
+}
+
+// Client fetches song details from the external music info API described in the README (GET /info?group=&song=).
+type Client struct {
+	baseURL string
+	http    *http.Client
+	opts    Options
+	breaker *breaker
+	logger  *zap.SugaredLogger
+}
+
+func NewClient(logger *zap.SugaredLogger, baseURL string, opts Options) *Client {
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.BackoffMax < opts.BackoffBase {
+		opts.BackoffMax = opts.BackoffBase
+	}
+	logger.Debugw("Initializing enrichment client", "baseURL", baseURL, "timeout", opts.Timeout, "retries", opts.Retries)
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    httpClient,
+		opts:    opts,
+		breaker: newBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+		logger:  logger,
+	}
+}
+
+// FetchInfo asks the upstream for the details of a song, retrying transient failures with exponential backoff.
+// ErrNotFound is returned when the upstream does not know the song, ErrCircuitOpen when it is considered down.
+func (c *Client) FetchInfo(ctx context.Context, group, song string) (model.SongDetail, error) {
+	if err := c.breaker.allow(); err != nil {
+		c.logger.Debugw("Skipping enrichment request", "group", group, "song", song, "error", err)
+		return model.SongDetail{}, err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.opts.Retries; attempt++ {
+		if attempt > 0 {
+			if err := c.sleep(ctx, attempt); err != nil {
+				c.breaker.abandon()
+				return model.SongDetail{}, err
+			}
+		}
+
+		detail, retry, err := c.fetchOnce(ctx, group, song)
+		if err == nil {
+			c.breaker.success()
+			return detail, nil
+		}
+		if ctx.Err() != nil {
+			c.breaker.abandon()
+			return model.SongDetail{}, ctx.Err()
+		}
+		if !retry {
+			// the upstream answered, it is just not able to help with this song
+			c.breaker.success()
+			return model.SongDetail{}, err
+		}
+		c.logger.Debugw("Enrichment request failed", "attempt", attempt+1, "group", group, "song", song, "error", err)
+		lastErr = err
+	}
+
+	c.breaker.failure()
+	c.logger.Info(zap.Error(lastErr))
+	return model.SongDetail{}, lastErr
+}
+
+func (c *Client) fetchOnce(ctx context.Context, group, song string) (model.SongDetail, bool, error) {
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
+	query := url.Values{}
+	query.Set("group", group)
+	query.Set("song", song)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/info?"+query.Encode(), nil)
+	if err != nil {
+		return model.SongDetail{}, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return model.SongDetail{}, true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNotFound:
+		return model.SongDetail{}, false, ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return model.SongDetail{}, true, fmt.Errorf("upstream responded with status %d", resp.StatusCode)
+	default:
+		return model.SongDetail{}, false, fmt.Errorf("upstream responded with status %d", resp.StatusCode)
+	}
+
+	// a body that is not the expected JSON would come back the same on a retry
+	var detail model.SongDetail
+	if err = json.NewDecoder(resp.Body).Decode(&detail); err != nil {
+		return model.SongDetail{}, false, fmt.Errorf("decode upstream response: %w", err)
+	}
+	return detail, false, nil
+}
+
+func (c *Client) sleep(ctx context.Context, attempt int) error {
+	delay := c.opts.BackoffBase << (attempt - 1)
+	if delay <= 0 || delay > c.opts.BackoffMax {
+		delay = c.opts.BackoffMax
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,182 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts Options) (*Client, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	if opts.BackoffBase == 0 {
+		opts.BackoffBase = time.Millisecond
+	}
+	if opts.BreakerThreshold == 0 {
+		opts.BreakerThreshold = 5
+	}
+	if opts.BreakerCooldown == 0 {
+		opts.BreakerCooldown = time.Hour
+	}
+	return NewClient(zap.NewNop().Sugar(), srv.URL, opts), &calls
+}
+
+func TestFetchInfo(t *testing.T) {
+	c, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/info" || r.URL.Query().Get("group") != "Muse" || r.URL.Query().Get("song") != "Supermassive Black Hole" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"releaseDate":"16.07.2006","text":"Ooh baby","link":"https://example.com"}`))
+	}, Options{Retries: 2})
+
+	detail, err := c.FetchInfo(context.Background(), "Muse", "Supermassive Black Hole")
+	if err != nil {
+		t.Fatalf("FetchInfo: %v", err)
+	}
+	if detail.ReleaseDate != "16.07.2006" || detail.Text != "Ooh baby" || detail.Link != "https://example.com" {
+		t.Errorf("FetchInfo = %+v", detail)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("upstream called %d times, want 1", n)
+	}
+}
+
+func TestFetchInfoRetriesServerErrors(t *testing.T) {
+	var failures atomic.Int32
+	c, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"text":"Ooh baby"}`))
+	}, Options{Retries: 2})
+
+	detail, err := c.FetchInfo(context.Background(), "Muse", "Supermassive Black Hole")
+	if err != nil {
+		t.Fatalf("FetchInfo: %v", err)
+	}
+	if detail.Text != "Ooh baby" {
+		t.Errorf("FetchInfo text = %q", detail.Text)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("upstream called %d times, want 3", n)
+	}
+}
+
+func TestFetchInfoGivesUpAfterRetries(t *testing.T) {
+	c, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}, Options{Retries: 2})
+
+	if _, err := c.FetchInfo(context.Background(), "Muse", "Uprising"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("FetchInfo error = %v, want an upstream error", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("upstream called %d times, want 3", n)
+	}
+}
+
+func TestFetchInfoNotFound(t *testing.T) {
+	c, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}, Options{Retries: 2, BreakerThreshold: 1})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.FetchInfo(context.Background(), "Muse", "Unknown"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("FetchInfo error = %v, want ErrNotFound", err)
+		}
+	}
+	// a 404 is an answer: it is neither retried nor counted against the breaker
+	if n := calls.Load(); n != 2 {
+		t.Errorf("upstream called %d times, want 2", n)
+	}
+}
+
+func TestFetchInfoClientErrorIsNotRetried(t *testing.T) {
+	c, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}, Options{Retries: 2})
+
+	if _, err := c.FetchInfo(context.Background(), "Muse", "Uprising"); err == nil {
+		t.Fatal("FetchInfo succeeded on a 400")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("upstream called %d times, want 1", n)
+	}
+}
+
+func TestFetchInfoInvalidBodyIsNotRetried(t *testing.T) {
+	c, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"releaseDate":16}`))
+	}, Options{Retries: 2})
+
+	if _, err := c.FetchInfo(context.Background(), "Muse", "Uprising"); err == nil {
+		t.Fatal("FetchInfo succeeded on an invalid body")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("upstream called %d times, want 1", n)
+	}
+}
+
+func TestFetchInfoOpensBreaker(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	c, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"text":"Ooh baby"}`))
+	}, Options{Retries: 1, BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.FetchInfo(context.Background(), "Muse", "Uprising"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: FetchInfo error = %v, want an upstream error", i+1, err)
+		}
+	}
+	if _, err := c.FetchInfo(context.Background(), "Muse", "Uprising"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("FetchInfo error = %v, want ErrCircuitOpen", err)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("upstream called %d times, want 4", n)
+	}
+
+	down.Store(false)
+	now = now.Add(time.Minute)
+	if _, err := c.FetchInfo(context.Background(), "Muse", "Uprising"); err != nil {
+		t.Fatalf("probe after cooldown: %v", err)
+	}
+	if _, err := c.FetchInfo(context.Background(), "Muse", "Uprising"); err != nil {
+		t.Fatalf("after the breaker closed: %v", err)
+	}
+	if n := calls.Load(); n != 6 {
+		t.Errorf("upstream called %d times, want 6", n)
+	}
+}
+
+func TestFetchInfoStopsOnCancel(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, Options{Retries: 3, BackoffBase: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.FetchInfo(ctx, "Muse", "Uprising"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("FetchInfo error = %v, want context.DeadlineExceeded", err)
+	}
+}
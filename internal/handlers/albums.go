@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetAlbums lists albums by release date, only the ones of a group with the artistId parameter.
+func (r *Handler) GetAlbums(c echo.Context) error {
+	limit, offset := r.pagination(c)
+	artistID := 0
+	if param := c.QueryParam("artistId"); param != "" {
+		var err error
+		if artistID, err = strconv.Atoi(param); err != nil || artistID < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "artistId: expected a group ID"})
+		}
+	}
+
+	r.log.Debugw("Fetching albums", "artistID", artistID, "limit", limit, "offset", offset)
+	albums, err := r.DB.GetAlbums(c.Request().Context(), artistID, limit, offset)
+	if err != nil {
+		r.log.Errorw("Failed to fetch albums", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch albums",
+		})
+	}
+	return c.JSON(http.StatusOK, albums)
+}
+
+func (r *Handler) GetAlbum(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid album ID"})
+	}
+
+	r.log.Debugw("Fetching album", "id", id)
+	album, err := r.DB.GetAlbum(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch album", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Album not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch album"})
+	}
+	return c.JSON(http.StatusOK, album)
+}
+
+func (r *Handler) AddAlbum(c echo.Context) error {
+	album, err := bindAlbum(c)
+	if err != nil {
+		r.log.Errorw("Invalid album", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	r.log.Debugw("Adding new album", "album", album)
+	album, err = r.DB.AddAlbum(c.Request().Context(), album)
+	if err != nil {
+		r.log.Errorw("Failed to add album", "error", err)
+		if errors.Is(err, storage.ErrAlbumExists) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "The group already has an album with this title"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add album"})
+	}
+	return c.JSON(http.StatusOK, album)
+}
+
+func (r *Handler) UpdateAlbum(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid album ID"})
+	}
+	album, err := bindAlbum(c)
+	if err != nil {
+		r.log.Errorw("Invalid album", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	album.ID = id
+
+	r.log.Debugw("Updating album", "album", album)
+	album, err = r.DB.UpdateAlbum(c.Request().Context(), album)
+	if err != nil {
+		r.log.Errorw("Failed to update album", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Album not found"})
+		}
+		if errors.Is(err, storage.ErrAlbumExists) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "The group already has an album with this title"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update album"})
+	}
+	return c.JSON(http.StatusOK, album)
+}
+
+// DeleteAlbum removes an album, its songs stay without one.
+func (r *Handler) DeleteAlbum(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid album ID"})
+	}
+
+	r.log.Debugw("Deleting album", "id", id)
+	if err := r.DB.DeleteAlbum(c.Request().Context(), id); err != nil {
+		r.log.Errorw("Failed to delete album", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Album not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete album"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetAlbumSongs lists the songs of an album by track number.
+func (r *Handler) GetAlbumSongs(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid album ID"})
+	}
+
+	r.log.Debugw("Fetching songs of album", "id", id)
+	songs, err := r.DB.GetAlbumSongs(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch songs of album", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Album not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch songs of album"})
+	}
+	return c.JSON(http.StatusOK, songs)
+}
+
+// bindAlbum reads and validates the album in the request body.
+func bindAlbum(c echo.Context) (model.Album, error) {
+	var album model.Album
+	if err := c.Bind(&album); err != nil {
+		return album, err
+	}
+	var missing []string
+	if strings.TrimSpace(album.Title) == "" {
+		missing = append(missing, "title")
+	}
+	if strings.TrimSpace(album.Group) == "" {
+		missing = append(missing, "group")
+	}
+	if len(missing) > 0 {
+		return album, fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	date, err := model.NormalizeDate(album.ReleaseDate)
+	if err != nil {
+		return album, err
+	}
+	album.ReleaseDate = date
+	return album, nil
+}
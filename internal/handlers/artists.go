@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (r *Handler) GetArtists(c echo.Context) error {
+	limit, offset := r.pagination(c)
+
+	r.log.Debugw("Fetching artists", "limit", limit, "offset", offset)
+	artists, err := r.DB.GetArtists(c.Request().Context(), limit, offset)
+	if err != nil {
+		r.log.Errorw("Failed to fetch artists", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch groups",
+		})
+	}
+	return c.JSON(http.StatusOK, artists)
+}
+
+func (r *Handler) GetArtist(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid group ID"})
+	}
+
+	r.log.Debugw("Fetching artist", "id", id)
+	artist, err := r.DB.GetArtist(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch artist", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Group not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch group"})
+	}
+	return c.JSON(http.StatusOK, artist)
+}
+
+// RenameArtist renames a group, the songs of the group follow the new name.
+func (r *Handler) RenameArtist(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid group ID"})
+	}
+	var rename model.ArtistRename
+	if err := c.Bind(&rename); err != nil {
+		r.log.Errorw("Failed to bind artist rename", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if strings.TrimSpace(rename.Name) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "missing required fields: name"})
+	}
+
+	r.log.Debugw("Renaming artist", "id", id, "name", rename.Name)
+	artist, err := r.DB.RenameArtist(c.Request().Context(), id, rename.Name)
+	if err != nil {
+		r.log.Errorw("Failed to rename artist", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Group not found"})
+		}
+		if errors.Is(err, storage.ErrArtistExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Another group has this name, merge the groups instead",
+			})
+		}
+		if errors.Is(err, storage.ErrSongExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Another song with this group and name exists",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to rename group"})
+	}
+	return c.JSON(http.StatusOK, artist)
+}
+
+// MergeArtists moves the songs of another group to this one and removes the other group.
+func (r *Handler) MergeArtists(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid group ID"})
+	}
+	var merge model.ArtistMerge
+	if err := c.Bind(&merge); err != nil {
+		r.log.Errorw("Failed to bind artist merge", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if merge.From <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "missing required fields: from"})
+	}
+	if merge.From == id {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "A group cannot be merged into itself"})
+	}
+
+	r.log.Debugw("Merging artists", "id", id, "from", merge.From)
+	artist, err := r.DB.MergeArtists(c.Request().Context(), id, merge.From)
+	if err != nil {
+		r.log.Errorw("Failed to merge artists", "id", id, "from", merge.From, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Group not found"})
+		}
+		if errors.Is(err, storage.ErrSongExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Both groups have a song with the same name",
+			})
+		}
+		if errors.Is(err, storage.ErrAlbumExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Both groups have an album with the same title",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to merge groups"})
+	}
+	return c.JSON(http.StatusOK, artist)
+}
+
+// idParam reads the ID of a group, album or playlist from the path.
+func idParam(c echo.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	return id, err == nil && id > 0
+}
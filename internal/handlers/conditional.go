@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+)
+
+var errInvalidIfMatch = errors.New("If-Match must be \"*\" or a single strong ETag returned by GET /songs/:id")
+
+func songETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion reads the song version the client expects from the If-Match header.
+// It returns 0 when the header is absent or "*", meaning the write is unconditional.
+func ifMatchVersion(c echo.Context) (int, error) {
+	header := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) || len(header) < 3 {
+		return 0, errInvalidIfMatch
+	}
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil || version < 1 {
+		return 0, errInvalidIfMatch
+	}
+	return version, nil
+}
+
+func preconditionFailed(c echo.Context) error {
+	return c.JSON(http.StatusPreconditionFailed, map[string]string{
+		"error": "Song was modified by someone else, fetch it again and retry",
+	})
+}
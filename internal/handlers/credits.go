@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// creditedSong is the response of GET /songs/{id}: the song with all the artists credited on it.
+type creditedSong struct {
+	model.Song
+	Artists []model.Credit `json:"artists"`
+}
+
+// GetSongArtists lists the artists credited on a song, its group first with the role main.
+func (r *Handler) GetSongArtists(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid song ID"})
+	}
+
+	r.log.Debugw("Fetching artists of song", "id", id)
+	artists, err := r.DB.GetSongArtists(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch artists of song", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Song not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch artists of song"})
+	}
+	return c.JSON(http.StatusOK, artists)
+}
+
+// SetSongArtists replaces the artists credited on a song besides its group, the group is changed with the song.
+func (r *Handler) SetSongArtists(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid song ID"})
+	}
+	var credits []model.Credit
+	if err := c.Bind(&credits); err != nil {
+		r.log.Errorw("Failed to bind song artists", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := validateCredits(credits); err != nil {
+		r.log.Errorw("Invalid song artists", "credits", credits, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	r.log.Debugw("Crediting artists on song", "id", id, "credits", credits)
+	artists, err := r.DB.SetSongArtists(c.Request().Context(), id, credits)
+	if err != nil {
+		r.log.Errorw("Failed to credit artists on song", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Song not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to credit artists on song"})
+	}
+	return c.JSON(http.StatusOK, artists)
+}
+
+// GetSongCovers lists the covers of a song by ID.
+func (r *Handler) GetSongCovers(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid song ID"})
+	}
+
+	r.log.Debugw("Fetching covers of song", "id", id)
+	songs, err := r.DB.GetSongCovers(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch covers of song", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Song not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch covers of song"})
+	}
+	return c.JSON(http.StatusOK, songs)
+}
+
+// validateCredits checks the body of PUT /songs/{id}/artists, the artist ids in it are ignored.
+func validateCredits(credits []model.Credit) error {
+	for i, credit := range credits {
+		if strings.TrimSpace(credit.Name) == "" {
+			return fmt.Errorf("artist %d: missing required fields: name", i)
+		}
+		if !slices.Contains(model.CreditRoles, credit.Role) {
+			return fmt.Errorf("artist %d: role must be one of %s", i, strings.Join(model.CreditRoles, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+)
+
+const headerLink = "Link"
+
+// encodeCursor makes the opaque cursor clients pass back to get the next page.
+func encodeCursor(cursor model.SongCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads the cursor query parameter, nil when it is empty.
+func decodeCursor(s string) (*model.SongCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor model.SongCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID < 1 {
+		return nil, errors.New("cursor without a song ID")
+	}
+	return &cursor, nil
+}
+
+// setPageLinks sets the RFC 8288 Link header with the first page and, unless this is the last page, the next one.
+// The links keep the other query parameters of the request.
+func setPageLinks(c echo.Context, nextCursor string) {
+	link := func(cursor, rel string) string {
+		query := c.Request().URL.Query()
+		query.Del("cursor")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		u := url.URL{Path: c.Request().URL.Path, RawQuery: query.Encode()}
+		return "<" + u.String() + `>; rel="` + rel + `"`
+	}
+	c.Response().Header().Add(headerLink, link("", "first"))
+	if nextCursor != "" {
+		c.Response().Header().Add(headerLink, link(nextCursor, "next"))
+	}
+}
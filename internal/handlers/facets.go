@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// facetsParam reads the facets query parameter: comma separated model.Facets, none by default.
+func facetsParam(c echo.Context) ([]string, error) {
+	param := c.QueryParam("facets")
+	if param == "" {
+		return nil, nil
+	}
+	var facets []string
+	for _, f := range strings.Split(param, ",") {
+		f = strings.TrimSpace(f)
+		if !slices.Contains(model.Facets, f) {
+			return nil, fmt.Errorf("facets: expected facets of %s", strings.Join(model.Facets, ", "))
+		}
+		if !slices.Contains(facets, f) {
+			facets = append(facets, f)
+		}
+	}
+	return facets, nil
+}
+
+// setFacetFilters links every facet value to the list of the request narrowed to it by the group, year or
+// release_from and release_to query parameters. The links keep the other query parameters of the request
+// and start from the first page.
+func setFacetFilters(c echo.Context, facets map[string][]model.FacetCount) {
+	for facet, counts := range facets {
+		for i := range counts {
+			query := c.Request().URL.Query()
+			query.Del("cursor")
+			query.Del("page")
+			switch facet {
+			case model.FacetGroup:
+				query.Set("group", counts[i].Value)
+			case model.FacetYear:
+				query.Set("year", counts[i].Value)
+			case model.FacetDecade:
+				decade, _ := strconv.Atoi(counts[i].Value)
+				query.Set("release_from", fmt.Sprintf("%04d-01-01", decade))
+				query.Set("release_to", fmt.Sprintf("%04d-12-31", decade+9))
+			}
+			u := url.URL{Path: c.Request().URL.Path, RawQuery: query.Encode()}
+			counts[i].Filter = u.String()
+		}
+	}
+}
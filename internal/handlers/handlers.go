@@ -1,59 +1,352 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/query"
 	"go_test_effective_mobile/internal/storage"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
 
+// InfoProvider is the external music info API queried to enrich newly added songs.
+type InfoProvider interface {
+	FetchInfo(ctx context.Context, group, song string) (model.SongDetail, error)
+}
+
 type Handler struct {
 	log               *zap.SugaredLogger
 	DB                storage.IStorage
+	info              InfoProvider
 	limitParamDefault int
 	pageParamDefault  int
 	verseParamDefault int
+	verseLimitDefault int
+	maxLimit          int
+	trashRetention    time.Duration
 }
 
-func NewHandler(log *zap.SugaredLogger, limitParam, pageParam, verseParam int, endPointDB string) (*Handler, error) {
-	db := &storage.Storage{}
-	c := &Handler{log: log, DB: db, limitParamDefault: limitParam, pageParamDefault: pageParam, verseParamDefault: verseParam}
-	log.Debug("Initializing new handler with DB endpoint:", endPointDB)
-	return c, c.DB.InitStorage(log, endPointDB)
+// Config holds the settings of a Handler: the storage to open, the defaults and bounds of the
+// pagination parameters, how long deleted songs stay in the trash and the API enriching new songs, nil for none.
+type Config struct {
+	Driver            string
+	EndPointDB        string
+	DefaultLimit      int
+	DefaultPage       int
+	DefaultVerse      int
+	DefaultVerseLimit int
+	MaxLimit          int
+	TrashRetention    time.Duration
+	Info              InfoProvider
 }
 
-func (r *Handler) GetSongs(c echo.Context) error {
-	group := c.QueryParam("group")
-	song := c.QueryParam("song")
-	releaseDate := c.QueryParam("release_date")
+func NewHandler(log *zap.SugaredLogger, cfg Config) (*Handler, error) {
+	db, err := storage.NewStorage(cfg.Driver, cfg.EndPointDB)
+	if err != nil {
+		return nil, err
+	}
+	c := &Handler{
+		log:               log,
+		DB:                db,
+		info:              cfg.Info,
+		limitParamDefault: cfg.DefaultLimit,
+		pageParamDefault:  cfg.DefaultPage,
+		verseParamDefault: cfg.DefaultVerse,
+		verseLimitDefault: cfg.DefaultVerseLimit,
+		maxLimit:          cfg.MaxLimit,
+		trashRetention:    cfg.TrashRetention,
+	}
+	log.Debug("Initializing new handler with storage driver:", cfg.Driver, "DB endpoint:", cfg.EndPointDB)
+	return c, c.DB.InitStorage(log, cfg.EndPointDB)
+}
 
-	pageParam := c.QueryParam("page")
-	limitParam := c.QueryParam("limit")
+func (r *Handler) GetSongs(c echo.Context) error {
+	filter, err := r.songFilter(c)
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		r.log.Errorw("Invalid song query", "q", c.QueryParam("q"), "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error":    err.Error(),
+			"position": syntaxErr.Pos,
+			"token":    syntaxErr.Token,
+		})
+	}
+	if err != nil {
+		r.log.Errorw("Invalid song filter", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
-	page, err := strconv.Atoi(pageParam)
-	if err != nil || page < 1 {
-		page = r.limitParamDefault
+	facets, err := facetsParam(c)
+	if err != nil {
+		r.log.Errorw("Invalid facets", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
-	limit, err := strconv.Atoi(limitParam)
-	if err != nil || limit < 1 {
-		limit = r.pageParamDefault
+
+	after, err := decodeCursor(c.QueryParam("cursor"))
+	if err != nil {
+		r.log.Errorw("Invalid cursor", "cursor", c.QueryParam("cursor"), "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
 	}
-	offset := (page - 1) * limit
+	limit := r.limitParam(c, r.limitParamDefault)
 
-	r.log.Debugw("Fetching songs", "group", group, "song", song, "releaseDate", releaseDate, "limit", limit, "offset", offset)
-	songs, err := r.DB.GetSongs(c.Request().Context(), group, song, releaseDate, limit, offset)
+	// one more song than asked tells whether there is a next page
+	r.log.Debugw("Fetching songs", "filter", filter, "after", after, "limit", limit)
+	songs, total, err := r.DB.GetSongs(c.Request().Context(), filter, after, limit+1)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		r.log.Errorw("Cursor does not match the sort order", "cursor", c.QueryParam("cursor"), "sort", filter.Sort)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+	}
 	if err != nil {
 		r.log.Errorw("Failed to fetch songs", "error", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
+		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to fetch songs",
 		})
 	}
-	return c.JSON(http.StatusOK, songs)
+
+	page := model.SongPage{Total: total}
+	if len(songs) > limit {
+		songs = songs[:limit]
+		page.NextCursor = encodeCursor(model.CursorAfter(songs[limit-1], filter.Sort))
+	}
+	page.Items = make([]map[string]any, len(songs))
+	for i, song := range songs {
+		page.Items[i] = song.Pick(filter.Fields)
+	}
+
+	if len(facets) > 0 {
+		if page.Facets, err = r.DB.GetSongFacets(c.Request().Context(), filter, facets); err != nil {
+			r.log.Errorw("Failed to count song facets", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to fetch songs",
+			})
+		}
+		setFacetFilters(c, page.Facets)
+	}
+	setPageLinks(c, page.NextCursor)
+	return c.JSON(http.StatusOK, page)
+}
+
+// SearchSongs finds songs by words of their title, group or lyrics, best matches first.
+func (r *Handler) SearchSongs(c echo.Context) error {
+	search := model.SongSearch{Query: strings.TrimSpace(c.QueryParam("q"))}
+	if search.Query == "" {
+		r.log.Errorw("Search query is missing")
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "q is required"})
+	}
+	search, err := searchFilter(c, search)
+	if err != nil {
+		r.log.Errorw("Invalid search filter", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	facets, err := facetsParam(c)
+	if err != nil {
+		r.log.Errorw("Invalid facets", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	limit, offset := r.pagination(c)
+
+	r.log.Debugw("Searching songs", "search", search, "limit", limit, "offset", offset)
+	results, err := r.DB.SearchSongs(c.Request().Context(), search, limit, offset)
+	if err != nil {
+		r.log.Errorw("Failed to search songs", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to search songs",
+		})
+	}
+	if len(facets) == 0 {
+		return c.JSON(http.StatusOK, results)
+	}
+
+	counts, err := r.DB.SearchFacets(c.Request().Context(), search, facets)
+	if err != nil {
+		r.log.Errorw("Failed to count search facets", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to search songs",
+		})
+	}
+	setFacetFilters(c, counts)
+	return c.JSON(http.StatusOK, model.SearchPage{Items: results, Facets: counts})
+}
+
+// searchFilter reads the filters of GET /songs/search, the ones of GET /songs facet values link to.
+func searchFilter(c echo.Context, search model.SongSearch) (model.SongSearch, error) {
+	search.Group = c.QueryParam("group")
+	for _, d := range []struct {
+		param string
+		dst   *string
+	}{
+		{"release_from", &search.ReleaseFrom},
+		{"release_to", &search.ReleaseTo},
+	} {
+		date, err := model.NormalizeDate(c.QueryParam(d.param))
+		if err != nil {
+			return search, fmt.Errorf("%s: %w", d.param, err)
+		}
+		*d.dst = date
+	}
+	return search, yearParam(c, &search.ReleaseFrom, &search.ReleaseTo)
+}
+
+// pagination reads the page and limit query parameters of song lists and turns them into a limit and offset.
+func (r *Handler) pagination(c echo.Context) (limit, offset int) {
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	if err != nil || page < 1 {
+		page = r.pageParamDefault
+	}
+	limit = r.limitParam(c, r.limitParamDefault)
+	return limit, (page - 1) * limit
+}
+
+// limitParam reads the limit query parameter, def when it is missing or invalid, capped at the configured maximum.
+func (r *Handler) limitParam(c echo.Context, def int) int {
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit < 1 {
+		limit = def
+	}
+	if r.maxLimit > 0 && limit > r.maxLimit {
+		limit = r.maxLimit
+	}
+	return limit
+}
+
+// songFilter reads the filters of GET /songs. year is turned into a release date range
+// intersected with release_from and release_to. A q expression that cannot be parsed
+// is reported as a *query.SyntaxError.
+func (r *Handler) songFilter(c echo.Context) (model.SongFilter, error) {
+	filter := model.SongFilter{
+		Group: c.QueryParam("group"),
+		Song:  c.QueryParam("song"),
+	}
+	match, err := matchParam(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.Match = match
+	if filter.Sort, err = sortParam(c); err != nil {
+		return filter, err
+	}
+	if filter.Fields, err = fieldsParam(c); err != nil {
+		return filter, err
+	}
+	if q := strings.TrimSpace(c.QueryParam("q")); q != "" {
+		if filter.Query, err = query.Parse(q); err != nil {
+			return filter, err
+		}
+	}
+
+	dates := []struct {
+		param string
+		dst   *string
+	}{
+		{"release_date", &filter.ReleaseDate},
+		{"release_from", &filter.ReleaseFrom},
+		{"release_to", &filter.ReleaseTo},
+	}
+	for _, d := range dates {
+		date, err := model.NormalizeDate(c.QueryParam(d.param))
+		if err != nil {
+			return filter, fmt.Errorf("%s: %w", d.param, err)
+		}
+		*d.dst = date
+	}
+
+	if album := c.QueryParam("album"); album != "" {
+		if filter.Album, err = strconv.Atoi(album); err != nil || filter.Album < 1 {
+			return filter, errors.New("album: expected an album ID")
+		}
+	}
+	if artist := c.QueryParam("artist"); artist != "" {
+		if filter.Artist, err = strconv.Atoi(artist); err != nil || filter.Artist < 1 {
+			return filter, errors.New("artist: expected a group ID")
+		}
+	}
+	if filter.Tags, filter.TagMatch, err = tagParams(c); err != nil {
+		return filter, err
+	}
+
+	return filter, yearParam(c, &filter.ReleaseFrom, &filter.ReleaseTo)
+}
+
+// yearParam intersects the release date range from and to with the year query parameter.
+func yearParam(c echo.Context, from, to *string) error {
+	param := c.QueryParam("year")
+	if param == "" {
+		return nil
+	}
+	year, err := strconv.Atoi(param)
+	if err != nil || year < 1 || year > 9999 {
+		return errors.New("year: expected a number between 1 and 9999")
+	}
+	first, last := fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-12-31", year)
+	if *from == "" || *from < first {
+		*from = first
+	}
+	if *to == "" || *to > last {
+		*to = last
+	}
+	return nil
+}
+
+// matchParam reads how group and song names are compared, model.MatchExact by default.
+func matchParam(c echo.Context) (string, error) {
+	match := c.QueryParam("match")
+	if match == "" {
+		return model.MatchExact, nil
+	}
+	if !slices.Contains(model.MatchModes, match) {
+		return "", fmt.Errorf("match: expected one of %s", strings.Join(model.MatchModes, ", "))
+	}
+	return match, nil
+}
+
+// sortParam reads the sort query parameter: comma separated model.SortFields, descending ones prefixed with "-".
+func sortParam(c echo.Context) ([]model.SortField, error) {
+	param := c.QueryParam("sort")
+	if param == "" {
+		return nil, nil
+	}
+	var sort []model.SortField
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		f := model.SortField{Field: strings.TrimPrefix(name, "-"), Desc: strings.HasPrefix(name, "-")}
+		if !slices.Contains(model.SortFields, f.Field) {
+			return nil, fmt.Errorf("sort: expected fields of %s", strings.Join(model.SortFields, ", "))
+		}
+		if slices.ContainsFunc(sort, func(s model.SortField) bool { return s.Field == f.Field }) {
+			return nil, fmt.Errorf("sort: %s is repeated", f.Field)
+		}
+		sort = append(sort, f)
+	}
+	return sort, nil
+}
+
+// fieldsParam reads the fields query parameter: comma separated model.SongFields, model.ListFields by default.
+func fieldsParam(c echo.Context) ([]string, error) {
+	param := c.QueryParam("fields")
+	if param == "" {
+		return model.ListFields, nil
+	}
+	var fields []string
+	for _, f := range strings.Split(param, ",") {
+		f = strings.TrimSpace(f)
+		if !slices.Contains(model.SongFields, f) {
+			return nil, fmt.Errorf("fields: expected fields of %s", strings.Join(model.SongFields, ", "))
+		}
+		if !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
 }
 
 func (r *Handler) AddSong(c echo.Context) error {
@@ -63,17 +356,37 @@ func (r *Handler) AddSong(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
+	if err := validateSong(song); err != nil {
+		r.log.Errorw("Invalid song", "song", song, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	releaseDate, err := model.NormalizeDate(song.ReleaseDate)
+	if err != nil {
+		r.log.Errorw("Invalid release date", "releaseDate", song.ReleaseDate, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	song.ReleaseDate = releaseDate
+
+	song = r.enrichSong(c.Request().Context(), song)
+
 	r.log.Debugw("Adding new song", "song", song)
-	song, err := r.DB.AddSong(c.Request().Context(), song)
+	song, err = r.DB.AddSong(c.Request().Context(), song)
 	if err != nil {
 		r.log.Errorw("Failed to add song", "error", err)
-		if errors.Is(err, sql.ErrNoRows) {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "this song already exists"})
+		if errors.Is(err, storage.ErrSongExists) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "this song already exists"})
+		}
+		if errors.Is(err, storage.ErrAlbumNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Album not found"})
+		}
+		if errors.Is(err, storage.ErrOriginalNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Original song not found"})
 		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
 	r.log.Debug("Song added successfully", "song", song)
+	c.Response().Header().Set(headerETag, songETag(song.Version))
 	return c.JSON(http.StatusOK, song)
 }
 
@@ -94,8 +407,17 @@ func (r *Handler) GetSongByID(c echo.Context) error {
 		})
 	}
 
+	artists, err := r.DB.GetSongArtists(c.Request().Context(), song.ID)
+	if err != nil {
+		r.log.Errorw("Failed to fetch song artists", "id", id, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to extract the song",
+		})
+	}
+
 	r.log.Debug("Song fetched successfully", "song", song)
-	return c.JSON(http.StatusOK, song)
+	c.Response().Header().Set(headerETag, songETag(song.Version))
+	return c.JSON(http.StatusOK, creditedSong{Song: song, Artists: artists})
 }
 
 func (r *Handler) UpdateSong(c echo.Context) error {
@@ -114,9 +436,28 @@ func (r *Handler) UpdateSong(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 	song.ID = id
+	if err = validateSong(song); err != nil {
+		r.log.Errorw("Invalid song", "song", song, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if song.CoverOf == id {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "a song cannot be a cover of itself"})
+	}
+	releaseDate, err := model.NormalizeDate(song.ReleaseDate)
+	if err != nil {
+		r.log.Errorw("Invalid release date", "releaseDate", song.ReleaseDate, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	song.ReleaseDate = releaseDate
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		r.log.Errorw("Invalid If-Match header", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
-	r.log.Debugw("Updating song", "song", song)
-	song, err = r.DB.UpdateSong(c.Request().Context(), song)
+	r.log.Debugw("Updating song", "song", song, "version", version)
+	song, err = r.DB.UpdateSong(c.Request().Context(), song, version)
 	if err != nil {
 		r.log.Errorw("Failed to update song", "id", id, "error", err)
 		if errors.Is(err, sql.ErrNoRows) {
@@ -124,20 +465,109 @@ func (r *Handler) UpdateSong(c echo.Context) error {
 				"error": "Song not found",
 			})
 		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			return preconditionFailed(c)
+		}
+		if errors.Is(err, storage.ErrSongExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Another song with this group and name exists",
+			})
+		}
+		if errors.Is(err, storage.ErrAlbumNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Album not found"})
+		}
+		if errors.Is(err, storage.ErrOriginalNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Original song not found"})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to update song",
 		})
 	}
 
 	r.log.Debug("Song updated successfully", "song", song)
+	c.Response().Header().Set(headerETag, songETag(song.Version))
+	return c.JSON(http.StatusOK, song)
+}
+
+// PatchSong applies a JSON merge patch (RFC 7396): only the fields present in the body are changed,
+// null clears an optional field.
+func (r *Handler) PatchSong(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		r.log.Errorw("Invalid song ID", "id", idStr, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid song ID",
+		})
+	}
+
+	// a null document decodes without an error but leaves doc nil, it is not an object either
+	var doc map[string]json.RawMessage
+	if err = json.NewDecoder(c.Request().Body).Decode(&doc); err != nil || doc == nil {
+		r.log.Errorw("Failed to decode merge patch", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Request body must be a JSON object"})
+	}
+	patch, err := parseSongPatch(doc)
+	if err != nil {
+		r.log.Errorw("Invalid merge patch", "patch", doc, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if patch.CoverOf != nil && *patch.CoverOf == id {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "a song cannot be a cover of itself"})
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		r.log.Errorw("Invalid If-Match header", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	r.log.Debugw("Patching song", "id", id, "version", version, "patch", patch)
+	song, err := r.DB.PatchSong(c.Request().Context(), id, version, patch)
+	if err != nil {
+		r.log.Errorw("Failed to patch song", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Song not found",
+			})
+		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			return preconditionFailed(c)
+		}
+		if errors.Is(err, storage.ErrSongExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Another song with this group and name exists",
+			})
+		}
+		if errors.Is(err, storage.ErrAlbumNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Album not found"})
+		}
+		if errors.Is(err, storage.ErrOriginalNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Original song not found"})
+		}
+		if errors.Is(err, storage.ErrTrackNoAlbum) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "track needs an albumId"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update song",
+		})
+	}
+
+	r.log.Debug("Song patched successfully", "song", song)
+	c.Response().Header().Set(headerETag, songETag(song.Version))
 	return c.JSON(http.StatusOK, song)
 }
 
 func (r *Handler) DeleteSong(c echo.Context) error {
 	id := c.Param("id")
-	r.log.Debug("Deleting song by ID", "id", id)
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		r.log.Errorw("Invalid If-Match header", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	r.log.Debug("Deleting song by ID", "id", id, "version", version)
 
-	err := r.DB.DeleteSong(c.Request().Context(), id)
+	err = r.DB.DeleteSong(c.Request().Context(), id, version)
 	if err != nil {
 		r.log.Errorw("Failed to delete song", "id", id, "error", err)
 		if errors.Is(err, sql.ErrNoRows) {
@@ -145,6 +575,9 @@ func (r *Handler) DeleteSong(c echo.Context) error {
 				"error": "Song not found",
 			})
 		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			return preconditionFailed(c)
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to delete song",
 		})
@@ -177,6 +610,11 @@ func (r *Handler) GetSongVerseByID(c echo.Context) error {
 				"error": "Song not found",
 			})
 		}
+		if errors.Is(err, storage.ErrVerseNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Verse not found",
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to retrieve verse",
 		})
@@ -188,6 +626,64 @@ func (r *Handler) GetSongVerseByID(c echo.Context) error {
 	})
 }
 
+func (r *Handler) GetSongText(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		r.log.Errorw("Invalid song ID", "id", idStr, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid song ID",
+		})
+	}
+
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	if err != nil || page < 1 {
+		page = r.pageParamDefault
+	}
+	limit := r.limitParam(c, r.verseLimitDefault)
+
+	sectionType := c.QueryParam("type")
+	if sectionType != "" && !slices.Contains(model.SectionTypes, sectionType) {
+		r.log.Errorw("Invalid section type", "type", sectionType)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid section type, expected one of: " + strings.Join(model.SectionTypes, ", "),
+		})
+	}
+	collapse, _ := strconv.ParseBool(c.QueryParam("collapse"))
+
+	r.log.Debugw("Fetching song text", "id", id, "page", page, "limit", limit, "type", sectionType, "collapse", collapse)
+	verses, total, err := r.DB.GetSongVerses(c.Request().Context(), id, sectionType, collapse, limit, (page-1)*limit)
+	if err != nil {
+		r.log.Errorw("Failed to fetch song text", "id", id, "page", page, "limit", limit, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Song not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve song text",
+		})
+	}
+
+	res := model.VersePage{SongID: id, Verses: verses, TotalVerses: total, Page: page, Limit: limit}
+	pageLink := func(page int) string {
+		query := c.QueryParams()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		return c.Request().URL.Path + "?" + query.Encode()
+	}
+	if page*limit < total {
+		res.Next = pageLink(page + 1)
+	}
+	if page > 1 {
+		// a page past the end points back to the last existing one
+		res.Prev = pageLink(max(1, min(page-1, (total+limit-1)/limit)))
+	}
+
+	r.log.Debug("Song text fetched successfully", "page", res)
+	return c.JSON(http.StatusOK, res)
+}
+
 func (r *Handler) GetInfo(c echo.Context) error {
 	group := c.QueryParam("group")
 	song := c.QueryParam("song")
@@ -195,11 +691,21 @@ func (r *Handler) GetInfo(c echo.Context) error {
 		r.log.Errorw("Group or song parameters are missing")
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Group or song are required"})
 	}
+	match, err := matchParam(c)
+	if err != nil {
+		r.log.Errorw("Invalid match mode", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
-	r.log.Debugw("Fetching song info", "group", group, "song", song)
-	info, err := r.DB.GetInfo(c.Request().Context(), group, song)
+	r.log.Debugw("Fetching song info", "group", group, "song", song, "match", match)
+	info, err := r.DB.GetInfo(c.Request().Context(), group, song, match)
 	if err != nil {
 		r.log.Errorw("Failed to fetch song info", "group", group, "song", song, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Song not found",
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to fetch song info",
 		})
@@ -207,3 +713,136 @@ func (r *Handler) GetInfo(c echo.Context) error {
 	r.log.Debug("Song info fetched successfully", "info", info)
 	return c.JSON(http.StatusOK, info)
 }
+
+// enrichSong fills the fields the client left empty with the data of the external info API.
+// The song is still stored when the API is unavailable, it is just marked as not enriched.
+func (r *Handler) enrichSong(ctx context.Context, song model.Song) model.Song {
+	song.Enriched = false
+	if r.info == nil {
+		r.log.Debug("Info API is not configured, skipping enrichment")
+		return song
+	}
+
+	r.log.Debugw("Fetching song details from info API", "group", song.Group, "song", song.Song)
+	detail, err := r.info.FetchInfo(ctx, song.Group, song.Song)
+	if err != nil {
+		r.log.Warnw("Failed to enrich song, storing it as is", "group", song.Group, "song", song.Song, "error", err)
+		return song
+	}
+
+	if song.ReleaseDate == "" {
+		if song.ReleaseDate, err = model.NormalizeDate(detail.ReleaseDate); err != nil {
+			r.log.Warnw("Info API returned an invalid release date, ignoring it", "releaseDate", detail.ReleaseDate)
+		}
+	}
+	if song.Text == "" {
+		song.Text = detail.Text
+	}
+	if song.Link == "" {
+		song.Link = detail.Link
+	}
+	song.Enriched = true
+	return song
+}
+
+// validateSong checks the fields required for a full song representation.
+func validateSong(song model.Song) error {
+	var missing []string
+	if strings.TrimSpace(song.Group) == "" {
+		missing = append(missing, "group")
+	}
+	if strings.TrimSpace(song.Song) == "" {
+		missing = append(missing, "song")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	if song.CoverOf < 0 {
+		return errors.New("coverOf must be positive")
+	}
+	return validateTrack(song.AlbumID, song.Track)
+}
+
+// validateTrack checks the album and track number of a song, a track number needs an album.
+func validateTrack(albumID, track int) error {
+	switch {
+	case albumID < 0:
+		return errors.New("albumId must be positive")
+	case track < 0:
+		return errors.New("track must be positive")
+	case track > 0 && albumID == 0:
+		return errors.New("track needs an albumId")
+	}
+	return nil
+}
+
+// parseSongPatch turns a merge patch document into a SongPatch. Read-only and unknown members are rejected
+// so a typo does not silently turn into a no-op.
+func parseSongPatch(doc map[string]json.RawMessage) (model.SongPatch, error) {
+	var patch model.SongPatch
+	fields := map[string]**string{
+		"group":       &patch.Group,
+		"song":        &patch.Song,
+		"releaseDate": &patch.ReleaseDate,
+		"text":        &patch.Text,
+		"link":        &patch.Link,
+	}
+
+	numbers := map[string]**int{
+		"albumId": &patch.AlbumID,
+		"track":   &patch.Track,
+		"coverOf": &patch.CoverOf,
+	}
+
+	for name, raw := range doc {
+		if dst, ok := numbers[name]; ok {
+			value := 0
+			if string(raw) != "null" {
+				if err := json.Unmarshal(raw, &value); err != nil || value <= 0 {
+					return patch, fmt.Errorf("field %q must be a positive integer or null", name)
+				}
+			}
+			*dst = &value
+			continue
+		}
+		dst, ok := fields[name]
+		if !ok {
+			return patch, fmt.Errorf("field %q cannot be patched", name)
+		}
+		value := ""
+		if string(raw) != "null" {
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return patch, fmt.Errorf("field %q must be a string or null", name)
+			}
+		}
+		*dst = &value
+	}
+
+	for _, required := range []struct {
+		name  string
+		value *string
+	}{{"group", patch.Group}, {"song", patch.Song}} {
+		if required.value != nil && strings.TrimSpace(*required.value) == "" {
+			return patch, fmt.Errorf("field %q cannot be empty", required.name)
+		}
+	}
+
+	// a song leaving its album leaves its track number too
+	if patch.AlbumID != nil && *patch.AlbumID == 0 && patch.Track == nil {
+		patch.Track = new(int)
+	}
+	if patch.AlbumID != nil && patch.Track != nil {
+		if err := validateTrack(*patch.AlbumID, *patch.Track); err != nil {
+			return patch, err
+		}
+	}
+
+	if patch.ReleaseDate != nil {
+		date, err := model.NormalizeDate(*patch.ReleaseDate)
+		if err != nil {
+			return patch, err
+		}
+		patch.ReleaseDate = &date
+	}
+	return patch, nil
+}
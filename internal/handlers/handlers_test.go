@@ -0,0 +1,330 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go_test_effective_mobile/internal/enrichment"
+	"go_test_effective_mobile/internal/handlers"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func newHandler(t *testing.T, info handlers.InfoProvider) *handlers.Handler {
+	t.Helper()
+	h, err := handlers.NewHandler(zap.NewNop().Sugar(), handlers.Config{
+		Driver:            storage.DriverMemory,
+		DefaultLimit:      10,
+		DefaultPage:       1,
+		DefaultVerse:      1,
+		DefaultVerseLimit: 10,
+		MaxLimit:          100,
+		TrashRetention:    time.Hour,
+		Info:              info,
+	})
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	return h
+}
+
+func addSong(t *testing.T, h *handlers.Handler, body string) model.Song {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/songs", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	if err := h.AddSong(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("AddSong status = %d, body %s", rec.Code, rec.Body)
+	}
+	var song model.Song
+	if err := json.Unmarshal(rec.Body.Bytes(), &song); err != nil {
+		t.Fatalf("decode song: %v", err)
+	}
+	return song
+}
+
+func TestAddSongEnriched(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"releaseDate":"16.07.2006","text":"Ooh baby","link":"https://example.com"}`))
+	}))
+	defer upstream.Close()
+	h := newHandler(t, enrichment.NewClient(zap.NewNop().Sugar(), upstream.URL, enrichment.Options{}))
+
+	song := addSong(t, h, `{"group":"Muse","song":"Supermassive Black Hole"}`)
+	if !song.Enriched || song.Text != "Ooh baby" || song.Link != "https://example.com" || song.ReleaseDate == "" {
+		t.Errorf("AddSong = %+v, want an enriched song", song)
+	}
+}
+
+func TestAddSongUpstreamDown(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+	h := newHandler(t, enrichment.NewClient(zap.NewNop().Sugar(), upstream.URL, enrichment.Options{
+		Retries:     1,
+		BackoffBase: time.Millisecond,
+	}))
+
+	song := addSong(t, h, `{"group":"Muse","song":"Uprising","link":"https://example.com/uprising"}`)
+	if song.Enriched || song.Link != "https://example.com/uprising" {
+		t.Errorf("AddSong = %+v, want the song stored as sent and not enriched", song)
+	}
+
+	stored, err := h.DB.GetSongByID(context.Background(), strconv.Itoa(song.ID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if stored.Enriched {
+		t.Errorf("stored song is marked as enriched")
+	}
+}
+
+// failingStorage fails every song listing as a broken database would.
+type failingStorage struct {
+	storage.IStorage
+}
+
+func (failingStorage) GetSongs(context.Context, model.SongFilter, *model.SongCursor, int) ([]model.Song, int, error) {
+	return nil, 0, errors.New("connection refused")
+}
+
+func getSongs(t *testing.T, h *handlers.Handler, target string) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := h.GetSongs(echo.New().NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)); err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	return rec.Code
+}
+
+func TestGetSongsErrors(t *testing.T) {
+	h := newHandler(t, nil)
+	if code := getSongs(t, h, "/songs?cursor=!!!"); code != http.StatusBadRequest {
+		t.Errorf("invalid cursor: status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := getSongs(t, h, "/songs?q=genre:rock"); code != http.StatusBadRequest {
+		t.Errorf("invalid q: status = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	h.DB = failingStorage{h.DB}
+	if code := getSongs(t, h, "/songs"); code != http.StatusInternalServerError {
+		t.Errorf("storage failure: status = %d, want %d", code, http.StatusInternalServerError)
+	}
+}
+
+// serveSong runs a handler of the song with the given ID.
+func serveSong(t *testing.T, handler echo.HandlerFunc, req *http.Request, id int) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(id))
+	if err := handler(c); err != nil {
+		t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	return rec
+}
+
+func TestPatchSong(t *testing.T) {
+	const stored = `{"group":"Muse","song":"Uprising","releaseDate":"2009-09-07","text":"They will not force us","link":"https://example.com/uprising"}`
+	base := model.Song{Group: "Muse", Song: "Uprising", ReleaseDate: "2009-09-07", Text: "They will not force us", Link: "https://example.com/uprising"}
+	with := func(change func(*model.Song)) *model.Song {
+		song := base
+		change(&song)
+		return &song
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   *model.Song
+	}{
+		{"empty patch", `{}`, http.StatusOK, &base},
+		{"absent fields are kept", `{"song":"Resistance"}`, http.StatusOK, with(func(s *model.Song) { s.Song = "Resistance" })},
+		{"null clears a field", `{"text":null}`, http.StatusOK, with(func(s *model.Song) { s.Text = "" })},
+		{"null clears the release date", `{"releaseDate":null,"link":"https://example.com"}`, http.StatusOK,
+			with(func(s *model.Song) { s.ReleaseDate, s.Link = "", "https://example.com" })},
+		{"release date is normalized", `{"releaseDate":"16.07.2006"}`, http.StatusOK, with(func(s *model.Song) { s.ReleaseDate = "2006-07-16" })},
+		{"null group", `{"group":null}`, http.StatusBadRequest, nil},
+		{"blank song", `{"song":"  "}`, http.StatusBadRequest, nil},
+		{"unknown field", `{"genre":"rock"}`, http.StatusBadRequest, nil},
+		{"read-only field", `{"version":7}`, http.StatusBadRequest, nil},
+		{"number for a string", `{"song":1}`, http.StatusBadRequest, nil},
+		{"array for a string", `{"text":["a"]}`, http.StatusBadRequest, nil},
+		{"string for a number", `{"track":"3"}`, http.StatusBadRequest, nil},
+		{"negative number", `{"albumId":-1}`, http.StatusBadRequest, nil},
+		{"fraction", `{"coverOf":1.5}`, http.StatusBadRequest, nil},
+		{"invalid date", `{"releaseDate":"yesterday"}`, http.StatusBadRequest, nil},
+		{"empty body", ``, http.StatusBadRequest, nil},
+		{"null body", `null`, http.StatusBadRequest, nil},
+		{"array body", `[]`, http.StatusBadRequest, nil},
+		{"malformed body", `{"song":`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t, nil)
+			song := addSong(t, h, stored)
+
+			req := httptest.NewRequest(http.MethodPatch, "/songs/"+strconv.Itoa(song.ID), strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+			rec := serveSong(t, h.PatchSong, req, song.ID)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.status, rec.Body)
+			}
+
+			got, err := h.DB.GetSongByID(context.Background(), strconv.Itoa(song.ID))
+			if err != nil {
+				t.Fatalf("GetSongByID: %v", err)
+			}
+			want := &base
+			if tt.want != nil {
+				want = tt.want
+			}
+			if got.Group != want.Group || got.Song != want.Song || got.ReleaseDate != want.ReleaseDate || got.Text != want.Text || got.Link != want.Link {
+				t.Errorf("stored song = %+v, want %+v", got, *want)
+			}
+		})
+	}
+}
+
+func songRequest(method string, id int, body, ifMatch string) *http.Request {
+	req := httptest.NewRequest(method, "/songs/"+strconv.Itoa(id), strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	return req
+}
+
+func TestSongETag(t *testing.T) {
+	h := newHandler(t, nil)
+	song := addSong(t, h, `{"group":"Muse","song":"Uprising","link":"l"}`)
+
+	rec := serveSong(t, h.GetSongByID, songRequest(http.MethodGet, song.ID, "", ""), song.ID)
+	var got model.Song
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode song: %v", err)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"`+strconv.Itoa(got.Version)+`"` {
+		t.Fatalf("GET ETag = %s, want the version %d", etag, got.Version)
+	}
+	stale := rec.Header().Get("ETag")
+
+	rec = serveSong(t, h.PatchSong, songRequest(http.MethodPatch, song.ID, `{"text":"They will not force us"}`, stale), song.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH with the current ETag: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if etag := rec.Header().Get("ETag"); etag == stale {
+		t.Fatalf("PATCH kept the ETag %s", etag)
+	}
+
+	// every write with the stale ETag loses to the PATCH above
+	writes := []struct {
+		method  string
+		handler echo.HandlerFunc
+		body    string
+	}{
+		{http.MethodPut, h.UpdateSong, `{"group":"Muse","song":"Resistance","link":"l"}`},
+		{http.MethodPatch, h.PatchSong, `{"song":"Resistance"}`},
+		{http.MethodDelete, h.DeleteSong, ``},
+	}
+	for _, w := range writes {
+		rec = serveSong(t, w.handler, songRequest(w.method, song.ID, w.body, stale), song.ID)
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("%s with a stale If-Match: status = %d, want %d", w.method, rec.Code, http.StatusPreconditionFailed)
+		}
+	}
+	stored, err := h.DB.GetSongByID(context.Background(), strconv.Itoa(song.ID))
+	if err != nil || stored.Song != "Uprising" || stored.Text != "They will not force us" {
+		t.Fatalf("song after the stale writes = %+v, %v, want it as patched", stored, err)
+	}
+
+	rec = serveSong(t, h.PatchSong, songRequest(http.MethodPatch, song.ID, `{"song":"Resistance"}`, `W/"1"`), song.ID)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("PATCH with a weak If-Match: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// without If-Match, or with "*", the writes are unconditional
+	unconditional := []struct {
+		method  string
+		handler echo.HandlerFunc
+		body    string
+		ifMatch string
+		status  int
+	}{
+		{http.MethodPut, h.UpdateSong, `{"group":"Muse","song":"Resistance","link":"l"}`, "", http.StatusOK},
+		{http.MethodPatch, h.PatchSong, `{"song":"Uprising"}`, "*", http.StatusOK},
+		{http.MethodDelete, h.DeleteSong, ``, "", http.StatusNoContent},
+	}
+	for _, w := range unconditional {
+		rec = serveSong(t, w.handler, songRequest(w.method, song.ID, w.body, w.ifMatch), song.ID)
+		if rec.Code != w.status {
+			t.Errorf("%s with If-Match %q: status = %d, want %d, body %s", w.method, w.ifMatch, rec.Code, w.status, rec.Body)
+		}
+	}
+}
+
+func TestGetSongTextPages(t *testing.T) {
+	h := newHandler(t, nil)
+	song := addSong(t, h, `{"group":"Muse","song":"Uprising","link":"l","text":"one\n\ntwo\n\nthree\n\nfour\n\nfive"}`)
+	path := "/songs/" + strconv.Itoa(song.ID) + "/text"
+
+	tests := []struct {
+		query      string
+		verses     int
+		next, prev string
+	}{
+		{"?page=1&limit=2", 2, path + "?limit=2&page=2", ""},
+		{"?page=2&limit=2", 2, path + "?limit=2&page=3", path + "?limit=2&page=1"},
+		{"?page=3&limit=2", 1, "", path + "?limit=2&page=2"},
+		// a page past the end points back to the last existing one
+		{"?page=7&limit=2", 0, "", path + "?limit=2&page=3"},
+		{"?page=1&limit=5", 5, "", ""},
+		{"?page=2&limit=2&collapse=true", 2, path + "?collapse=true&limit=2&page=3", path + "?collapse=true&limit=2&page=1"},
+	}
+	for _, tt := range tests {
+		rec := serveSong(t, h.GetSongText, httptest.NewRequest(http.MethodGet, path+tt.query, nil), song.ID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body %s", tt.query, rec.Code, rec.Body)
+		}
+		var page model.VersePage
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decode page: %v", err)
+		}
+		if len(page.Verses) != tt.verses || page.TotalVerses != 5 {
+			t.Errorf("GET %s: %d of %d verses, want %d of 5", tt.query, len(page.Verses), page.TotalVerses, tt.verses)
+		}
+		if page.Next != tt.next || page.Prev != tt.prev {
+			t.Errorf("GET %s: next %q, prev %q, want %q, %q", tt.query, page.Next, page.Prev, tt.next, tt.prev)
+		}
+	}
+}
+
+func TestAddSongDuplicate(t *testing.T) {
+	h := newHandler(t, nil)
+	addSong(t, h, `{"group":"Muse","song":"Uprising","link":"l"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/songs", strings.NewReader(`{"group":"Muse","song":"Uprising","link":"other"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	if err := h.AddSong(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	if rec.Code != http.StatusConflict {
+		t.Errorf("duplicate AddSong status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
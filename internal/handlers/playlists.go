@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetPlaylists lists playlists in the order they were created.
+func (r *Handler) GetPlaylists(c echo.Context) error {
+	limit, offset := r.pagination(c)
+
+	r.log.Debugw("Fetching playlists", "limit", limit, "offset", offset)
+	playlists, err := r.DB.GetPlaylists(c.Request().Context(), limit, offset)
+	if err != nil {
+		r.log.Errorw("Failed to fetch playlists", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch playlists"})
+	}
+	return c.JSON(http.StatusOK, playlists)
+}
+
+func (r *Handler) GetPlaylist(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid playlist ID"})
+	}
+
+	r.log.Debugw("Fetching playlist", "id", id)
+	playlist, err := r.DB.GetPlaylist(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch playlist", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Playlist not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch playlist"})
+	}
+	return c.JSON(http.StatusOK, playlist)
+}
+
+func (r *Handler) AddPlaylist(c echo.Context) error {
+	name, err := bindPlaylistName(c)
+	if err != nil {
+		r.log.Errorw("Invalid playlist", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	r.log.Debugw("Adding new playlist", "name", name)
+	playlist, err := r.DB.AddPlaylist(c.Request().Context(), name)
+	if err != nil {
+		r.log.Errorw("Failed to add playlist", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add playlist"})
+	}
+	return c.JSON(http.StatusOK, playlist)
+}
+
+func (r *Handler) RenamePlaylist(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid playlist ID"})
+	}
+	name, err := bindPlaylistName(c)
+	if err != nil {
+		r.log.Errorw("Invalid playlist", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	r.log.Debugw("Renaming playlist", "id", id, "name", name)
+	playlist, err := r.DB.RenamePlaylist(c.Request().Context(), id, name)
+	if err != nil {
+		r.log.Errorw("Failed to rename playlist", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Playlist not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to rename playlist"})
+	}
+	return c.JSON(http.StatusOK, playlist)
+}
+
+// DeletePlaylist removes a playlist, its songs stay in the library.
+func (r *Handler) DeletePlaylist(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid playlist ID"})
+	}
+
+	r.log.Debugw("Deleting playlist", "id", id)
+	if err := r.DB.DeletePlaylist(c.Request().Context(), id); err != nil {
+		r.log.Errorw("Failed to delete playlist", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Playlist not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete playlist"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetPlaylistEntries lists the songs of a playlist in order, songs in the trash are left out.
+func (r *Handler) GetPlaylistEntries(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid playlist ID"})
+	}
+
+	r.log.Debugw("Fetching entries of playlist", "id", id)
+	entries, err := r.DB.GetPlaylistEntries(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch entries of playlist", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Playlist not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch entries of playlist"})
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+// AddPlaylistEntry puts a song in a playlist, at the end unless a position is given.
+func (r *Handler) AddPlaylistEntry(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid playlist ID"})
+	}
+	var entry model.NewPlaylistEntry
+	if err := c.Bind(&entry); err != nil {
+		r.log.Errorw("Failed to bind playlist entry", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if entry.SongID < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "missing required fields: songId"})
+	}
+	if entry.Position < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "position must be positive"})
+	}
+
+	r.log.Debugw("Adding playlist entry", "id", id, "entry", entry)
+	added, err := r.DB.AddPlaylistEntry(c.Request().Context(), id, entry.SongID, entry.Position)
+	if err != nil {
+		r.log.Errorw("Failed to add playlist entry", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Playlist not found"})
+		}
+		if errors.Is(err, storage.ErrSongNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Song not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add playlist entry"})
+	}
+	return c.JSON(http.StatusOK, added)
+}
+
+// MovePlaylistEntry moves an entry of a playlist to another position, the entries in between shift by one.
+func (r *Handler) MovePlaylistEntry(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid playlist ID"})
+	}
+	entryID, ok := entryIDParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid entry ID"})
+	}
+	var move model.PlaylistEntryMove
+	if err := c.Bind(&move); err != nil {
+		r.log.Errorw("Failed to bind playlist entry move", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if move.Position < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "position must be positive"})
+	}
+
+	r.log.Debugw("Moving playlist entry", "id", id, "entryID", entryID, "position", move.Position)
+	moved, err := r.DB.MovePlaylistEntry(c.Request().Context(), id, entryID, move.Position)
+	if err != nil {
+		r.log.Errorw("Failed to move playlist entry", "id", id, "entryID", entryID, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Playlist not found"})
+		}
+		if errors.Is(err, storage.ErrEntryNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Entry not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to move playlist entry"})
+	}
+	return c.JSON(http.StatusOK, moved)
+}
+
+func (r *Handler) RemovePlaylistEntry(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid playlist ID"})
+	}
+	entryID, ok := entryIDParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid entry ID"})
+	}
+
+	r.log.Debugw("Removing playlist entry", "id", id, "entryID", entryID)
+	if err := r.DB.RemovePlaylistEntry(c.Request().Context(), id, entryID); err != nil {
+		r.log.Errorw("Failed to remove playlist entry", "id", id, "entryID", entryID, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Playlist not found"})
+		}
+		if errors.Is(err, storage.ErrEntryNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Entry not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to remove playlist entry"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// bindPlaylistName reads the name of a playlist in the request body.
+func bindPlaylistName(c echo.Context) (string, error) {
+	var body model.PlaylistName
+	if err := c.Bind(&body); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		return "", errors.New("missing required fields: name")
+	}
+	return name, nil
+}
+
+// entryIDParam reads the ID of a playlist entry from the path.
+func entryIDParam(c echo.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("entryId"))
+	return id, err == nil && id > 0
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (r *Handler) GetSongRevisions(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		r.log.Errorw("Invalid song ID", "id", idStr, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid song ID",
+		})
+	}
+
+	r.log.Debugw("Fetching song revisions", "id", id)
+	revisions, err := r.DB.GetSongRevisions(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch song revisions", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Song not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch song revisions",
+		})
+	}
+	return c.JSON(http.StatusOK, revisions)
+}
+
+func (r *Handler) GetSongRevision(c echo.Context) error {
+	id, revision, ok := r.revisionParams(c)
+	if !ok {
+		return nil
+	}
+
+	r.log.Debugw("Fetching song revision", "id", id, "revision", revision)
+	res, err := r.DB.GetSongRevision(c.Request().Context(), id, revision)
+	if err != nil {
+		r.log.Errorw("Failed to fetch song revision", "id", id, "revision", revision, "error", err)
+		return r.revisionError(c, err, "Failed to fetch song revision")
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// DiffSongRevisions compares two revisions of a song field by field.
+// to defaults to the latest revision and from to the one before to.
+func (r *Handler) DiffSongRevisions(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		r.log.Errorw("Invalid song ID", "id", idStr, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid song ID",
+		})
+	}
+
+	revisions, err := r.DB.GetSongRevisions(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch song revisions", "id", id, "error", err)
+		return r.revisionError(c, err, "Failed to fetch song revisions")
+	}
+
+	to, err := revisionQueryParam(c, "to", len(revisions))
+	if err != nil {
+		r.log.Errorw("Invalid revision", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	from, err := revisionQueryParam(c, "from", max(1, to-1))
+	if err != nil {
+		r.log.Errorw("Invalid revision", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if from > len(revisions) || to > len(revisions) {
+		r.log.Errorw("Revision not found", "id", id, "from", from, "to", to)
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Revision not found"})
+	}
+
+	// revisions are numbered from 1 without gaps
+	diff := model.RevisionDiff{
+		SongID:  id,
+		From:    from,
+		To:      to,
+		Changes: model.DiffSongs(revisions[from-1].Song, revisions[to-1].Song),
+	}
+	return c.JSON(http.StatusOK, diff)
+}
+
+// RevertSong restores the state of a past revision as a new version of the song. A deleted song is recreated.
+func (r *Handler) RevertSong(c echo.Context) error {
+	id, revision, ok := r.revisionParams(c)
+	if !ok {
+		return nil
+	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		r.log.Errorw("Invalid If-Match header", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	r.log.Debugw("Reverting song", "id", id, "revision", revision, "version", version)
+	song, err := r.DB.RevertSong(c.Request().Context(), id, revision, version)
+	if err != nil {
+		r.log.Errorw("Failed to revert song", "id", id, "revision", revision, "error", err)
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			return preconditionFailed(c)
+		}
+		if errors.Is(err, storage.ErrSongExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Another song with this group and name exists",
+			})
+		}
+		return r.revisionError(c, err, "Failed to revert song")
+	}
+
+	r.log.Debug("Song reverted successfully", "song", song)
+	c.Response().Header().Set(headerETag, songETag(song.Version))
+	return c.JSON(http.StatusOK, song)
+}
+
+// revisionParams reads the song ID and revision from the path, writing a 400 response when they are invalid.
+func (r *Handler) revisionParams(c echo.Context) (id, revision int, ok bool) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		r.log.Errorw("Invalid song ID", "id", idStr, "error", err)
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid song ID"})
+		return 0, 0, false
+	}
+	revStr := c.Param("rev")
+	revision, err = strconv.Atoi(revStr)
+	if err != nil || revision < 1 {
+		r.log.Errorw("Invalid revision", "revision", revStr, "error", err)
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid revision"})
+		return 0, 0, false
+	}
+	return id, revision, true
+}
+
+func (r *Handler) revisionError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Song not found"})
+	case errors.Is(err, storage.ErrRevisionNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Revision not found"})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": message})
+	}
+}
+
+func revisionQueryParam(c echo.Context, name string, def int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return def, nil
+	}
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, errors.New(name + ": expected a revision number")
+	}
+	return revision, nil
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetTags lists the tags of the songs, the most used first.
+func (r *Handler) GetTags(c echo.Context) error {
+	limit, offset := r.pagination(c)
+
+	r.log.Debugw("Fetching tags", "limit", limit, "offset", offset)
+	tags, err := r.DB.GetTags(c.Request().Context(), limit, offset)
+	if err != nil {
+		r.log.Errorw("Failed to fetch tags", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch tags"})
+	}
+	return c.JSON(http.StatusOK, tags)
+}
+
+func (r *Handler) GetSongTags(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid song ID"})
+	}
+
+	r.log.Debugw("Fetching tags of song", "id", id)
+	tags, err := r.DB.GetSongTags(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to fetch tags of song", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Song not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch tags of song"})
+	}
+	return c.JSON(http.StatusOK, tags)
+}
+
+// AddSongTags attaches tags to a song, the ones it has already are kept once.
+func (r *Handler) AddSongTags(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid song ID"})
+	}
+	var body model.SongTags
+	if err := c.Bind(&body); err != nil {
+		r.log.Errorw("Failed to bind song tags", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if len(body.Tags) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "missing required fields: tags"})
+	}
+	tags, err := normalizeTags(body.Tags)
+	if err != nil {
+		r.log.Errorw("Invalid song tags", "tags", body.Tags, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	r.log.Debugw("Tagging song", "id", id, "tags", tags)
+	tags, err = r.DB.AddSongTags(c.Request().Context(), id, tags)
+	if err != nil {
+		r.log.Errorw("Failed to tag song", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Song not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to tag song"})
+	}
+	return c.JSON(http.StatusOK, tags)
+}
+
+func (r *Handler) RemoveSongTag(c echo.Context) error {
+	id, ok := idParam(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid song ID"})
+	}
+	// path parameters come escaped
+	tag, err := url.PathUnescape(c.Param("tag"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid tag"})
+	}
+	tag = model.NormalizeTag(tag)
+
+	r.log.Debugw("Untagging song", "id", id, "tag", tag)
+	if err = r.DB.RemoveSongTag(c.Request().Context(), id, tag); err != nil {
+		r.log.Errorw("Failed to untag song", "id", id, "tag", tag, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Song not found"})
+		}
+		if errors.Is(err, storage.ErrTagNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Song has no such tag"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to untag song"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tagParams reads the repeated tag query parameter and tag_match, how the tags are matched:
+// model.TagMatchAll by default.
+func tagParams(c echo.Context) ([]string, string, error) {
+	match := c.QueryParam("tag_match")
+	if match == "" {
+		match = model.TagMatchAll
+	}
+	if !slices.Contains(model.TagMatchModes, match) {
+		return nil, "", fmt.Errorf("tag_match: expected one of %s", strings.Join(model.TagMatchModes, ", "))
+	}
+	params := c.QueryParams()["tag"]
+	if len(params) == 0 {
+		return nil, match, nil
+	}
+	tags, err := normalizeTags(params)
+	if err != nil {
+		return nil, "", fmt.Errorf("tag: %w", err)
+	}
+	return tags, match, nil
+}
+
+// normalizeTags checks tag names and returns them normalized, each once.
+func normalizeTags(names []string) ([]string, error) {
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tag := model.NormalizeTag(name)
+		if tag == "" {
+			return nil, errors.New("tags cannot be empty")
+		}
+		if utf8.RuneCountInString(tag) > model.MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, model.MaxTagLength)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
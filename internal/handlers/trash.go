@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/storage"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (r *Handler) GetTrash(c echo.Context) error {
+	limit, offset := r.pagination(c)
+
+	r.log.Debugw("Fetching trash", "limit", limit, "offset", offset)
+	songs, err := r.DB.GetTrash(c.Request().Context(), limit, offset)
+	if err != nil {
+		r.log.Errorw("Failed to fetch trash", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch trash",
+		})
+	}
+	return c.JSON(http.StatusOK, songs)
+}
+
+func (r *Handler) RestoreSong(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		r.log.Errorw("Invalid song ID", "id", idStr, "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid song ID",
+		})
+	}
+
+	r.log.Debugw("Restoring song", "id", id)
+	song, err := r.DB.RestoreSong(c.Request().Context(), id)
+	if err != nil {
+		r.log.Errorw("Failed to restore song", "id", id, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Song not found in trash",
+			})
+		}
+		if errors.Is(err, storage.ErrSongExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Another song with this group and name exists",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to restore song",
+		})
+	}
+
+	r.log.Debug("Song restored successfully", "song", song)
+	c.Response().Header().Set(headerETag, songETag(song.Version))
+	return c.JSON(http.StatusOK, song)
+}
+
+// PurgeTrash permanently removes the songs that stayed in the trash longer than the retention period.
+func (r *Handler) PurgeTrash(c echo.Context) error {
+	purged, err := r.PurgeExpiredTrash(c.Request().Context())
+	if err != nil {
+		r.log.Errorw("Failed to purge trash", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to purge trash",
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]int{"purged": purged})
+}
+
+// PurgeExpiredTrash removes the songs deleted more than the retention period ago, the server also runs it periodically.
+func (r *Handler) PurgeExpiredTrash(ctx context.Context) (int, error) {
+	before := time.Now().Add(-r.trashRetention)
+	r.log.Debugw("Purging trash", "before", before)
+	purged, err := r.DB.PurgeTrash(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		r.log.Infow("Purged songs from trash", "purged", purged, "before", before)
+	}
+	return purged, nil
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"go_test_effective_mobile/internal/storage"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderActor names whoever makes the request. There is no authentication, so it is taken on trust
+// and only used to attribute song revisions.
+const HeaderActor = "X-Actor"
+
+func Actor() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if actor := strings.TrimSpace(c.Request().Header.Get(HeaderActor)); actor != "" {
+				req := c.Request()
+				c.SetRequest(req.WithContext(storage.WithActor(req.Context(), actor)))
+			}
+			return next(c)
+		}
+	}
+}
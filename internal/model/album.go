@@ -0,0 +1,13 @@
+package model
+
+// Album is a release of an artist. Like songs, albums name their artist by Group, ArtistID is set by the storage.
+type Album struct {
+	ID          int    `json:"id,omitempty" example:"1"`
+	Title       string `json:"title" validate:"required" example:"Black Holes and Revelations"`
+	Group       string `json:"group" validate:"required" example:"Muse"`
+	ArtistID    int    `json:"artistId,omitempty" example:"1"`
+	ReleaseDate string `json:"releaseDate,omitempty" example:"2006-07-03"`
+	CoverLink   string `json:"coverLink,omitempty" example:"https://example.com/covers/bhar.jpg"`
+	// SongCount is the number of songs of the album, not counting the ones in the trash.
+	SongCount int `json:"songCount" example:"11"`
+}
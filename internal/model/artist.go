@@ -0,0 +1,37 @@
+package model
+
+// Artist is a group songs belong to. The Group of a Song is the name of its artist, ArtistID is set by the storage.
+type Artist struct {
+	ID   int    `json:"id" example:"1"`
+	Name string `json:"name" example:"Muse"`
+	// SongCount is the number of songs of the artist, not counting the ones in the trash.
+	SongCount int `json:"songCount" example:"12"`
+}
+
+// ArtistRename is the body of PATCH /groups/{id}.
+type ArtistRename struct {
+	Name string `json:"name" validate:"required" example:"Muse"`
+}
+
+// ArtistMerge is the body of POST /groups/{id}/merge, From is the artist whose songs move over.
+type ArtistMerge struct {
+	From int `json:"from" validate:"required" example:"2"`
+}
+
+// Roles of the artists credited on a song. The main artist is the group of the song,
+// the others are credited through PUT /songs/{id}/artists.
+const (
+	RoleMain      = "main"
+	RoleFeaturing = "featuring"
+	RoleProducer  = "producer"
+)
+
+// CreditRoles are the roles an artist can be credited with besides the group of the song.
+var CreditRoles = []string{RoleFeaturing, RoleProducer}
+
+// Credit is an artist credited on a song. Artists are credited by Name like groups, ArtistID is set by the storage.
+type Credit struct {
+	ArtistID int    `json:"artistId,omitempty" example:"2"`
+	Name     string `json:"name" example:"Rihanna"`
+	Role     string `json:"role" example:"featuring"`
+}
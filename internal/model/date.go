@@ -0,0 +1,28 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// DateLayout is the canonical format of release dates in requests, responses and storage.
+const DateLayout = "2006-01-02"
+
+// upstreamDateLayout is the format used by the external info API, e.g. "16.07.2006".
+const upstreamDateLayout = "02.01.2006"
+
+var ErrInvalidDate = errors.New("invalid date, expected YYYY-MM-DD or DD.MM.YYYY")
+
+// NormalizeDate accepts "2006-07-16" and "16.07.2006" and returns the date in DateLayout.
+// An empty string stays empty.
+func NormalizeDate(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, layout := range []string{DateLayout, upstreamDateLayout} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(DateLayout), nil
+		}
+	}
+	return "", ErrInvalidDate
+}
@@ -1,10 +1,304 @@
 package model
 
+import "time"
+
 type Song struct {
 	ID          int    `json:"id,omitempty"  example:"1"`
 	Group       string `json:"group,omitempty" validate:"required" example:"Muse"`
+	ArtistID    int    `json:"artistId,omitempty" example:"1"`
 	Song        string `json:"song,omitempty" validate:"required" example:"Supermassive Black Hole"`
+	AlbumID     int    `json:"albumId,omitempty" example:"1"`
+	Track       int    `json:"track,omitempty" example:"2"`
+	CoverOf     int    `json:"coverOf,omitempty" example:"7"`
 	ReleaseDate string `json:"releaseDate,omitempty" example:"2006-07-16"`
 	Text        string `json:"text,omitempty" example:"Ooh baby, don't you know I suffer..."`
 	Link        string `json:"link,omitempty" example:"https://www.youtube.com/watch?v=Xsp3_a-PMTw"`
+	Enriched    bool   `json:"enriched" example:"true"`
+	VerseCount  int    `json:"verseCount" example:"4"`
+	Version     int    `json:"version" example:"3"`
+	// Score is the similarity to the group and song filters, only set for fuzzy matches.
+	Score float64 `json:"score,omitempty" example:"0.57"`
+}
+
+// TrashedSong is a deleted song that can still be restored.
+type TrashedSong struct {
+	Song
+	DeletedAt time.Time `json:"deletedAt" example:"2024-05-01T12:00:00Z"`
+}
+
+// SongPatch is a partial update of a song, nil fields are left unchanged.
+// For optional fields a pointer to an empty string or zero clears the value.
+type SongPatch struct {
+	Group       *string
+	Song        *string
+	ReleaseDate *string
+	Text        *string
+	Link        *string
+	AlbumID     *int
+	Track       *int
+	CoverOf     *int
+}
+
+func (p SongPatch) Empty() bool {
+	return p.Group == nil && p.Song == nil && p.ReleaseDate == nil && p.Text == nil && p.Link == nil &&
+		p.AlbumID == nil && p.Track == nil && p.CoverOf == nil
+}
+
+// Apply returns the song with the patch applied.
+func (p SongPatch) Apply(song Song) Song {
+	for _, f := range []struct {
+		src *string
+		dst *string
+	}{
+		{p.Group, &song.Group},
+		{p.Song, &song.Song},
+		{p.ReleaseDate, &song.ReleaseDate},
+		{p.Text, &song.Text},
+		{p.Link, &song.Link},
+	} {
+		if f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+	if p.AlbumID != nil {
+		song.AlbumID = *p.AlbumID
+	}
+	if p.Track != nil {
+		song.Track = *p.Track
+	}
+	if p.CoverOf != nil {
+		song.CoverOf = *p.CoverOf
+	}
+	return song
+}
+
+const (
+	MatchExact  = "exact"
+	MatchPrefix = "prefix"
+	MatchFuzzy  = "fuzzy"
+)
+
+var MatchModes = []string{MatchExact, MatchPrefix, MatchFuzzy}
+
+// SongFilter narrows the song list, empty fields are not applied.
+// Match is how Group and Song are compared, MatchExact when empty. Every mode ignores case.
+// Dates are in DateLayout, ReleaseFrom and ReleaseTo are inclusive.
+// Sort orders the songs, ties are broken by ID. Without it songs are ordered by ID, fuzzy matches by Score first.
+// Query is a parsed q expression the songs also have to match, nil when there is none.
+// Fields are the SongFields the caller needs, all when empty; storages may leave the other fields unset.
+// Album is the ID of the album the songs belong to, Artist the ID of an artist credited on them in any role.
+// Tags are normalized tag names, a song needs all of them or, with TagMatch TagMatchAny, one of them.
+type SongFilter struct {
+	Group       string
+	Song        string
+	Match       string
+	ReleaseDate string
+	ReleaseFrom string
+	ReleaseTo   string
+	Sort        []SortField
+	Query       Expr
+	Fields      []string
+	Album       int
+	Artist      int
+	Tags        []string
+	TagMatch    string
+}
+
+// Fields songs can be sorted by, named like the query parameters of GET /songs.
+const (
+	SortID          = "id"
+	SortGroup       = "group"
+	SortSong        = "song"
+	SortReleaseDate = "release_date"
+)
+
+var SortFields = []string{SortID, SortGroup, SortSong, SortReleaseDate}
+
+// SortField is one of SortFields, in descending order when Desc is set.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// SortValue returns the value a song is sorted by for a field other than SortID.
+// Songs without a release date come before the dated ones.
+func SortValue(song Song, field string) string {
+	switch field {
+	case SortGroup:
+		return song.Group
+	case SortSong:
+		return song.Song
+	case SortReleaseDate:
+		return song.ReleaseDate
+	}
+	return ""
+}
+
+type SongDetail struct {
+	ReleaseDate string `json:"releaseDate" example:"2006-07-16"`
+	Text        string `json:"text" example:"Ooh baby, don't you know I suffer..."`
+	Link        string `json:"link" example:"https://www.youtube.com/watch?v=Xsp3_a-PMTw"`
+}
+
+const (
+	SectionVerse     = "verse"
+	SectionChorus    = "chorus"
+	SectionPreChorus = "pre-chorus"
+	SectionBridge    = "bridge"
+	SectionIntro     = "intro"
+	SectionOutro     = "outro"
+	SectionHook      = "hook"
+	SectionOther     = "other"
+)
+
+var SectionTypes = []string{
+	SectionVerse, SectionChorus, SectionPreChorus, SectionBridge, SectionIntro, SectionOutro, SectionHook, SectionOther,
+}
+
+type Verse struct {
+	Number int    `json:"number" example:"1"`
+	Type   string `json:"type" example:"chorus"`
+	Label  string `json:"label,omitempty" example:"Chorus"`
+	Text   string `json:"text" example:"Ooh baby, don't you know I suffer?"`
+}
+
+type VersePage struct {
+	SongID      int     `json:"song_id" example:"1"`
+	Verses      []Verse `json:"verses"`
+	TotalVerses int     `json:"total_verses" example:"7"`
+	Page        int     `json:"page" example:"2"`
+	Limit       int     `json:"limit" example:"2"`
+	Next        string  `json:"next,omitempty" example:"/songs/1/text?page=3&limit=2"`
+	Prev        string  `json:"prev,omitempty" example:"/songs/1/text?page=1&limit=2"`
+}
+
+// SongSearch is a full-text query over song titles, groups and lyrics.
+// Group, ReleaseFrom and ReleaseTo narrow the matches like the fields of SongFilter, empty ones are not applied.
+type SongSearch struct {
+	Query       string
+	Group       string
+	ReleaseFrom string
+	ReleaseTo   string
+}
+
+// SearchPage is a page of search results with the facets of all of them.
+type SearchPage struct {
+	Items  []SearchResult          `json:"items"`
+	Facets map[string][]FacetCount `json:"facets"`
+}
+
+type SearchResult struct {
+	Song
+	Rank float64 `json:"rank" example:"0.61"`
+	// Match is the verse that matched best, nil when only the title or the group matched.
+	Match *VerseMatch `json:"match,omitempty"`
+}
+
+type VerseMatch struct {
+	Number  int    `json:"number" example:"3"`
+	Type    string `json:"type" example:"chorus"`
+	Label   string `json:"label,omitempty" example:"Chorus"`
+	Snippet string `json:"snippet" example:"Ooh baby, don&#39;t you know I <b>suffer</b>?"`
+}
+
+// SongCursor is the position of the last song of a page: its Score for fuzzy matches listed by score,
+// its SortValue for each of the sort fields other than SortID, and its ID.
+type SongCursor struct {
+	Score  float64  `json:"s,omitempty"`
+	Values []string `json:"v,omitempty"`
+	ID     int      `json:"id"`
+}
+
+// CursorAfter returns the cursor of a page ending with song, listed in the sort order.
+func CursorAfter(song Song, sort []SortField) SongCursor {
+	cursor := SongCursor{Score: song.Score, ID: song.ID}
+	for _, f := range sort {
+		if f.Field == SortID {
+			break
+		}
+		cursor.Values = append(cursor.Values, SortValue(song, f.Field))
+	}
+	return cursor
+}
+
+// SongFields are the fields of a song that can be picked, named like their JSON keys.
+var SongFields = []string{"id", "group", "artistId", "song", "albumId", "track", "coverOf", "releaseDate", "text", "link", "enriched", "verseCount", "version"}
+
+// ListFields are the fields of the songs of a list unless others are asked for, the lyrics are left out.
+var ListFields = []string{"id", "group", "artistId", "song", "albumId", "track", "coverOf", "releaseDate", "link", "enriched", "verseCount", "version"}
+
+// Pick returns the given SongFields of the song by their JSON keys, with the score of a fuzzy match.
+// Like in the JSON of a Song, empty strings and the album or original of a song without one are left out.
+func (s Song) Pick(fields []string) map[string]any {
+	res := make(map[string]any, len(fields)+1)
+	for _, f := range fields {
+		var value any
+		switch f {
+		case "id":
+			value = s.ID
+		case "group":
+			value = s.Group
+		case "artistId":
+			value = s.ArtistID
+		case "song":
+			value = s.Song
+		case "albumId":
+			if s.AlbumID != 0 {
+				value = s.AlbumID
+			}
+		case "track":
+			if s.Track != 0 {
+				value = s.Track
+			}
+		case "coverOf":
+			if s.CoverOf != 0 {
+				value = s.CoverOf
+			}
+		case "releaseDate":
+			value = s.ReleaseDate
+		case "text":
+			value = s.Text
+		case "link":
+			value = s.Link
+		case "enriched":
+			value = s.Enriched
+		case "verseCount":
+			value = s.VerseCount
+		case "version":
+			value = s.Version
+		}
+		if value != nil && value != "" {
+			res[f] = value
+		}
+	}
+	if s.Score != 0 {
+		res["score"] = s.Score
+	}
+	return res
+}
+
+// Facets songs can be counted by.
+const (
+	FacetGroup  = "group"
+	FacetYear   = "year"
+	FacetDecade = "decade"
+)
+
+var Facets = []string{FacetGroup, FacetYear, FacetDecade}
+
+// FacetCount is the number of songs with a value of a facet. A decade is the first year of it.
+// Filter is the link of the list narrowed to those songs.
+type FacetCount struct {
+	Value  string `json:"value" example:"Muse"`
+	Count  int    `json:"count" example:"12"`
+	Filter string `json:"filter" example:"/songs?facets=group&group=Muse"`
+}
+
+// SongPage is a page of GET /songs, its items hold the picked fields of the songs. NextCursor is empty on the last page.
+type SongPage struct {
+	Items      []map[string]any `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty" example:"eyJpZCI6NX0"`
+	Total      int              `json:"total" example:"42"`
+	// Facets count all the songs matching the filters, only the asked for facets are there.
+	Facets map[string][]FacetCount `json:"facets,omitempty"`
 }
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Playlist is an ordered list of songs, a song may be in it more than once.
+type Playlist struct {
+	ID   int    `json:"id" example:"1"`
+	Name string `json:"name" example:"Road trip"`
+	// EntryCount is the number of entries, not counting the ones of songs in the trash.
+	EntryCount int       `json:"entryCount" example:"12"`
+	CreatedAt  time.Time `json:"createdAt" example:"2024-05-01T12:00:00Z"`
+	UpdatedAt  time.Time `json:"updatedAt" example:"2024-05-02T08:30:00Z"`
+}
+
+// PlaylistName is the body of POST /playlists and PATCH /playlists/{id}.
+type PlaylistName struct {
+	Name string `json:"name" validate:"required" example:"Road trip"`
+}
+
+// PlaylistEntry is a song in a playlist. ID tells apart the entries of a song that is in the playlist twice.
+// Position is 1-based and counted over the entries of songs not in the trash, which playlists leave out.
+type PlaylistEntry struct {
+	ID       int       `json:"id" example:"7"`
+	Position int       `json:"position" example:"3"`
+	AddedAt  time.Time `json:"addedAt" example:"2024-05-01T12:00:00Z"`
+	Song     Song      `json:"song"`
+}
+
+// NewPlaylistEntry is the body of POST /playlists/{id}/entries. The song is inserted at Position,
+// moving the entries from there down; it is appended when Position is zero or past the end.
+type NewPlaylistEntry struct {
+	SongID   int `json:"songId" validate:"required" example:"1"`
+	Position int `json:"position,omitempty" example:"2"`
+}
+
+// PlaylistEntryMove is the body of PATCH /playlists/{id}/entries/{entryId}, a Position past the end moves
+// the entry to the end.
+type PlaylistEntryMove struct {
+	Position int `json:"position" validate:"required" example:"1"`
+}
@@ -0,0 +1,97 @@
+package model
+
+// Expr is a node of a parsed song query, the q parameter of GET /songs.
+type Expr interface {
+	expr()
+}
+
+// Fields of a song query.
+const (
+	FieldID          = "id"
+	FieldGroup       = "group"
+	FieldSong        = "song"
+	FieldText        = "text"
+	FieldLink        = "link"
+	FieldReleaseDate = "release_date"
+	FieldYear        = "year"
+	FieldVersion     = "version"
+	FieldEnriched    = "enriched"
+)
+
+// Operators of a Compare.
+const (
+	OpEq       = ":"
+	OpNe       = "!="
+	OpLt       = "<"
+	OpLe       = "<="
+	OpGt       = ">"
+	OpGe       = ">="
+	OpContains = "~"
+	OpPrefix   = "*"
+)
+
+// FieldKind is the type of the values of a query field.
+type FieldKind int
+
+const (
+	KindText FieldKind = iota
+	KindNumber
+	KindDate
+	KindBool
+)
+
+// QueryFields are the fields a query can test, with the kind of their values.
+var QueryFields = map[string]FieldKind{
+	FieldID:          KindNumber,
+	FieldGroup:       KindText,
+	FieldSong:        KindText,
+	FieldText:        KindText,
+	FieldLink:        KindText,
+	FieldReleaseDate: KindDate,
+	FieldYear:        KindNumber,
+	FieldVersion:     KindNumber,
+	FieldEnriched:    KindBool,
+}
+
+// And matches the songs matched by both sides.
+type And struct {
+	Left, Right Expr
+}
+
+// Or matches the songs matched by either side.
+type Or struct {
+	Left, Right Expr
+}
+
+// Not matches the songs Expr does not.
+type Not struct {
+	Expr Expr
+}
+
+// Compare tests a field against a value: a string for KindText, an int for KindNumber,
+// a DateLayout string for KindDate and a bool for KindBool. Contains and prefix matches ignore case.
+// A song without a release date matches no comparison of release_date or year.
+type Compare struct {
+	Field string
+	Op    string
+	Value any
+}
+
+// Range matches the values of a field from From to To inclusive.
+type Range struct {
+	Field    string
+	From, To any
+}
+
+// In matches the values of a field equal to one of Values.
+type In struct {
+	Field  string
+	Values []any
+}
+
+func (And) expr()     {}
+func (Or) expr()      {}
+func (Not) expr()     {}
+func (Compare) expr() {}
+func (Range) expr()   {}
+func (In) expr()      {}
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRevert  = "revert"
+	RevisionRestore = "restore"
+)
+
+// SongRevision is an immutable snapshot of a song taken after each write.
+// For a delete it holds the state the song had when it was removed.
+type SongRevision struct {
+	Revision  int       `json:"revision" example:"2"`
+	Operation string    `json:"operation" example:"update"`
+	Actor     string    `json:"actor,omitempty" example:"editor@example.com"`
+	CreatedAt time.Time `json:"createdAt" example:"2024-05-01T12:00:00Z"`
+	Song      Song      `json:"song"`
+}
+
+type FieldChange struct {
+	Field string `json:"field" example:"link"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type RevisionDiff struct {
+	SongID  int           `json:"song_id" example:"1"`
+	From    int           `json:"from" example:"1"`
+	To      int           `json:"to" example:"3"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// DiffSongs lists the user visible fields that differ between two snapshots of a song.
+// Derived fields such as the version and the verse count are left out.
+func DiffSongs(from, to Song) []FieldChange {
+	changes := make([]FieldChange, 0)
+	for _, f := range []struct {
+		name     string
+		from, to any
+	}{
+		{"group", from.Group, to.Group},
+		{"song", from.Song, to.Song},
+		{"albumId", from.AlbumID, to.AlbumID},
+		{"track", from.Track, to.Track},
+		{"coverOf", from.CoverOf, to.CoverOf},
+		{"releaseDate", from.ReleaseDate, to.ReleaseDate},
+		{"text", from.Text, to.Text},
+		{"link", from.Link, to.Link},
+		{"enriched", from.Enriched, to.Enriched},
+	} {
+		if f.from != f.to {
+			changes = append(changes, FieldChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,31 @@
+package model
+
+import "strings"
+
+// Tag is a genre, mood or any other label songs are tagged with.
+type Tag struct {
+	Name string `json:"name" example:"rock"`
+	// SongCount is the number of songs with the tag, not counting the ones in the trash.
+	SongCount int `json:"songCount" example:"12"`
+}
+
+// SongTags is the body of POST /songs/{id}/tags.
+type SongTags struct {
+	Tags []string `json:"tags" validate:"required" example:"rock,90s"`
+}
+
+// How the Tags of a SongFilter are matched.
+const (
+	TagMatchAll = "all"
+	TagMatchAny = "any"
+)
+
+var TagMatchModes = []string{TagMatchAll, TagMatchAny}
+
+// MaxTagLength is the longest tag name, in characters.
+const MaxTagLength = 64
+
+// NormalizeTag returns the stored form of a tag name: trimmed and in lower case, so "Rock" and "rock " are one tag.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokAnd
+	tokOr
+	tokNot
+	tokIn
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the 1-based position of the first character of the token in the query
+	pos int
+}
+
+// operators, longest first so "<=" is not read as "<"
+var operators = []string{"!=", "<=", ">=", ":", "=", "<", ">", "~"}
+
+// specials end a bare word
+const specials = `()",:=!<>~`
+
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: pos})
+			i++
+		case r == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				b.WriteRune(runes[j])
+			}
+			if j == len(runes) {
+				return nil, &SyntaxError{Pos: pos, Token: string(runes[i:]), Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: pos})
+			i = j + 1
+		case strings.ContainsRune(specials, r):
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(string(runes[i:min(i+2, len(runes))]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, &SyntaxError{Pos: pos, Token: string(r), Msg: "unexpected character"}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+			i += len([]rune(op))
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune(specials, runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			tokens = append(tokens, token{kind: keyword(word), text: word, pos: pos})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+// keyword reads the operators of the language, they are upper case so values like "or" need no quotes.
+func keyword(word string) tokenKind {
+	switch word {
+	case "AND":
+		return tokAnd
+	case "OR":
+		return tokOr
+	case "NOT":
+		return tokNot
+	case "IN":
+		return tokIn
+	}
+	return tokWord
+}
@@ -0,0 +1,261 @@
+// Package query parses the song query language of the q parameter of GET /songs, e.g.
+//
+//	group:Muse AND year>=2005 AND text~"black hole"
+//
+// A term tests a field: field:value, field!=value, field<value (also <=, >, >=), field~value for text containing
+// the value, field:value* for a prefix, field:from..to for an inclusive range and field IN (a, b) for a list.
+// Terms are combined with AND, OR and NOT, AND binding tighter than OR, and grouped with parentheses.
+// Values with spaces or special characters are double-quoted.
+package query
+
+import (
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError points at the token of the query that could not be parsed.
+type SyntaxError struct {
+	// Pos is the 1-based position of the token in the query, in characters
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("q: %s at the end of the query", e.Msg)
+	}
+	return fmt.Sprintf("q: %s at position %d near %q", e.Msg, e.Pos, e.Token)
+}
+
+// Parse reads a query into its AST. The errors are *SyntaxError.
+func Parse(input string) (model.Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, p.errorf(p.peek(), "empty query")
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "expected AND, OR or the end of the query")
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	return &SyntaxError{Pos: tok.pos, Token: tok.text, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (model.Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = model.Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (model.Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.advance()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = model.And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (model.Expr, error) {
+	if p.peek().kind != tokNot {
+		return p.parsePrimary()
+	}
+	p.advance()
+	expr, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return model.Not{Expr: expr}, nil
+}
+
+func (p *parser) parsePrimary() (model.Expr, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "expected )")
+		}
+		return expr, nil
+	case tokWord:
+		return p.parseTerm(tok)
+	default:
+		return nil, p.errorf(tok, "expected a field or (")
+	}
+}
+
+func (p *parser) parseTerm(field token) (model.Expr, error) {
+	kind, ok := model.QueryFields[field.text]
+	if !ok {
+		return nil, p.errorf(field, "unknown field, expected one of %s", strings.Join(fieldNames(), ", "))
+	}
+
+	opTok := p.advance()
+	if opTok.kind == tokIn {
+		return p.parseIn(field.text, kind)
+	}
+	if opTok.kind != tokOp {
+		return nil, p.errorf(opTok, "expected an operator or IN after %s", field.text)
+	}
+	op := opTok.text
+	if op == "=" {
+		op = model.OpEq
+	}
+
+	valueTok := p.advance()
+	if valueTok.kind != tokWord && valueTok.kind != tokString {
+		return nil, p.errorf(valueTok, "expected a value")
+	}
+
+	// ranges and prefixes are only read from bare words, quoted ".." and "*" are literal
+	if op == model.OpEq && valueTok.kind == tokWord {
+		if from, to, ok := strings.Cut(valueTok.text, ".."); ok {
+			if kind == model.KindText || kind == model.KindBool {
+				return nil, p.errorf(valueTok, "%s has no ranges", field.text)
+			}
+			fromValue, err := p.value(field.text, kind, token{kind: tokWord, text: from, pos: valueTok.pos})
+			if err != nil {
+				return nil, err
+			}
+			toValue, err := p.value(field.text, kind, token{kind: tokWord, text: to, pos: valueTok.pos + len([]rune(from)) + 2})
+			if err != nil {
+				return nil, err
+			}
+			return model.Range{Field: field.text, From: fromValue, To: toValue}, nil
+		}
+		if prefix, ok := strings.CutSuffix(valueTok.text, "*"); ok && kind == model.KindText {
+			if prefix == "" {
+				return nil, p.errorf(valueTok, "expected a prefix before *")
+			}
+			return model.Compare{Field: field.text, Op: model.OpPrefix, Value: prefix}, nil
+		}
+	}
+
+	switch {
+	case op == model.OpContains && kind != model.KindText:
+		return nil, p.errorf(opTok, "~ only applies to text fields")
+	case (op == model.OpLt || op == model.OpLe || op == model.OpGt || op == model.OpGe) && (kind == model.KindText || kind == model.KindBool):
+		return nil, p.errorf(opTok, "%s does not apply to %s", op, field.text)
+	}
+	value, err := p.value(field.text, kind, valueTok)
+	if err != nil {
+		return nil, err
+	}
+	return model.Compare{Field: field.text, Op: op, Value: value}, nil
+}
+
+func (p *parser) parseIn(field string, kind model.FieldKind) (model.Expr, error) {
+	if tok := p.advance(); tok.kind != tokLParen {
+		return nil, p.errorf(tok, "expected ( after IN")
+	}
+	var values []any
+	for {
+		tok := p.advance()
+		if tok.kind != tokWord && tok.kind != tokString {
+			return nil, p.errorf(tok, "expected a value")
+		}
+		value, err := p.value(field, kind, tok)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		switch sep := p.advance(); sep.kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			return model.In{Field: field, Values: values}, nil
+		default:
+			return nil, p.errorf(sep, "expected , or )")
+		}
+	}
+}
+
+// value converts the text of a value token to the kind of the field.
+func (p *parser) value(field string, kind model.FieldKind, tok token) (any, error) {
+	switch kind {
+	case model.KindNumber:
+		n, err := strconv.Atoi(tok.text)
+		if field == model.FieldYear && (err != nil || n < 1 || n > 9999) {
+			return nil, p.errorf(tok, "expected a year between 1 and 9999")
+		}
+		if err != nil {
+			return nil, p.errorf(tok, "expected a number")
+		}
+		return n, nil
+	case model.KindDate:
+		date, err := model.NormalizeDate(tok.text)
+		if err != nil || date == "" {
+			return nil, p.errorf(tok, "expected a date as YYYY-MM-DD or DD.MM.YYYY")
+		}
+		return date, nil
+	case model.KindBool:
+		b, err := strconv.ParseBool(tok.text)
+		if err != nil {
+			return nil, p.errorf(tok, "expected true or false")
+		}
+		return b, nil
+	default:
+		return tok.text, nil
+	}
+}
+
+func fieldNames() []string {
+	names := make([]string, 0, len(model.QueryFields))
+	for name := range model.QueryFields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
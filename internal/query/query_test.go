@@ -0,0 +1,215 @@
+package query_test
+
+import (
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/query"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func eq(field string, value any) model.Compare {
+	return model.Compare{Field: field, Op: model.OpEq, Value: value}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  model.Expr
+	}{
+		{
+			name:  "single term",
+			input: "group:Muse",
+			want:  eq(model.FieldGroup, "Muse"),
+		},
+		{
+			name:  "equals sign",
+			input: "year=2005",
+			want:  eq(model.FieldYear, 2005),
+		},
+		{
+			name:  "AND binds tighter than OR",
+			input: "group:Muse OR group:Placebo AND year>=2005",
+			want: model.Or{
+				Left: eq(model.FieldGroup, "Muse"),
+				Right: model.And{
+					Left:  eq(model.FieldGroup, "Placebo"),
+					Right: model.Compare{Field: model.FieldYear, Op: model.OpGe, Value: 2005},
+				},
+			},
+		},
+		{
+			name:  "parentheses",
+			input: "(group:Muse OR group:Placebo) AND year>=2005",
+			want: model.And{
+				Left: model.Or{
+					Left:  eq(model.FieldGroup, "Muse"),
+					Right: eq(model.FieldGroup, "Placebo"),
+				},
+				Right: model.Compare{Field: model.FieldYear, Op: model.OpGe, Value: 2005},
+			},
+		},
+		{
+			name:  "operators are left associative",
+			input: "id:1 OR id:2 OR id:3",
+			want: model.Or{
+				Left:  model.Or{Left: eq(model.FieldID, 1), Right: eq(model.FieldID, 2)},
+				Right: eq(model.FieldID, 3),
+			},
+		},
+		{
+			name:  "NOT binds tighter than AND",
+			input: "NOT group:Muse AND enriched:true",
+			want: model.And{
+				Left:  model.Not{Expr: eq(model.FieldGroup, "Muse")},
+				Right: eq(model.FieldEnriched, true),
+			},
+		},
+		{
+			name:  "NOT of a group",
+			input: "NOT (group:Muse OR NOT enriched:false)",
+			want: model.Not{Expr: model.Or{
+				Left:  eq(model.FieldGroup, "Muse"),
+				Right: model.Not{Expr: eq(model.FieldEnriched, false)},
+			}},
+		},
+		{
+			name:  "comparisons",
+			input: "group!=Muse AND version<3 AND version<=3 AND id>1",
+			want: model.And{
+				Left: model.And{
+					Left: model.And{
+						Left:  model.Compare{Field: model.FieldGroup, Op: model.OpNe, Value: "Muse"},
+						Right: model.Compare{Field: model.FieldVersion, Op: model.OpLt, Value: 3},
+					},
+					Right: model.Compare{Field: model.FieldVersion, Op: model.OpLe, Value: 3},
+				},
+				Right: model.Compare{Field: model.FieldID, Op: model.OpGt, Value: 1},
+			},
+		},
+		{
+			name:  "number range",
+			input: "year:2000..2009",
+			want:  model.Range{Field: model.FieldYear, From: 2000, To: 2009},
+		},
+		{
+			name:  "date range in both layouts",
+			input: "release_date:01.01.2000..2009-12-31",
+			want:  model.Range{Field: model.FieldReleaseDate, From: "2000-01-01", To: "2009-12-31"},
+		},
+		{
+			name:  "IN list",
+			input: `group IN (Muse, "Placebo", "The Killers")`,
+			want:  model.In{Field: model.FieldGroup, Values: []any{"Muse", "Placebo", "The Killers"}},
+		},
+		{
+			name:  "IN list of numbers",
+			input: "id IN (1,2)",
+			want:  model.In{Field: model.FieldID, Values: []any{1, 2}},
+		},
+		{
+			name:  "prefix",
+			input: "song:Up*",
+			want:  model.Compare{Field: model.FieldSong, Op: model.OpPrefix, Value: "Up"},
+		},
+		{
+			name:  "contains",
+			input: `text~"black hole"`,
+			want:  model.Compare{Field: model.FieldText, Op: model.OpContains, Value: "black hole"},
+		},
+		{
+			name:  "quoted star and dots are literal",
+			input: `song:"Up*" OR song:"a..b"`,
+			want:  model.Or{Left: eq(model.FieldSong, "Up*"), Right: eq(model.FieldSong, "a..b")},
+		},
+		{
+			name:  "escaped quote",
+			input: `text~"say \"hi\""`,
+			want:  model.Compare{Field: model.FieldText, Op: model.OpContains, Value: `say "hi"`},
+		},
+		{
+			name:  "lower case keywords are values",
+			input: "group:or",
+			want:  eq(model.FieldGroup, "or"),
+		},
+		{
+			name:  "Cyrillic value",
+			input: "group:Кино",
+			want:  eq(model.FieldGroup, "Кино"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := query.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+		token string
+		msg   string
+	}{
+		{"empty", "   ", 4, "", "empty query"},
+		{"unterminated quote", `group:"Muse`, 7, `"Muse`, "unterminated string"},
+		{"dangling AND", "group:Muse AND", 15, "", "expected a field or ("},
+		{"dangling OR inside parentheses", "(group:Muse OR)", 15, ")", "expected a field or ("},
+		{"unknown field", "genre:rock", 1, "genre", "unknown field"},
+		{"missing operator", "group Muse", 7, "Muse", "expected an operator or IN"},
+		{"unknown operator", "group!Muse", 6, "!", "unexpected character"},
+		{"order on text", "group<Muse", 6, "<", "< does not apply to group"},
+		{"contains on a number", "year~2005", 5, "~", "~ only applies to text fields"},
+		{"missing value", "group:", 7, "", "expected a value"},
+		{"unclosed parenthesis", "(group:Muse", 12, "", "expected )"},
+		{"extra parenthesis", "group:Muse)", 11, ")", "expected AND, OR or the end of the query"},
+		{"two terms without operator", "group:Muse year:2005", 12, "year", "expected AND, OR or the end of the query"},
+		{"bad number", "id:abc", 4, "abc", "expected a number"},
+		{"bad year", "year:20x5", 6, "20x5", "expected a year"},
+		{"bad range end", "year:2000..abc", 12, "abc", "expected a year"},
+		{"range on text", "song:a..b", 6, "a..b", "song has no ranges"},
+		{"bad date", "release_date:2005-13-01", 14, "2005-13-01", "expected a date"},
+		{"bad bool", "enriched:yes", 10, "yes", "expected true or false"},
+		{"empty prefix", "song:*", 6, "*", "expected a prefix before *"},
+		{"IN without list", "group IN Muse", 10, "Muse", "expected ( after IN"},
+		{"IN without separator", "group IN (a b)", 13, "b", "expected , or )"},
+		{"IN without values", "group IN ()", 11, ")", "expected a value"},
+		{"position counts characters", `song:"Звезда" AND genre:x`, 19, "genre", "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := query.Parse(tt.input)
+			var syntaxErr *query.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q) error = %v, want a *query.SyntaxError", tt.input, err)
+			}
+			if syntaxErr.Pos != tt.pos || syntaxErr.Token != tt.token {
+				t.Errorf("Parse(%q) error at %d near %q, want %d near %q", tt.input, syntaxErr.Pos, syntaxErr.Token, tt.pos, tt.token)
+			}
+			if !strings.Contains(syntaxErr.Msg, tt.msg) {
+				t.Errorf("Parse(%q) error message %q, want it to contain %q", tt.input, syntaxErr.Msg, tt.msg)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorMessage(t *testing.T) {
+	_, err := query.Parse("genre:rock")
+	if err == nil || !strings.Contains(err.Error(), `at position 1 near "genre"`) {
+		t.Errorf("error = %v, want it to point at genre", err)
+	}
+	_, err = query.Parse("group:Muse AND")
+	if err == nil || !strings.Contains(err.Error(), "at the end of the query") {
+		t.Errorf("error = %v, want it to point at the end of the query", err)
+	}
+}
@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"go_test_effective_mobile/internal/config"
+	"go_test_effective_mobile/internal/enrichment"
 	"go_test_effective_mobile/internal/handlers"
+	"time"
 
 	echoSwagger "github.com/swaggo/echo-swagger"
 
@@ -20,19 +23,44 @@ type Server struct {
 	logger         *zap.SugaredLogger
 	endPointServer string
 	handler        *handlers.Handler
+	purgeInterval  time.Duration
+	stopPurge      context.CancelFunc
 }
 
-func New(logLvl, endPointServer, endPointDB string, limitParam, pageParam, verseParam int) (*Server, error) {
-	ZapLog, err := logger.InitLogger(logLvl)
+func New(cfg *config.Config) (*Server, error) {
+	ZapLog, err := logger.InitLogger(cfg.LogLevel)
 	if err != nil {
 		return nil, err
 	}
 
-	h, err := handlers.NewHandler(ZapLog, limitParam, pageParam, verseParam, endPointDB)
+	var info handlers.InfoProvider
+	if cfg.InfoAPIEndPoint != "" {
+		info = enrichment.NewClient(ZapLog, cfg.InfoAPIEndPoint, enrichment.Options{
+			Timeout:          cfg.InfoAPITimeout,
+			Retries:          cfg.InfoAPIRetries,
+			BackoffBase:      cfg.InfoAPIBackoff,
+			BackoffMax:       cfg.InfoAPIMaxBackoff,
+			BreakerThreshold: cfg.InfoAPIBreakerThreshold,
+			BreakerCooldown:  cfg.InfoAPIBreakerCooldown,
+		})
+	}
+
+	h, err := handlers.NewHandler(ZapLog, handlers.Config{
+		Driver:            cfg.StorageDriver,
+		EndPointDB:        cfg.DataBaseEndPoint,
+		DefaultLimit:      cfg.DefaultLimit,
+		DefaultPage:       cfg.DefaultPage,
+		DefaultVerse:      cfg.DefaultVerse,
+		DefaultVerseLimit: cfg.DefaultVerseLimit,
+		MaxLimit:          cfg.MaxLimit,
+		TrashRetention:    cfg.TrashRetention,
+		Info:              info,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	endPointServer := cfg.ServerEndPoint
 	ZapLog.Debugw("Initializing Echo framework", "endPointServer", endPointServer)
 
 	e := echo.New()
@@ -44,6 +72,7 @@ func New(logLvl, endPointServer, endPointDB string, limitParam, pageParam, verse
 
 	e.Use(middlewares.GetLogg(ZapLog))
 	e.Use(middleware.Gzip())
+	e.Use(middlewares.Actor())
 
 	ZapLog.Debug("Defining routes")
 
@@ -52,26 +81,101 @@ func New(logLvl, endPointServer, endPointDB string, limitParam, pageParam, verse
 	songsGroup := e.Group("/songs")
 
 	songsGroup.GET("", h.GetSongs)
+	songsGroup.GET("/search", h.SearchSongs)
 	songsGroup.GET("/:id", h.GetSongByID)
 	songsGroup.GET("/:id/verse", h.GetSongVerseByID)
+	songsGroup.GET("/:id/text", h.GetSongText)
+	songsGroup.GET("/:id/revisions", h.GetSongRevisions)
+	songsGroup.GET("/:id/revisions/diff", h.DiffSongRevisions)
+	songsGroup.GET("/:id/revisions/:rev", h.GetSongRevision)
+	songsGroup.GET("/:id/artists", h.GetSongArtists)
+	songsGroup.GET("/:id/covers", h.GetSongCovers)
+	songsGroup.GET("/:id/tags", h.GetSongTags)
 
 	songsGroup.POST("", h.AddSong)
+	songsGroup.POST("/:id/revisions/:rev/revert", h.RevertSong)
+	songsGroup.POST("/:id/tags", h.AddSongTags)
 
 	songsGroup.PUT("/:id", h.UpdateSong)
+	songsGroup.PUT("/:id/artists", h.SetSongArtists)
+	songsGroup.PATCH("/:id", h.PatchSong)
 
 	songsGroup.DELETE("/:id", h.DeleteSong)
+	songsGroup.DELETE("/:id/tags/:tag", h.RemoveSongTag)
+
+	trashGroup := e.Group("/trash")
+
+	trashGroup.GET("", h.GetTrash)
+	trashGroup.POST("/:id/restore", h.RestoreSong)
+	trashGroup.DELETE("", h.PurgeTrash)
+
+	groupsGroup := e.Group("/groups")
+
+	groupsGroup.GET("", h.GetArtists)
+	groupsGroup.GET("/:id", h.GetArtist)
+	groupsGroup.PATCH("/:id", h.RenameArtist)
+	groupsGroup.POST("/:id/merge", h.MergeArtists)
+
+	albumsGroup := e.Group("/albums")
+
+	albumsGroup.GET("", h.GetAlbums)
+	albumsGroup.GET("/:id", h.GetAlbum)
+	albumsGroup.GET("/:id/songs", h.GetAlbumSongs)
+	albumsGroup.POST("", h.AddAlbum)
+	albumsGroup.PUT("/:id", h.UpdateAlbum)
+	albumsGroup.DELETE("/:id", h.DeleteAlbum)
+
+	e.GET("/tags", h.GetTags)
+
+	playlistsGroup := e.Group("/playlists")
+
+	playlistsGroup.GET("", h.GetPlaylists)
+	playlistsGroup.GET("/:id", h.GetPlaylist)
+	playlistsGroup.GET("/:id/entries", h.GetPlaylistEntries)
+	playlistsGroup.POST("", h.AddPlaylist)
+	playlistsGroup.POST("/:id/entries", h.AddPlaylistEntry)
+	playlistsGroup.PATCH("/:id", h.RenamePlaylist)
+	playlistsGroup.PATCH("/:id/entries/:entryId", h.MovePlaylistEntry)
+	playlistsGroup.DELETE("/:id", h.DeletePlaylist)
+	playlistsGroup.DELETE("/:id/entries/:entryId", h.RemovePlaylistEntry)
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	return &Server{server: e, logger: ZapLog, endPointServer: endPointServer, handler: h}, nil
+	return &Server{server: e, logger: ZapLog, endPointServer: endPointServer, handler: h, purgeInterval: cfg.TrashPurgeInterval}, nil
 }
 
 func (s *Server) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopPurge = cancel
+	if s.purgeInterval > 0 {
+		go s.purgeTrash(ctx)
+	}
+
 	s.logger.Info("Server starting on: ", s.endPointServer)
 	return s.server.Start(s.endPointServer)
 }
+
+// purgeTrash removes expired songs from the trash every purgeInterval until ctx is cancelled.
+func (s *Server) purgeTrash(ctx context.Context) {
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.handler.PurgeExpiredTrash(ctx); err != nil {
+			s.logger.Errorw("Failed to purge trash", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Info("Server shutting down")
+	if s.stopPurge != nil {
+		s.stopPurge()
+	}
 	if err := s.server.Server.Shutdown(ctx); err != nil {
 		return err
 	}
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// albumsQuery selects albums with the name of their artist and the number of their live songs,
+// ordered like a discography: by release date, albums without one first, then by title.
+func (s *Storage) albumsQuery() squirrel.SelectBuilder {
+	columns := []string{"al.id", "al.title", "ar.name", "al.artist_id", "al.release_date", "al.cover_link"}
+	return squirrel.Select(columns...).Column("count(s.id)").From("albums al").
+		Join("artists ar ON ar.id = al.artist_id").
+		LeftJoin("songs s ON s.album_id = al.id AND s.deleted_at IS NULL").
+		GroupBy(columns...).
+		OrderBy(s.sortExpr(model.SortReleaseDate, "al.release_date"), s.sortExpr(model.SortSong, "al.title"), "al.id")
+}
+
+func scanAlbum(row rowScanner) (model.Album, error) {
+	var a model.Album
+	err := row.Scan(&a.ID, &a.Title, &a.Group, &a.ArtistID, releaseDate(&a.ReleaseDate), &a.CoverLink, &a.SongCount)
+	return a, err
+}
+
+// GetAlbums returns a page of albums, only the ones of an artist when artistID is not zero.
+func (s *Storage) GetAlbums(ctx context.Context, artistID, limit, offset int) ([]model.Album, error) {
+	s.logger.Debugw("Fetching albums", "artistID", artistID, "limit", limit, "offset", offset)
+
+	query := s.albumsQuery().Limit(uint64(limit)).Offset(uint64(offset))
+	if artistID != 0 {
+		query = query.Where(squirrel.Eq{"al.artist_id": artistID})
+	}
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	albums := make([]model.Album, 0)
+	for rows.Next() {
+		a, err := scanAlbum(rows)
+		if err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		albums = append(albums, a)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	return albums, nil
+}
+
+func (s *Storage) GetAlbum(ctx context.Context, id int) (model.Album, error) {
+	s.logger.Debug("Fetching album by ID:", id)
+
+	a, err := s.album(ctx, s.db, id)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return a, err
+}
+
+// album reads an album with q, a database or a transaction. sql.ErrNoRows is returned when it does not exist.
+func (s *Storage) album(ctx context.Context, q queryRower, id int) (model.Album, error) {
+	sqlString, args, err := s.albumsQuery().Where(squirrel.Eq{"al.id": id}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return model.Album{}, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+	return scanAlbum(q.QueryRowContext(ctx, sqlString, args...))
+}
+
+// AddAlbum adds an album of the artist named by its Group, adding the artist if there is none.
+// ErrAlbumExists is returned when the artist already has an album of the title.
+func (s *Storage) AddAlbum(ctx context.Context, album model.Album) (model.Album, error) {
+	s.logger.Debugw("Adding new album", "album", album)
+
+	date, err := releaseDateArg(album.ReleaseDate)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return album, err
+	}
+	sqlString, args, err := squirrel.Insert("albums").Columns("title", "artist_id", "release_date", "cover_link").
+		Values(album.Title, artistIDExpr(album.Group), date, album.CoverLink).
+		Suffix("ON CONFLICT (artist_id, title) DO NOTHING RETURNING id").
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return album, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	var added model.Album
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.ensureArtist(ctx, tx, album.Group); err != nil {
+			return err
+		}
+		var id int
+		err := tx.QueryRowContext(ctx, sqlString, args...).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAlbumExists
+		}
+		if err != nil {
+			return err
+		}
+		added, err = s.album(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return album, err
+	}
+	return added, nil
+}
+
+// UpdateAlbum replaces an album, ErrAlbumExists is returned when its artist has another album of the title.
+func (s *Storage) UpdateAlbum(ctx context.Context, album model.Album) (model.Album, error) {
+	s.logger.Debugw("Updating album", "album", album)
+
+	date, err := releaseDateArg(album.ReleaseDate)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return album, err
+	}
+
+	var updated model.Album
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := s.album(ctx, tx, album.ID)
+		if err != nil {
+			return err
+		}
+		if err = s.ensureArtist(ctx, tx, album.Group); err != nil {
+			return err
+		}
+
+		sqlString, args, err := squirrel.Select("count(*)").From("albums").
+			Where(squirrel.Eq{"title": album.Title}).
+			Where(squirrel.Expr("artist_id = ?", artistIDExpr(album.Group))).
+			Where(squirrel.NotEq{"id": album.ID}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		var taken int
+		if err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&taken); err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrAlbumExists
+		}
+
+		sqlString, args, err = squirrel.Update("albums").
+			Set("title", album.Title).
+			Set("artist_id", artistIDExpr(album.Group)).
+			Set("release_date", date).
+			Set("cover_link", album.CoverLink).
+			Where(squirrel.Eq{"id": album.ID}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+		if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+			return err
+		}
+		if err = s.deleteOrphanArtists(ctx, tx, []int{current.ArtistID}); err != nil {
+			return err
+		}
+		updated, err = s.album(ctx, tx, album.ID)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return album, err
+	}
+	return updated, nil
+}
+
+// DeleteAlbum removes an album. Its songs, including the ones in the trash, stay without an album
+// and get a new version and revision.
+func (s *Storage) DeleteAlbum(ctx context.Context, id int) error {
+	s.logger.Debug("Deleting album by ID:", id)
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := s.album(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		err = s.rewriteSongs(ctx, tx, squirrel.Update("songs").
+			Set("album_id", nil).
+			Set("track_number", nil).
+			Where(squirrel.Eq{"album_id": id}))
+		if err != nil {
+			return err
+		}
+		sqlString, args, err := squirrel.Delete("albums").Where(squirrel.Eq{"id": id}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+			return err
+		}
+		return s.deleteOrphanArtists(ctx, tx, []int{current.ArtistID})
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return err
+}
+
+// GetAlbumSongs returns the live songs of an album by track number, songs without one last.
+func (s *Storage) GetAlbumSongs(ctx context.Context, id int) ([]model.Song, error) {
+	s.logger.Debug("Fetching songs of album:", id)
+
+	if _, err := s.album(ctx, s.db, id); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	query := squirrel.Select(songColumns).From("songs").
+		Where(squirrel.Eq{"album_id": id, "deleted_at": nil}).
+		OrderBy("track_number IS NULL", "track_number", "id")
+	return s.querySongs(ctx, query)
+}
+
+// checkAlbum returns ErrAlbumNotFound unless the album a song is given exists, zero being no album.
+func (s *Storage) checkAlbum(ctx context.Context, tx *sql.Tx, id int) error {
+	if id == 0 {
+		return nil
+	}
+	sqlString, args, err := squirrel.Select("count(*)").From("albums").Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	var count int
+	if err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
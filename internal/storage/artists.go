@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"go_test_effective_mobile/internal/model"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// ensureArtist adds the artist of a group name unless there is one, songs refer to it by artistIDExpr.
+func (s *Storage) ensureArtist(ctx context.Context, tx *sql.Tx, name string) error {
+	sqlString, args, err := squirrel.Insert("artists").Columns("name").Values(name).
+		Suffix("ON CONFLICT (name) DO NOTHING").
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, sqlString, args...)
+	return err
+}
+
+// artistIDExpr is the ID of the artist of a group name, see ensureArtist.
+func artistIDExpr(name string) squirrel.Sqlizer {
+	return squirrel.Expr("(SELECT id FROM artists WHERE name = ?)", name)
+}
+
+// artistIDs reads the artist IDs selected by query, the artists a write is about to take references from.
+func (s *Storage) artistIDs(ctx context.Context, tx *sql.Tx, query squirrel.SelectBuilder) ([]int, error) {
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// deleteOrphanArtists removes the artists of ids that no song, album or credit refers to any more, in the
+// transaction of the write that took the last reference. Songs in the trash keep their artist, they can be restored.
+func (s *Storage) deleteOrphanArtists(ctx context.Context, tx *sql.Tx, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	sqlString, args, err := squirrel.Delete("artists").Where(squirrel.Eq{"id": ids}).
+		Where("NOT EXISTS (SELECT 1 FROM songs WHERE songs.artist_id = artists.id)").
+		Where("NOT EXISTS (SELECT 1 FROM albums WHERE albums.artist_id = artists.id)").
+		Where("NOT EXISTS (SELECT 1 FROM song_artists WHERE song_artists.artist_id = artists.id)").
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+	_, err = tx.ExecContext(ctx, sqlString, args...)
+	return err
+}
+
+// songArtistIDs selects the artist of a song, including one in the trash, see artistIDs.
+func songArtistIDs(id any) squirrel.SelectBuilder {
+	return squirrel.Select("artist_id").From("songs").Where(squirrel.Eq{"id": id})
+}
+
+// artistsQuery selects artists with the number of their live songs, ordered by name like songs are by group.
+func (s *Storage) artistsQuery() squirrel.SelectBuilder {
+	return squirrel.Select("a.id", "a.name", "count(s.id)").From("artists a").
+		LeftJoin("songs s ON s.artist_id = a.id AND s.deleted_at IS NULL").
+		GroupBy("a.id", "a.name").
+		OrderBy(s.sortExpr(model.SortGroup, "a.name"), "a.id")
+}
+
+// GetArtists returns a page of artists ordered by name.
+func (s *Storage) GetArtists(ctx context.Context, limit, offset int) ([]model.Artist, error) {
+	s.logger.Debugw("Fetching artists", "limit", limit, "offset", offset)
+
+	sqlString, args, err := s.artistsQuery().Limit(uint64(limit)).Offset(uint64(offset)).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	artists := make([]model.Artist, 0)
+	for rows.Next() {
+		var a model.Artist
+		if err = rows.Scan(&a.ID, &a.Name, &a.SongCount); err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		artists = append(artists, a)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	return artists, nil
+}
+
+func (s *Storage) GetArtist(ctx context.Context, id int) (model.Artist, error) {
+	s.logger.Debug("Fetching artist by ID:", id)
+
+	a, err := s.artist(ctx, s.db, id)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return a, err
+}
+
+// artist reads an artist with q, a database or a transaction. sql.ErrNoRows is returned when it does not exist.
+func (s *Storage) artist(ctx context.Context, q queryRower, id int) (model.Artist, error) {
+	sqlString, args, err := s.artistsQuery().Where(squirrel.Eq{"a.id": id}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return model.Artist{}, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	var a model.Artist
+	err = q.QueryRowContext(ctx, sqlString, args...).Scan(&a.ID, &a.Name, &a.SongCount)
+	return a, err
+}
+
+// RenameArtist renames an artist together with the group of all of its songs, each song gets a new version.
+// ErrArtistExists is returned when another artist has the name, the two can be merged instead.
+func (s *Storage) RenameArtist(ctx context.Context, id int, name string) (model.Artist, error) {
+	s.logger.Debugw("Renaming artist", "id", id, "name", name)
+
+	var renamed model.Artist
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := s.artist(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.Name == name {
+			renamed = current
+			return nil
+		}
+		if taken, err := s.artistNameTaken(ctx, tx, name); err != nil || taken {
+			if taken {
+				return ErrArtistExists
+			}
+			return err
+		}
+
+		sqlString, args, err := squirrel.Update("artists").Set("name", name).Where(squirrel.Eq{"id": id}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+			return err
+		}
+		if err = s.moveSongs(ctx, tx, id, id, name); err != nil {
+			return err
+		}
+		renamed, err = s.artist(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Artist{}, err
+	}
+	return renamed, nil
+}
+
+// MergeArtists moves the songs, albums and credits of the artist from over to the artist id under its name and removes
+// the artist from. ErrSongExists is returned when both artists have a live song of the same name,
+// ErrAlbumExists when they have an album of the same title.
+func (s *Storage) MergeArtists(ctx context.Context, id, from int) (model.Artist, error) {
+	s.logger.Debugw("Merging artists", "id", id, "from", from)
+
+	var merged model.Artist
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		target, err := s.artist(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if _, err = s.artist(ctx, tx, from); err != nil {
+			return err
+		}
+
+		sqlString, args, err := squirrel.Select("count(*)").From("songs a").
+			Join("songs b ON b.song = a.song").
+			Where(squirrel.Eq{"a.artist_id": from, "b.artist_id": id, "a.deleted_at": nil, "b.deleted_at": nil}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		var clashes int
+		if err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&clashes); err != nil {
+			return err
+		}
+		if clashes > 0 {
+			return ErrSongExists
+		}
+		sqlString, args, err = squirrel.Select("count(*)").From("albums a").
+			Join("albums b ON b.title = a.title").
+			Where(squirrel.Eq{"a.artist_id": from, "b.artist_id": id}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		if err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&clashes); err != nil {
+			return err
+		}
+		if clashes > 0 {
+			return ErrAlbumExists
+		}
+
+		if err = s.moveSongs(ctx, tx, from, id, target.Name); err != nil {
+			return err
+		}
+		if err = s.moveCredits(ctx, tx, from, id); err != nil {
+			return err
+		}
+		sqlString, args, err = squirrel.Update("albums").Set("artist_id", id).Where(squirrel.Eq{"artist_id": from}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+			return err
+		}
+		sqlString, args, err = squirrel.Delete("artists").Where(squirrel.Eq{"id": from}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+			return err
+		}
+		merged, err = s.artist(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Artist{}, err
+	}
+	return merged, nil
+}
+
+// moveSongs gives all the songs of the artist from, including the ones in the trash, to the artist to named name.
+func (s *Storage) moveSongs(ctx context.Context, tx *sql.Tx, from, to int, name string) error {
+	return s.rewriteSongs(ctx, tx, squirrel.Update("songs").
+		Set("artist_id", to).
+		Set("group_name", name).
+		Set("group_key", nameKey(name)).
+		Where(squirrel.Eq{"artist_id": from}))
+}
+
+// rewriteSongs runs an update of songs that may include the ones in the trash as an edit of each of them:
+// the songs get a new version and revision.
+func (s *Storage) rewriteSongs(ctx context.Context, tx *sql.Tx, query squirrel.UpdateBuilder) error {
+	sqlString, args, err := query.
+		Set("version", squirrel.Expr("version + 1")).
+		Suffix("RETURNING " + songColumns).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := tx.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		return err
+	}
+	var rewritten []model.Song
+	for rows.Next() {
+		var song model.Song
+		if err = rows.Scan(songDests(&song, songColumnList)...); err != nil {
+			rows.Close()
+			return err
+		}
+		rewritten = append(rewritten, song)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// revisions are written once the rows are closed, the transaction has a single connection
+	for _, song := range rewritten {
+		if err = s.writeRevision(ctx, tx, model.RevisionUpdate, song); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) artistNameTaken(ctx context.Context, tx *sql.Tx, name string) (bool, error) {
+	sqlString, args, err := squirrel.Select("count(*)").From("artists").Where(squirrel.Eq{"name": name}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return false, err
+	}
+	var count int
+	err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&count)
+	return count > 0, err
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"go_test_effective_mobile/internal/model"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// creditedExpr matches the songs an artist is credited on in any role, the group of a song being its main artist.
+func creditedExpr(artistID int) squirrel.Sqlizer {
+	return squirrel.Or{
+		squirrel.Eq{"artist_id": artistID},
+		squirrel.Expr("id IN (SELECT song_id FROM song_artists WHERE artist_id = ?)", artistID),
+	}
+}
+
+// GetSongArtists returns the artists credited on a live song: its group first, then the others by role and name.
+func (s *Storage) GetSongArtists(ctx context.Context, id int) ([]model.Credit, error) {
+	s.logger.Debug("Fetching artists of song:", id)
+
+	credits, err := s.songArtists(ctx, s.db, id)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return credits, err
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	queryRower
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// songArtists reads the credits of a song with q, a database or a transaction.
+// sql.ErrNoRows is returned when there is no such live song.
+func (s *Storage) songArtists(ctx context.Context, q querier, id int) ([]model.Credit, error) {
+	sqlString, args, err := squirrel.Select("artist_id", "group_name").From("songs").
+		Where(squirrel.Eq{"id": id, "deleted_at": nil}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	main := model.Credit{Role: model.RoleMain}
+	if err = q.QueryRowContext(ctx, sqlString, args...).Scan(&main.ArtistID, &main.Name); err != nil {
+		return nil, err
+	}
+
+	sqlString, args, err = squirrel.Select("a.id", "a.name", "sa.role").From("song_artists sa").
+		Join("artists a ON a.id = sa.artist_id").
+		Where(squirrel.Eq{"sa.song_id": id}).
+		OrderBy("sa.role", s.sortExpr(model.SortGroup, "a.name"), "a.id").
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := q.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []model.Credit{main}
+	for rows.Next() {
+		var c model.Credit
+		if err = rows.Scan(&c.ArtistID, &c.Name, &c.Role); err != nil {
+			return nil, err
+		}
+		credits = append(credits, c)
+	}
+	return credits, rows.Err()
+}
+
+// SetSongArtists replaces the artists credited on a live song besides its group, adding the artists there are none of.
+// It returns all the credits of the song like GetSongArtists.
+func (s *Storage) SetSongArtists(ctx context.Context, id int, credits []model.Credit) ([]model.Credit, error) {
+	s.logger.Debugw("Crediting artists on song", "id", id, "credits", credits)
+
+	var res []model.Credit
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		live, err := s.songExists(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !live {
+			return sql.ErrNoRows
+		}
+		previous, err := s.artistIDs(ctx, tx, squirrel.Select("artist_id").From("song_artists").Where(squirrel.Eq{"song_id": id}))
+		if err != nil {
+			return err
+		}
+
+		sqlString, args, err := squirrel.Delete("song_artists").Where(squirrel.Eq{"song_id": id}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+			return err
+		}
+		for _, c := range credits {
+			if err = s.ensureArtist(ctx, tx, c.Name); err != nil {
+				return err
+			}
+			sqlString, args, err = squirrel.Insert("song_artists").Columns("song_id", "artist_id", "role").
+				Values(id, artistIDExpr(c.Name), c.Role).
+				Suffix("ON CONFLICT DO NOTHING").
+				PlaceholderFormat(s.placeholder).ToSql()
+			if err != nil {
+				return err
+			}
+			if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+				return err
+			}
+		}
+		if err = s.deleteOrphanArtists(ctx, tx, previous); err != nil {
+			return err
+		}
+		res, err = s.songArtists(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetSongCovers returns the live covers of a live song by ID.
+func (s *Storage) GetSongCovers(ctx context.Context, id int) ([]model.Song, error) {
+	s.logger.Debug("Fetching covers of song:", id)
+
+	live, err := s.songExists(ctx, s.db, id)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	if !live {
+		return nil, sql.ErrNoRows
+	}
+	query := squirrel.Select(songColumns).From("songs").
+		Where(squirrel.Eq{"cover_of": id, "deleted_at": nil}).
+		OrderBy("id")
+	return s.querySongs(ctx, query)
+}
+
+// checkOriginal returns ErrOriginalNotFound unless the original a cover is given is a live song, zero being none.
+func (s *Storage) checkOriginal(ctx context.Context, tx *sql.Tx, id int) error {
+	if id == 0 {
+		return nil
+	}
+	live, err := s.songExists(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if !live {
+		return ErrOriginalNotFound
+	}
+	return nil
+}
+
+// songExists reports whether there is a live song with the ID.
+func (s *Storage) songExists(ctx context.Context, q queryRower, id int) (bool, error) {
+	sqlString, args, err := squirrel.Select("count(*)").From("songs").Where(squirrel.Eq{"id": id, "deleted_at": nil}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return false, err
+	}
+	var count int
+	err = q.QueryRowContext(ctx, sqlString, args...).Scan(&count)
+	return count > 0, err
+}
+
+// moveCredits credits the artist to instead of from, where the song does not credit to in the same role already.
+func (s *Storage) moveCredits(ctx context.Context, tx *sql.Tx, from, to int) error {
+	sqlString, args, err := squirrel.Delete("song_artists").
+		Where(squirrel.Eq{"artist_id": from}).
+		Where("EXISTS (SELECT 1 FROM song_artists o WHERE o.song_id = song_artists.song_id AND o.role = song_artists.role AND o.artist_id = ?)", to).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+		return err
+	}
+	sqlString, args, err = squirrel.Update("song_artists").Set("artist_id", to).Where(squirrel.Eq{"artist_id": from}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, sqlString, args...)
+	return err
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"cmp"
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// sortScore orders fuzzy matches by their similarity, it is not one of model.SortFields.
+const sortScore = "score"
+
+// sortColumns are the columns behind the sort fields.
+var sortColumns = map[string]string{
+	sortScore:             "score",
+	model.SortID:          "id",
+	model.SortGroup:       "group_name",
+	model.SortSong:        "song",
+	model.SortReleaseDate: "release_date",
+}
+
+// Sort expressions wrap both the column and the cursor value they are compared with: names are compared
+// by the rules of Russian and songs without a release date come first, as in the in-process songComparer.
+var (
+	postgresSortExprs = map[string]string{
+		model.SortGroup:       "%s COLLATE songs_ru",
+		model.SortSong:        "%s COLLATE songs_ru",
+		model.SortReleaseDate: "COALESCE(%s, DATE '0001-01-01')",
+	}
+	sqliteSortExprs = map[string]string{
+		model.SortGroup:       "songs_collation_key(%s)",
+		model.SortSong:        "songs_collation_key(%s)",
+		model.SortReleaseDate: "COALESCE(%s, '')",
+	}
+)
+
+// scoredFilter reports whether the songs matching filter are scored.
+func scoredFilter(filter model.SongFilter) bool {
+	return filter.Match == model.MatchFuzzy && (filter.Group != "" || filter.Song != "")
+}
+
+// songOrder returns the fields the songs matching filter are ordered by, always ending with the ID
+// so songs with equal values keep their order between pages.
+func songOrder(filter model.SongFilter) []model.SortField {
+	var order []model.SortField
+	if len(filter.Sort) == 0 && scoredFilter(filter) {
+		order = append(order, model.SortField{Field: sortScore, Desc: true})
+	}
+	for _, f := range filter.Sort {
+		order = append(order, f)
+		if f.Field == model.SortID {
+			return order
+		}
+	}
+	return append(order, model.SortField{Field: model.SortID})
+}
+
+// cursorSong turns a cursor back into the song it points after, with the fields of order filled in.
+func cursorSong(order []model.SortField, after *model.SongCursor) (model.Song, error) {
+	song := model.Song{ID: after.ID, Score: after.Score}
+	values := after.Values
+	for _, f := range order {
+		if f.Field == sortScore || f.Field == model.SortID {
+			continue
+		}
+		if len(values) == 0 {
+			return song, ErrInvalidCursor
+		}
+		switch f.Field {
+		case model.SortGroup:
+			song.Group = values[0]
+		case model.SortSong:
+			song.Song = values[0]
+		case model.SortReleaseDate:
+			song.ReleaseDate = values[0]
+		}
+		values = values[1:]
+	}
+	if len(values) > 0 {
+		return song, ErrInvalidCursor
+	}
+	return song, nil
+}
+
+// sortExpr applies the sort expression of field to a column or placeholder.
+func (s *Storage) sortExpr(field, operand string) string {
+	if tmpl, ok := s.sortExprs[field]; ok {
+		return fmt.Sprintf(tmpl, operand)
+	}
+	return operand
+}
+
+// orderBy returns the ORDER BY terms of order.
+func (s *Storage) orderBy(order []model.SortField) []string {
+	terms := make([]string, 0, len(order))
+	for _, f := range order {
+		term := s.sortExpr(f.Field, sortColumns[f.Field])
+		if f.Desc {
+			term += " DESC"
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// songsAfter is the condition of the songs that come after the cursor in order: equal in the leading
+// fields and past the cursor in the next one.
+func (s *Storage) songsAfter(order []model.SortField, after *model.SongCursor) (squirrel.Sqlizer, error) {
+	pivot, err := cursorSong(order, after)
+	if err != nil {
+		return nil, err
+	}
+
+	var cond squirrel.Or
+	var equal squirrel.And
+	for _, f := range order {
+		var arg any
+		switch f.Field {
+		case sortScore:
+			arg = pivot.Score
+		case model.SortID:
+			arg = pivot.ID
+		case model.SortReleaseDate:
+			if arg, err = releaseDateArg(pivot.ReleaseDate); err != nil {
+				return nil, ErrInvalidCursor
+			}
+		default:
+			arg = model.SortValue(pivot, f.Field)
+		}
+		column, value := s.sortExpr(f.Field, sortColumns[f.Field]), s.sortExpr(f.Field, "?")
+		op := " > "
+		if f.Desc {
+			op = " < "
+		}
+		cond = append(cond, append(slices.Clone(equal), squirrel.Expr(column+op+value, arg)))
+		equal = append(equal, squirrel.Expr(column+" = "+value, arg))
+	}
+	return cond, nil
+}
+
+// songComparer is the in-process counterpart of orderBy.
+type songComparer struct {
+	order    []model.SortField
+	collator *collate.Collator
+}
+
+func newSongComparer(order []model.SortField) *songComparer {
+	return &songComparer{order: order, collator: collate.New(language.Russian)}
+}
+
+func (c *songComparer) compare(a, b model.Song) int {
+	for _, f := range c.order {
+		var res int
+		switch f.Field {
+		case sortScore:
+			res = cmp.Compare(a.Score, b.Score)
+		case model.SortID:
+			res = cmp.Compare(a.ID, b.ID)
+		case model.SortReleaseDate:
+			res = strings.Compare(a.ReleaseDate, b.ReleaseDate)
+		default:
+			res = c.collator.CompareString(model.SortValue(a, f.Field), model.SortValue(b, f.Field))
+		}
+		if f.Desc {
+			res = -res
+		}
+		if res != 0 {
+			return res
+		}
+	}
+	return 0
+}
+
+// pageSongs is the in-process counterpart of the page query of GetSongs: it orders the songs matching filter
+// and returns up to limit of them that come after the cursor.
+func pageSongs(songs []model.Song, filter model.SongFilter, after *model.SongCursor, limit int) ([]model.Song, error) {
+	order := songOrder(filter)
+	c := newSongComparer(order)
+	slices.SortFunc(songs, c.compare)
+
+	start := 0
+	if after != nil {
+		pivot, err := cursorSong(order, after)
+		if err != nil {
+			return nil, err
+		}
+		start, _ = slices.BinarySearchFunc(songs, pivot, c.compare)
+		for start < len(songs) && c.compare(songs[start], pivot) <= 0 {
+			start++
+		}
+	}
+	return slices.Clone(songs[start:min(start+limit, len(songs))]), nil
+}
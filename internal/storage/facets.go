@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"go_test_effective_mobile/internal/model"
+	"slices"
+	"strconv"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// facetLimit is the number of the most frequent values returned for a facet.
+const facetLimit = 20
+
+// Facet expressions are the values songs are grouped by, years and decades are NULL for songs without a release date.
+var (
+	postgresFacetExprs = map[string]string{
+		model.FacetGroup:  "group_name",
+		model.FacetYear:   "EXTRACT(YEAR FROM release_date)::int",
+		model.FacetDecade: "EXTRACT(YEAR FROM release_date)::int / 10 * 10",
+	}
+	sqliteFacetExprs = map[string]string{
+		model.FacetGroup:  "group_name",
+		model.FacetYear:   "CAST(substr(release_date, 1, 4) AS INTEGER)",
+		model.FacetDecade: "CAST(substr(release_date, 1, 4) AS INTEGER) / 10 * 10",
+	}
+)
+
+// GetSongFacets counts the songs matching filter by the values of each of the facets, most frequent first.
+func (s *Storage) GetSongFacets(ctx context.Context, filter model.SongFilter, facets []string) (map[string][]model.FacetCount, error) {
+	s.logger.Debugw("Counting song facets", "filter", filter, "facets", facets)
+	query, _ := s.songsQuery(filter)
+	return s.countFacets(ctx, query, facets)
+}
+
+// SearchFacets counts all the songs matching a search by the values of each of the facets, most frequent first.
+func (s *Storage) SearchFacets(ctx context.Context, search model.SongSearch, facets []string) (map[string][]model.FacetCount, error) {
+	s.logger.Debugw("Counting search facets", "search", search, "facets", facets)
+	query := squirrel.Select(qualified("s", songColumnList)...).
+		From("songs s").
+		Where("s.search_vector @@ ("+searchTSQuery+")", searchTSQueryArgs(search)...).
+		Where(squirrel.Eq{"s.deleted_at": nil}).
+		Where(searchFilter(search))
+	return s.countFacets(ctx, query, facets)
+}
+
+// countFacets groups the songs selected by query once for every facet.
+func (s *Storage) countFacets(ctx context.Context, query squirrel.SelectBuilder, facets []string) (map[string][]model.FacetCount, error) {
+	res := make(map[string][]model.FacetCount, len(facets))
+	for _, facet := range facets {
+		expr := s.facetExprs[facet]
+		sqlString, args, err := squirrel.Select(expr+" AS value", "count(*)").FromSelect(query, "s").
+			Where(expr+" IS NOT NULL").
+			GroupBy("value").OrderBy("count(*) DESC", "value").Limit(facetLimit).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+		rows, err := s.db.QueryContext(ctx, sqlString, args...)
+		if err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		counts := make([]model.FacetCount, 0)
+		for rows.Next() {
+			var c model.FacetCount
+			if err = rows.Scan(&c.Value, &c.Count); err != nil {
+				rows.Close()
+				s.logger.Info(zap.Error(err))
+				return nil, err
+			}
+			counts = append(counts, c)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		res[facet] = counts
+	}
+	return res, nil
+}
+
+// GetSongFacets counts in process when names are matched in process, see GetSongs.
+func (s *SQLiteStorage) GetSongFacets(ctx context.Context, filter model.SongFilter, facets []string) (map[string][]model.FacetCount, error) {
+	if !namesInProcess(filter) {
+		return s.Storage.GetSongFacets(ctx, filter, facets)
+	}
+	s.logger.Debugw("Counting song facets", "filter", filter, "facets", facets)
+
+	dates := filter
+	dates.Group, dates.Song = "", ""
+	query, _ := s.songsQuery(dates)
+	songs, err := s.querySongs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return facetCounts(filterNames(songs, filter), facets), nil
+}
+
+// SearchFacets counts the songs matched by the FTS5 tables, see SearchSongs.
+func (s *SQLiteStorage) SearchFacets(ctx context.Context, search model.SongSearch, facets []string) (map[string][]model.FacetCount, error) {
+	s.logger.Debugw("Counting search facets", "search", search, "facets", facets)
+
+	match := ftsQuery(search.Query)
+	if match == "" {
+		return facetCounts(nil, facets), nil
+	}
+	query := squirrel.Select(qualified("s", songColumnList)...).
+		From("song_search").
+		Join("songs s ON s.id = song_search.rowid").
+		Where("song_search MATCH ?", match).
+		Where(squirrel.Eq{"s.deleted_at": nil}).
+		Where(searchFilter(search))
+	return s.countFacets(ctx, query, facets)
+}
+
+// facetCounts is the in-process counterpart of countFacets.
+func facetCounts(songs []model.Song, facets []string) map[string][]model.FacetCount {
+	res := make(map[string][]model.FacetCount, len(facets))
+	for _, facet := range facets {
+		counts := make(map[string]int)
+		for _, song := range songs {
+			if value, ok := facetValue(song, facet); ok {
+				counts[value]++
+			}
+		}
+		values := make([]model.FacetCount, 0, len(counts))
+		for value, n := range counts {
+			values = append(values, model.FacetCount{Value: value, Count: n})
+		}
+		slices.SortFunc(values, func(a, b model.FacetCount) int {
+			if c := cmp.Compare(b.Count, a.Count); c != 0 {
+				return c
+			}
+			return compareFacetValues(facet, a.Value, b.Value)
+		})
+		res[facet] = values[:min(len(values), facetLimit)]
+	}
+	return res
+}
+
+func facetValue(song model.Song, facet string) (string, bool) {
+	if facet == model.FacetGroup {
+		return song.Group, true
+	}
+	if song.ReleaseDate == "" {
+		return "", false
+	}
+	year, err := strconv.Atoi(song.ReleaseDate[:4])
+	if err != nil {
+		return "", false
+	}
+	if facet == model.FacetDecade {
+		year = year / 10 * 10
+	}
+	return strconv.Itoa(year), true
+}
+
+// compareFacetValues orders years and decades as numbers, like the SQL storages do.
+func compareFacetValues(facet, a, b string) int {
+	if facet == model.FacetGroup {
+		return cmp.Compare(a, b)
+	}
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	return cmp.Compare(x, y)
+}
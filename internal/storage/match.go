@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"go_test_effective_mobile/internal/model"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// fuzzyThreshold is the lowest similarity of a fuzzy match, the pg_trgm.similarity_threshold default.
+const fuzzyThreshold = 0.3
+
+// trigrams splits s the way pg_trgm does: every word is lower-cased, padded with two spaces in front
+// and one behind, and cut into overlapping three-rune sequences.
+func trigrams(s string) map[string]struct{} {
+	res := make(map[string]struct{})
+	for _, word := range searchTerms(s) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			res[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+	return res
+}
+
+// similarity is the in-process counterpart of the pg_trgm similarity function:
+// the share of trigrams the strings have in common, from 0 to 1.
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// matchName compares the keys of a group or song name and a filter value in the given match mode,
+// the keys are lower-cased so every mode ignores case. The similarity is only computed for fuzzy matches.
+func matchName(name, value, mode string) (float64, bool) {
+	name, value = nameKey(name), nameKey(value)
+	switch mode {
+	case model.MatchPrefix:
+		return 0, strings.HasPrefix(name, value)
+	case model.MatchFuzzy:
+		score := similarity(name, value)
+		return score, score >= fuzzyThreshold
+	default:
+		return 0, name == value
+	}
+}
+
+// filterNames keeps the songs whose group and song match the filter. Fuzzy matches get the mean similarity
+// of the filtered fields as their score, like the Postgres query does.
+func filterNames(songs []model.Song, filter model.SongFilter) []model.Song {
+	res := make([]model.Song, 0, len(songs))
+	for _, song := range songs {
+		var scores []float64
+		ok := true
+		for _, f := range []struct{ name, value string }{{song.Group, filter.Group}, {song.Song, filter.Song}} {
+			if f.value == "" {
+				continue
+			}
+			score, matched := matchName(f.name, f.value, filter.Match)
+			if !matched {
+				ok = false
+				break
+			}
+			scores = append(scores, score)
+		}
+		if !ok {
+			continue
+		}
+		if scoredFilter(filter) {
+			for _, score := range scores {
+				song.Score += score / float64(len(scores))
+			}
+		}
+		res = append(res, song)
+	}
+	return res
+}
+
+// likeEscape escapes the LIKE wildcards of a value, backslash being the escape character.
+func likeEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// likePrefix escapes the LIKE wildcards of a value and turns it into a prefix pattern.
+func likePrefix(value string) string {
+	return likeEscape(value) + "%"
+}
+
+// namesInProcess reports whether the group and song filters need a match mode the database cannot do itself.
+func namesInProcess(filter model.SongFilter) bool {
+	return (filter.Group != "" || filter.Song != "") && filter.Match != "" && filter.Match != model.MatchExact
+}
+
+// GetSongs matches group and song names in process for prefix and fuzzy matches, SQLite has neither
+// pg_trgm nor case folding beyond ASCII. The database still applies the release date filters.
+func (s *SQLiteStorage) GetSongs(ctx context.Context, filter model.SongFilter, after *model.SongCursor, limit int) ([]model.Song, int, error) {
+	if !namesInProcess(filter) {
+		return s.Storage.GetSongs(ctx, filter, after, limit)
+	}
+	s.logger.Debugw("Fetching songs with filters", "filter", filter, "after", after, "limit", limit)
+
+	dates := filter
+	dates.Group, dates.Song = "", ""
+	query, _ := s.songsQuery(dates)
+	songs, err := s.querySongs(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	songs = filterNames(songs, filter)
+	page, err := pageSongs(songs, filter, after, limit)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, 0, err
+	}
+	return page, len(songs), nil
+}
+
+func (s *SQLiteStorage) GetInfo(ctx context.Context, group, song, match string) (model.SongDetail, error) {
+	if match == "" || match == model.MatchExact {
+		return s.Storage.GetInfo(ctx, group, song, match)
+	}
+	s.logger.Debug("Fetching song info", "group", group, "song", song, "match", match)
+	songs, _, err := s.GetSongs(ctx, model.SongFilter{Group: group, Song: song, Match: match}, nil, 1)
+	return songInfo(songs, err)
+}
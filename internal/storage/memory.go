@@ -0,0 +1,1372 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"go_test_effective_mobile/internal/model"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// MemoryStorage keeps songs in process memory. It mirrors the semantics of Storage,
+// including the sql.ErrNoRows errors, so handlers behave the same on both backends.
+type MemoryStorage struct {
+	mu     sync.RWMutex
+	songs  map[int]model.Song
+	verses map[int][]model.Verse
+	trash  map[int]model.TrashedSong
+	// revisions outlive the songs they belong to
+	revisions map[int][]model.SongRevision
+	nextID    int
+	// artists are kept by ID, their song counts are counted on demand
+	artists      map[int]string
+	nextArtistID int
+	// albums are kept without their Group and SongCount, see album
+	albums      map[int]model.Album
+	nextAlbumID int
+	// credits are the artists of a song besides its group, kept without their names
+	credits map[int][]model.Credit
+	// tags of a song are kept sorted by name
+	tags map[int][]string
+	// playlists are kept without their EntryCount, entries in order with only the ID of their Song
+	playlists      map[int]model.Playlist
+	nextPlaylistID int
+	entries        map[int][]model.PlaylistEntry
+	nextEntryID    int
+	logger         *zap.SugaredLogger
+}
+
+func (s *MemoryStorage) InitStorage(logger *zap.SugaredLogger, EndPointDB string) error {
+	logger.Debug("Initializing in-memory storage")
+	s.logger = logger
+	s.songs = make(map[int]model.Song)
+	s.verses = make(map[int][]model.Verse)
+	s.trash = make(map[int]model.TrashedSong)
+	s.revisions = make(map[int][]model.SongRevision)
+	s.nextID = 1
+	s.artists = make(map[int]string)
+	s.nextArtistID = 1
+	s.albums = make(map[int]model.Album)
+	s.nextAlbumID = 1
+	s.credits = make(map[int][]model.Credit)
+	s.tags = make(map[int][]string)
+	s.playlists = make(map[int]model.Playlist)
+	s.nextPlaylistID = 1
+	s.entries = make(map[int][]model.PlaylistEntry)
+	s.nextEntryID = 1
+	return s.initMigrations()
+}
+
+func (s *MemoryStorage) initMigrations() error {
+	s.logger.Debug("In-memory storage has no migrations")
+	return nil
+}
+
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *MemoryStorage) GetSongs(ctx context.Context, filter model.SongFilter, after *model.SongCursor, limit int) ([]model.Song, int, error) {
+	s.logger.Debugw("Fetching songs with filters", "filter", filter, "after", after, "limit", limit)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	songs := s.filterSongs(filter)
+	page, err := pageSongs(songs, filter, after, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(songs), nil
+}
+
+func (s *MemoryStorage) GetSongFacets(ctx context.Context, filter model.SongFilter, facets []string) (map[string][]model.FacetCount, error) {
+	s.logger.Debugw("Counting song facets", "filter", filter, "facets", facets)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return facetCounts(s.filterSongs(filter), facets), nil
+}
+
+// filterSongs returns the songs matching filter in the order of their IDs, the caller holds the lock.
+func (s *MemoryStorage) filterSongs(filter model.SongFilter) []model.Song {
+	songs := make([]model.Song, 0)
+	for id := 1; id < s.nextID; id++ {
+		v, ok := s.songs[id]
+		if !ok {
+			continue
+		}
+		if !matchesFilter(v, filter) || filter.Artist != 0 && !s.credited(v, filter.Artist) || !s.tagged(id, filter) {
+			continue
+		}
+		songs = append(songs, v)
+	}
+	return filterNames(songs, filter)
+}
+
+func (s *MemoryStorage) SearchSongs(ctx context.Context, search model.SongSearch, limit, offset int) ([]model.SearchResult, error) {
+	s.logger.Debugw("Searching songs", "search", search, "limit", limit, "offset", offset)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := s.searchSongs(search)
+	results := make([]model.SearchResult, 0, limit)
+	for i := offset; i < len(all) && len(results) < limit; i++ {
+		results = append(results, all[i])
+	}
+	return results, nil
+}
+
+func (s *MemoryStorage) SearchFacets(ctx context.Context, search model.SongSearch, facets []string) (map[string][]model.FacetCount, error) {
+	s.logger.Debugw("Counting search facets", "search", search, "facets", facets)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := s.searchSongs(search)
+	songs := make([]model.Song, len(results))
+	for i, res := range results {
+		songs[i] = res.Song
+	}
+	return facetCounts(songs, facets), nil
+}
+
+// searchSongs returns all the songs matching a search, best first, the caller holds the lock.
+func (s *MemoryStorage) searchSongs(search model.SongSearch) []model.SearchResult {
+	terms := searchTerms(search.Query)
+	all := make([]model.SearchResult, 0)
+	for id := 1; id < s.nextID; id++ {
+		song, ok := s.songs[id]
+		if !ok || !matchesSearchFilter(song, search) {
+			continue
+		}
+		if res, ok := matchSong(song, s.verses[id], terms); ok {
+			all = append(all, res)
+		}
+	}
+	// ids are visited in order, a stable sort keeps equally ranked songs ordered by id
+	slices.SortStableFunc(all, func(a, b model.SearchResult) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+	return all
+}
+
+func (s *MemoryStorage) AddSong(ctx context.Context, song model.Song) (model.Song, error) {
+	s.logger.Debugw("Adding new song", "song", song)
+
+	var err error
+	if song.ReleaseDate, err = model.NormalizeDate(song.ReleaseDate); err != nil {
+		return song, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = s.checkAlbum(song.AlbumID); err != nil {
+		return song, err
+	}
+	if err = s.checkOriginal(song.CoverOf); err != nil {
+		return song, err
+	}
+	if s.exists(song.Group, song.Song, 0) {
+		s.logger.Info(zap.Error(ErrSongExists))
+		return song, ErrSongExists
+	}
+	song.ID = s.nextID
+	song.ArtistID = s.artistID(song.Group)
+	song.Version = 1
+	s.nextID++
+	s.verses[song.ID] = splitVerses(song.Text)
+	song.VerseCount = len(s.verses[song.ID])
+	s.songs[song.ID] = song
+	s.addRevision(ctx, model.RevisionCreate, song)
+	return song, nil
+}
+
+func (s *MemoryStorage) GetSongByID(ctx context.Context, id string) (model.Song, error) {
+	s.logger.Debug("Fetching song by ID:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	song, ok := s.lookup(id)
+	if !ok {
+		return model.Song{}, sql.ErrNoRows
+	}
+	return song, nil
+}
+
+func (s *MemoryStorage) DeleteSong(ctx context.Context, id string, version int) error {
+	s.logger.Debug("Deleting song by ID:", id, "version:", version)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.lookup(id)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if version > 0 && song.Version != version {
+		return ErrVersionMismatch
+	}
+	delete(s.songs, song.ID)
+	delete(s.verses, song.ID)
+	s.trash[song.ID] = model.TrashedSong{Song: song, DeletedAt: time.Now().UTC()}
+	s.addRevision(ctx, model.RevisionDelete, song)
+	return nil
+}
+
+func (s *MemoryStorage) UpdateSong(ctx context.Context, song model.Song, version int) (model.Song, error) {
+	s.logger.Debugw("Updating song", "song", song, "version", version)
+
+	var err error
+	if song.ReleaseDate, err = model.NormalizeDate(song.ReleaseDate); err != nil {
+		return song, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.songs[song.ID]
+	if !ok {
+		return song, sql.ErrNoRows
+	}
+	if version > 0 && current.Version != version {
+		return song, ErrVersionMismatch
+	}
+	if err = s.checkAlbum(song.AlbumID); err != nil {
+		return song, err
+	}
+	if err = s.checkOriginal(song.CoverOf); err != nil {
+		return song, err
+	}
+	if s.exists(song.Group, song.Song, song.ID) {
+		s.logger.Info(zap.Error(ErrSongExists))
+		return song, ErrSongExists
+	}
+	song.Enriched = current.Enriched
+	song.ArtistID = s.artistID(song.Group)
+	song.Version = current.Version + 1
+	s.verses[song.ID] = splitVerses(song.Text)
+	song.VerseCount = len(s.verses[song.ID])
+	s.songs[song.ID] = song
+	s.deleteOrphanArtists(current.ArtistID)
+	s.addRevision(ctx, model.RevisionUpdate, song)
+	return song, nil
+}
+
+func (s *MemoryStorage) PatchSong(ctx context.Context, id, version int, patch model.SongPatch) (model.Song, error) {
+	s.logger.Debugw("Patching song", "id", id, "version", version, "patch", patch)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.songs[id]
+	if !ok {
+		return model.Song{}, sql.ErrNoRows
+	}
+	if version > 0 && current.Version != version {
+		return model.Song{}, ErrVersionMismatch
+	}
+	if patch.Empty() {
+		return current, nil
+	}
+	song := patch.Apply(current)
+	song.Version++
+	if song.Track != 0 && song.AlbumID == 0 {
+		return model.Song{}, ErrTrackNoAlbum
+	}
+
+	var err error
+	if song.ReleaseDate, err = model.NormalizeDate(song.ReleaseDate); err != nil {
+		return model.Song{}, err
+	}
+	if err = s.checkAlbum(song.AlbumID); err != nil {
+		return model.Song{}, err
+	}
+	if err = s.checkOriginal(song.CoverOf); err != nil {
+		return model.Song{}, err
+	}
+	if s.exists(song.Group, song.Song, song.ID) {
+		s.logger.Info(zap.Error(ErrSongExists))
+		return model.Song{}, ErrSongExists
+	}
+	if patch.Text != nil {
+		s.verses[id] = splitVerses(song.Text)
+		song.VerseCount = len(s.verses[id])
+	}
+	song.ArtistID = s.artistID(song.Group)
+	s.songs[id] = song
+	s.deleteOrphanArtists(current.ArtistID)
+	s.addRevision(ctx, model.RevisionUpdate, song)
+	return song, nil
+}
+
+func (s *MemoryStorage) GetSongVerseByID(ctx context.Context, id, verse int) (string, error) {
+	s.logger.Debug("Fetching song verse by ID:", id, "verse:", verse)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	verses, ok := s.verses[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	if verse < 1 || len(verses) < verse {
+		s.logger.Info(zap.Error(ErrVerseNotFound))
+		return "", ErrVerseNotFound
+	}
+	return verses[verse-1].Text, nil
+}
+
+func (s *MemoryStorage) GetSongVerses(ctx context.Context, id int, sectionType string, collapse bool, limit, offset int) ([]model.Verse, int, error) {
+	s.logger.Debugw("Fetching song verses", "id", id, "type", sectionType, "collapse", collapse, "limit", limit, "offset", offset)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all, ok := s.verses[id]
+	if !ok {
+		return nil, 0, sql.ErrNoRows
+	}
+	if collapse {
+		all = collapseRepeats(all)
+	}
+	if sectionType != "" {
+		filtered := make([]model.Verse, 0, len(all))
+		for _, v := range all {
+			if v.Type == sectionType {
+				filtered = append(filtered, v)
+			}
+		}
+		all = filtered
+	}
+
+	verses := make([]model.Verse, 0, limit)
+	for i := offset; i < len(all) && len(verses) < limit; i++ {
+		verses = append(verses, all[i])
+	}
+	return verses, len(all), nil
+}
+
+func (s *MemoryStorage) GetInfo(ctx context.Context, group, song, match string) (model.SongDetail, error) {
+	s.logger.Debug("Fetching song info", "group", group, "song", song, "match", match)
+
+	if match != "" && match != model.MatchExact {
+		songs, _, err := s.GetSongs(ctx, model.SongFilter{Group: group, Song: song, Match: match}, nil, 1)
+		return songInfo(songs, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// the exact spelling wins over a transliteration, like in the SQL storages
+	found, ok := model.Song{}, false
+	for id := 1; id < s.nextID; id++ {
+		v, exists := s.songs[id]
+		if !exists || nameKey(v.Group) != nameKey(group) || nameKey(v.Song) != nameKey(song) {
+			continue
+		}
+		exact := v.Group == group && v.Song == song
+		if !ok || exact {
+			found, ok = v, true
+		}
+		if exact {
+			break
+		}
+	}
+	if !ok {
+		return model.SongDetail{}, sql.ErrNoRows
+	}
+	return model.SongDetail{ReleaseDate: found.ReleaseDate, Text: found.Text, Link: found.Link}, nil
+}
+
+func (s *MemoryStorage) GetSongRevisions(ctx context.Context, id int) ([]model.SongRevision, error) {
+	s.logger.Debug("Fetching song revisions by ID:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions, ok := s.revisions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return slices.Clone(revisions), nil
+}
+
+func (s *MemoryStorage) GetSongRevision(ctx context.Context, id, revision int) (model.SongRevision, error) {
+	s.logger.Debug("Fetching song revision by ID:", id, "revision:", revision)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions := s.revisions[id]
+	if revision < 1 || len(revisions) < revision {
+		return model.SongRevision{}, ErrRevisionNotFound
+	}
+	return revisions[revision-1], nil
+}
+
+func (s *MemoryStorage) RevertSong(ctx context.Context, id, revision, version int) (model.Song, error) {
+	s.logger.Debugw("Reverting song", "id", id, "revision", revision, "version", version)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions := s.revisions[id]
+	if revision < 1 || len(revisions) < revision {
+		return model.Song{}, ErrRevisionNotFound
+	}
+	song := revisions[revision-1].Song
+
+	current, ok := s.songs[id]
+	if version > 0 && (!ok || current.Version != version) {
+		return model.Song{}, ErrVersionMismatch
+	}
+	if ok {
+		song.Version = current.Version + 1
+	} else {
+		// in the trash or purged, the last revision has the last version the song had
+		song.Version = revisions[len(revisions)-1].Song.Version + 1
+	}
+	if s.exists(song.Group, song.Song, id) {
+		s.logger.Info(zap.Error(ErrSongExists))
+		return model.Song{}, ErrSongExists
+	}
+	// the artist of the revision may have been merged away since, the album or the original deleted
+	song.ArtistID = s.artistID(song.Group)
+	if s.checkAlbum(song.AlbumID) != nil {
+		song.AlbumID, song.Track = 0, 0
+	}
+	if s.checkOriginal(song.CoverOf) != nil {
+		song.CoverOf = 0
+	}
+	previous := current.ArtistID
+	if trashed, ok := s.trash[id]; ok {
+		previous = trashed.ArtistID
+	}
+	delete(s.trash, id)
+	s.verses[id] = splitVerses(song.Text)
+	song.VerseCount = len(s.verses[id])
+	s.songs[id] = song
+	s.deleteOrphanArtists(previous)
+	s.addRevision(ctx, model.RevisionRevert, song)
+	return song, nil
+}
+
+func (s *MemoryStorage) GetTrash(ctx context.Context, limit, offset int) ([]model.TrashedSong, error) {
+	s.logger.Debugw("Fetching trash", "limit", limit, "offset", offset)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]model.TrashedSong, 0, len(s.trash))
+	for _, v := range s.trash {
+		all = append(all, v)
+	}
+	slices.SortFunc(all, func(a, b model.TrashedSong) int {
+		if c := b.DeletedAt.Compare(a.DeletedAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+
+	songs := make([]model.TrashedSong, 0, limit)
+	for i := offset; i < len(all) && len(songs) < limit; i++ {
+		songs = append(songs, all[i])
+	}
+	return songs, nil
+}
+
+func (s *MemoryStorage) RestoreSong(ctx context.Context, id int) (model.Song, error) {
+	s.logger.Debug("Restoring song by ID:", id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trashed, ok := s.trash[id]
+	if !ok {
+		return model.Song{}, sql.ErrNoRows
+	}
+	song := trashed.Song
+	if s.exists(song.Group, song.Song, id) {
+		s.logger.Info(zap.Error(ErrSongExists))
+		return model.Song{}, ErrSongExists
+	}
+	delete(s.trash, id)
+	song.Version++
+	s.verses[id] = splitVerses(song.Text)
+	s.songs[id] = song
+	s.addRevision(ctx, model.RevisionRestore, song)
+	return song, nil
+}
+
+func (s *MemoryStorage) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	s.logger.Debug("Purging songs deleted before:", before)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purge := func(id int) bool {
+		v, ok := s.trash[id]
+		return ok && v.DeletedAt.Before(before)
+	}
+	s.rewriteSongs(ctx, func(song *model.Song) bool {
+		if song.CoverOf == 0 || !purge(song.CoverOf) {
+			return false
+		}
+		song.CoverOf = 0
+		return true
+	})
+	for playlistID, entries := range s.entries {
+		s.entries[playlistID] = slices.DeleteFunc(entries, func(e model.PlaylistEntry) bool {
+			return purge(e.Song.ID)
+		})
+	}
+	purged := 0
+	var artists []int
+	for id, v := range s.trash {
+		if purge(id) {
+			artists = append(artists, v.ArtistID)
+			for _, c := range s.credits[id] {
+				artists = append(artists, c.ArtistID)
+			}
+			delete(s.trash, id)
+			delete(s.credits, id)
+			delete(s.tags, id)
+			purged++
+		}
+	}
+	s.deleteOrphanArtists(artists...)
+	return purged, nil
+}
+
+func (s *MemoryStorage) GetArtists(ctx context.Context, limit, offset int) ([]model.Artist, error) {
+	s.logger.Debugw("Fetching artists", "limit", limit, "offset", offset)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]model.Artist, 0, len(s.artists))
+	for id := range s.artists {
+		all = append(all, s.artist(id))
+	}
+	collator := collate.New(language.Russian)
+	slices.SortFunc(all, func(a, b model.Artist) int {
+		if c := collator.CompareString(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+
+	artists := make([]model.Artist, 0, limit)
+	for i := offset; i < len(all) && len(artists) < limit; i++ {
+		artists = append(artists, all[i])
+	}
+	return artists, nil
+}
+
+func (s *MemoryStorage) GetArtist(ctx context.Context, id int) (model.Artist, error) {
+	s.logger.Debug("Fetching artist by ID:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.artists[id]; !ok {
+		return model.Artist{}, sql.ErrNoRows
+	}
+	return s.artist(id), nil
+}
+
+func (s *MemoryStorage) RenameArtist(ctx context.Context, id int, name string) (model.Artist, error) {
+	s.logger.Debugw("Renaming artist", "id", id, "name", name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.artists[id]
+	if !ok {
+		return model.Artist{}, sql.ErrNoRows
+	}
+	if current == name {
+		return s.artist(id), nil
+	}
+	for _, v := range s.artists {
+		if v == name {
+			s.logger.Info(zap.Error(ErrArtistExists))
+			return model.Artist{}, ErrArtistExists
+		}
+	}
+	s.artists[id] = name
+	s.moveSongs(ctx, id, id, name)
+	return s.artist(id), nil
+}
+
+func (s *MemoryStorage) MergeArtists(ctx context.Context, id, from int) (model.Artist, error) {
+	s.logger.Debugw("Merging artists", "id", id, "from", from)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, ok := s.artists[id]
+	if _, found := s.artists[from]; !ok || !found {
+		return model.Artist{}, sql.ErrNoRows
+	}
+	for _, v := range s.songs {
+		if v.ArtistID == from && s.exists(name, v.Song, v.ID) {
+			s.logger.Info(zap.Error(ErrSongExists))
+			return model.Artist{}, ErrSongExists
+		}
+	}
+	for _, v := range s.albums {
+		if v.ArtistID == from && s.albumExists(id, v.Title, v.ID) {
+			s.logger.Info(zap.Error(ErrAlbumExists))
+			return model.Artist{}, ErrAlbumExists
+		}
+	}
+	s.moveSongs(ctx, from, id, name)
+	for songID, credits := range s.credits {
+		moved := make([]model.Credit, 0, len(credits))
+		for _, c := range credits {
+			if c.ArtistID == from {
+				c.ArtistID = id
+			}
+			if !slices.Contains(moved, c) {
+				moved = append(moved, c)
+			}
+		}
+		s.credits[songID] = moved
+	}
+	for albumID, v := range s.albums {
+		if v.ArtistID == from {
+			v.ArtistID = id
+			s.albums[albumID] = v
+		}
+	}
+	delete(s.artists, from)
+	return s.artist(id), nil
+}
+
+func (s *MemoryStorage) GetAlbums(ctx context.Context, artistID, limit, offset int) ([]model.Album, error) {
+	s.logger.Debugw("Fetching albums", "artistID", artistID, "limit", limit, "offset", offset)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]model.Album, 0, len(s.albums))
+	for id, v := range s.albums {
+		if artistID == 0 || v.ArtistID == artistID {
+			all = append(all, s.album(id))
+		}
+	}
+	collator := collate.New(language.Russian)
+	slices.SortFunc(all, func(a, b model.Album) int {
+		if c := strings.Compare(a.ReleaseDate, b.ReleaseDate); c != 0 {
+			return c
+		}
+		if c := collator.CompareString(a.Title, b.Title); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+
+	albums := make([]model.Album, 0, limit)
+	for i := offset; i < len(all) && len(albums) < limit; i++ {
+		albums = append(albums, all[i])
+	}
+	return albums, nil
+}
+
+func (s *MemoryStorage) GetAlbum(ctx context.Context, id int) (model.Album, error) {
+	s.logger.Debug("Fetching album by ID:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.albums[id]; !ok {
+		return model.Album{}, sql.ErrNoRows
+	}
+	return s.album(id), nil
+}
+
+func (s *MemoryStorage) AddAlbum(ctx context.Context, album model.Album) (model.Album, error) {
+	s.logger.Debugw("Adding new album", "album", album)
+
+	var err error
+	if album.ReleaseDate, err = model.NormalizeDate(album.ReleaseDate); err != nil {
+		return album, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	album.ArtistID = s.artistID(album.Group)
+	if s.albumExists(album.ArtistID, album.Title, 0) {
+		s.logger.Info(zap.Error(ErrAlbumExists))
+		return album, ErrAlbumExists
+	}
+	album.ID = s.nextAlbumID
+	s.nextAlbumID++
+	s.albums[album.ID] = album
+	return s.album(album.ID), nil
+}
+
+func (s *MemoryStorage) UpdateAlbum(ctx context.Context, album model.Album) (model.Album, error) {
+	s.logger.Debugw("Updating album", "album", album)
+
+	var err error
+	if album.ReleaseDate, err = model.NormalizeDate(album.ReleaseDate); err != nil {
+		return album, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.albums[album.ID]
+	if !ok {
+		return album, sql.ErrNoRows
+	}
+	album.ArtistID = s.artistID(album.Group)
+	if s.albumExists(album.ArtistID, album.Title, album.ID) {
+		s.logger.Info(zap.Error(ErrAlbumExists))
+		return album, ErrAlbumExists
+	}
+	s.albums[album.ID] = album
+	s.deleteOrphanArtists(current.ArtistID)
+	return s.album(album.ID), nil
+}
+
+func (s *MemoryStorage) DeleteAlbum(ctx context.Context, id int) error {
+	s.logger.Debug("Deleting album by ID:", id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.albums[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	s.rewriteSongs(ctx, func(song *model.Song) bool {
+		if song.AlbumID != id {
+			return false
+		}
+		song.AlbumID, song.Track = 0, 0
+		return true
+	})
+	delete(s.albums, id)
+	s.deleteOrphanArtists(current.ArtistID)
+	return nil
+}
+
+func (s *MemoryStorage) GetAlbumSongs(ctx context.Context, id int) ([]model.Song, error) {
+	s.logger.Debug("Fetching songs of album:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.albums[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	songs := make([]model.Song, 0)
+	for _, v := range s.songs {
+		if v.AlbumID == id {
+			songs = append(songs, v)
+		}
+	}
+	// songs without a track number come last
+	track := func(song model.Song) int {
+		if song.Track == 0 {
+			return math.MaxInt
+		}
+		return song.Track
+	}
+	slices.SortFunc(songs, func(a, b model.Song) int {
+		if c := cmp.Compare(track(a), track(b)); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	return songs, nil
+}
+
+func (s *MemoryStorage) GetSongArtists(ctx context.Context, id int) ([]model.Credit, error) {
+	s.logger.Debug("Fetching artists of song:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.songs[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	return s.songArtists(id), nil
+}
+
+func (s *MemoryStorage) SetSongArtists(ctx context.Context, id int, credits []model.Credit) ([]model.Credit, error) {
+	s.logger.Debugw("Crediting artists on song", "id", id, "credits", credits)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.songs[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	kept := make([]model.Credit, 0, len(credits))
+	for _, c := range credits {
+		c = model.Credit{ArtistID: s.artistID(c.Name), Role: c.Role}
+		if !slices.Contains(kept, c) {
+			kept = append(kept, c)
+		}
+	}
+	previous := s.credits[id]
+	s.credits[id] = kept
+	for _, c := range previous {
+		s.deleteOrphanArtists(c.ArtistID)
+	}
+	return s.songArtists(id), nil
+}
+
+func (s *MemoryStorage) GetSongCovers(ctx context.Context, id int) ([]model.Song, error) {
+	s.logger.Debug("Fetching covers of song:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.songs[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	songs := make([]model.Song, 0)
+	for _, v := range s.songs {
+		if v.CoverOf == id {
+			songs = append(songs, v)
+		}
+	}
+	slices.SortFunc(songs, func(a, b model.Song) int {
+		return a.ID - b.ID
+	})
+	return songs, nil
+}
+
+func (s *MemoryStorage) GetTags(ctx context.Context, limit, offset int) ([]model.Tag, error) {
+	s.logger.Debugw("Fetching tags", "limit", limit, "offset", offset)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for id := range s.songs {
+		for _, tag := range s.tags[id] {
+			counts[tag]++
+		}
+	}
+	all := make([]model.Tag, 0, len(counts))
+	for name, count := range counts {
+		all = append(all, model.Tag{Name: name, SongCount: count})
+	}
+	collator := collate.New(language.Russian)
+	slices.SortFunc(all, func(a, b model.Tag) int {
+		if c := cmp.Compare(b.SongCount, a.SongCount); c != 0 {
+			return c
+		}
+		return collator.CompareString(a.Name, b.Name)
+	})
+
+	tags := make([]model.Tag, 0, limit)
+	for i := offset; i < len(all) && len(tags) < limit; i++ {
+		tags = append(tags, all[i])
+	}
+	return tags, nil
+}
+
+func (s *MemoryStorage) GetSongTags(ctx context.Context, id int) ([]string, error) {
+	s.logger.Debug("Fetching tags of song:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.songs[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	return append(make([]string, 0), s.tags[id]...), nil
+}
+
+func (s *MemoryStorage) AddSongTags(ctx context.Context, id int, tags []string) ([]string, error) {
+	s.logger.Debugw("Tagging song", "id", id, "tags", tags)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.songs[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	all := append(slices.Clone(s.tags[id]), tags...)
+	collator := collate.New(language.Russian)
+	slices.SortFunc(all, collator.CompareString)
+	s.tags[id] = slices.Compact(all)
+	return append(make([]string, 0), s.tags[id]...), nil
+}
+
+func (s *MemoryStorage) RemoveSongTag(ctx context.Context, id int, tag string) error {
+	s.logger.Debugw("Untagging song", "id", id, "tag", tag)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.songs[id]; !ok {
+		return sql.ErrNoRows
+	}
+	i := slices.Index(s.tags[id], tag)
+	if i < 0 {
+		return ErrTagNotFound
+	}
+	s.tags[id] = slices.Delete(slices.Clone(s.tags[id]), i, i+1)
+	return nil
+}
+
+func (s *MemoryStorage) GetPlaylists(ctx context.Context, limit, offset int) ([]model.Playlist, error) {
+	s.logger.Debugw("Fetching playlists", "limit", limit, "offset", offset)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	playlists := make([]model.Playlist, 0, limit)
+	skipped := 0
+	for id := 1; id < s.nextPlaylistID && len(playlists) < limit; id++ {
+		if _, ok := s.playlists[id]; !ok {
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		playlists = append(playlists, s.playlist(id))
+	}
+	return playlists, nil
+}
+
+func (s *MemoryStorage) GetPlaylist(ctx context.Context, id int) (model.Playlist, error) {
+	s.logger.Debug("Fetching playlist by ID:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.playlists[id]; !ok {
+		return model.Playlist{}, sql.ErrNoRows
+	}
+	return s.playlist(id), nil
+}
+
+func (s *MemoryStorage) AddPlaylist(ctx context.Context, name string) (model.Playlist, error) {
+	s.logger.Debug("Adding new playlist:", name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	id := s.nextPlaylistID
+	s.nextPlaylistID++
+	s.playlists[id] = model.Playlist{ID: id, Name: name, CreatedAt: now, UpdatedAt: now}
+	return s.playlist(id), nil
+}
+
+func (s *MemoryStorage) RenamePlaylist(ctx context.Context, id int, name string) (model.Playlist, error) {
+	s.logger.Debugw("Renaming playlist", "id", id, "name", name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.playlists[id]
+	if !ok {
+		return model.Playlist{}, sql.ErrNoRows
+	}
+	p.Name = name
+	p.UpdatedAt = time.Now().UTC()
+	s.playlists[id] = p
+	return s.playlist(id), nil
+}
+
+func (s *MemoryStorage) DeletePlaylist(ctx context.Context, id int) error {
+	s.logger.Debug("Deleting playlist by ID:", id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.playlists[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(s.playlists, id)
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *MemoryStorage) GetPlaylistEntries(ctx context.Context, id int) ([]model.PlaylistEntry, error) {
+	s.logger.Debug("Fetching entries of playlist:", id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.playlists[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	return s.playlistEntries(id), nil
+}
+
+func (s *MemoryStorage) AddPlaylistEntry(ctx context.Context, id, songID, position int) (model.PlaylistEntry, error) {
+	s.logger.Debugw("Adding playlist entry", "id", id, "songID", songID, "position", position)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.playlists[id]; !ok {
+		return model.PlaylistEntry{}, sql.ErrNoRows
+	}
+	if _, ok := s.songs[songID]; !ok {
+		return model.PlaylistEntry{}, ErrSongNotFound
+	}
+	entry := model.PlaylistEntry{ID: s.nextEntryID, AddedAt: time.Now().UTC(), Song: model.Song{ID: songID}}
+	s.nextEntryID++
+	s.insertEntry(id, entry, position)
+	return s.playlistEntry(id, entry.ID)
+}
+
+func (s *MemoryStorage) MovePlaylistEntry(ctx context.Context, id, entryID, position int) (model.PlaylistEntry, error) {
+	s.logger.Debugw("Moving playlist entry", "id", id, "entryID", entryID, "position", position)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.playlists[id]; !ok {
+		return model.PlaylistEntry{}, sql.ErrNoRows
+	}
+	if _, err := s.playlistEntry(id, entryID); err != nil {
+		return model.PlaylistEntry{}, err
+	}
+	i := slices.IndexFunc(s.entries[id], func(e model.PlaylistEntry) bool { return e.ID == entryID })
+	entry := s.entries[id][i]
+	s.entries[id] = slices.Delete(s.entries[id], i, i+1)
+	s.insertEntry(id, entry, position)
+	return s.playlistEntry(id, entryID)
+}
+
+func (s *MemoryStorage) RemovePlaylistEntry(ctx context.Context, id, entryID int) error {
+	s.logger.Debugw("Removing playlist entry", "id", id, "entryID", entryID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.playlists[id]; !ok {
+		return sql.ErrNoRows
+	}
+	if _, err := s.playlistEntry(id, entryID); err != nil {
+		return err
+	}
+	s.entries[id] = slices.DeleteFunc(s.entries[id], func(e model.PlaylistEntry) bool { return e.ID == entryID })
+	s.touchPlaylist(id)
+	return nil
+}
+
+func (s *MemoryStorage) Close() error {
+	s.logger.Debug("Closing in-memory storage")
+	return nil
+}
+
+// lookup must be called with s.mu held.
+func (s *MemoryStorage) lookup(id string) (model.Song, bool) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return model.Song{}, false
+	}
+	song, ok := s.songs[n]
+	return song, ok
+}
+
+// addRevision must be called with s.mu held.
+func (s *MemoryStorage) addRevision(ctx context.Context, operation string, song model.Song) {
+	s.revisions[song.ID] = append(s.revisions[song.ID], model.SongRevision{
+		Revision:  len(s.revisions[song.ID]) + 1,
+		Operation: operation,
+		Actor:     actorFrom(ctx),
+		CreatedAt: time.Now().UTC(),
+		Song:      song,
+	})
+}
+
+// artistID returns the ID of the artist named name, adding the artist if there is none.
+// It must be called with s.mu held.
+func (s *MemoryStorage) artistID(name string) int {
+	for id, v := range s.artists {
+		if v == name {
+			return id
+		}
+	}
+	id := s.nextArtistID
+	s.nextArtistID++
+	s.artists[id] = name
+	return id
+}
+
+// deleteOrphanArtists is the in-process counterpart of Storage.deleteOrphanArtists, it must be called with s.mu held.
+func (s *MemoryStorage) deleteOrphanArtists(ids ...int) {
+	for _, id := range ids {
+		if !s.artistReferenced(id) {
+			delete(s.artists, id)
+		}
+	}
+}
+
+// artistReferenced reports whether a song, including one in the trash, an album or a credit refers to an artist.
+// It must be called with s.mu held.
+func (s *MemoryStorage) artistReferenced(id int) bool {
+	for _, v := range s.songs {
+		if v.ArtistID == id {
+			return true
+		}
+	}
+	for _, v := range s.trash {
+		if v.ArtistID == id {
+			return true
+		}
+	}
+	for _, v := range s.albums {
+		if v.ArtistID == id {
+			return true
+		}
+	}
+	for _, credits := range s.credits {
+		for _, c := range credits {
+			if c.ArtistID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// artist counts the live songs of an artist, it must be called with s.mu held.
+func (s *MemoryStorage) artist(id int) model.Artist {
+	a := model.Artist{ID: id, Name: s.artists[id]}
+	for _, v := range s.songs {
+		if v.ArtistID == id {
+			a.SongCount++
+		}
+	}
+	return a
+}
+
+// moveSongs is the in-process counterpart of Storage.moveSongs, it must be called with s.mu held.
+func (s *MemoryStorage) moveSongs(ctx context.Context, from, to int, name string) {
+	s.rewriteSongs(ctx, func(song *model.Song) bool {
+		if song.ArtistID != from {
+			return false
+		}
+		song.ArtistID, song.Group = to, name
+		return true
+	})
+}
+
+// rewriteSongs is the in-process counterpart of Storage.rewriteSongs: edit changes the songs, including the ones
+// in the trash, it reports whether it changed one. It must be called with s.mu held.
+func (s *MemoryStorage) rewriteSongs(ctx context.Context, edit func(song *model.Song) bool) {
+	for id, v := range s.songs {
+		if edit(&v) {
+			v.Version++
+			s.songs[id] = v
+			s.addRevision(ctx, model.RevisionUpdate, v)
+		}
+	}
+	for id, v := range s.trash {
+		if edit(&v.Song) {
+			v.Version++
+			s.trash[id] = v
+			s.addRevision(ctx, model.RevisionUpdate, v.Song)
+		}
+	}
+}
+
+// album fills in the artist name and song count of an album, it must be called with s.mu held.
+func (s *MemoryStorage) album(id int) model.Album {
+	a := s.albums[id]
+	a.Group = s.artists[a.ArtistID]
+	a.SongCount = 0
+	for _, v := range s.songs {
+		if v.AlbumID == id {
+			a.SongCount++
+		}
+	}
+	return a
+}
+
+// albumExists reports whether an artist has an album of the title other than exceptID.
+// It must be called with s.mu held.
+func (s *MemoryStorage) albumExists(artistID int, title string, exceptID int) bool {
+	for id, v := range s.albums {
+		if id != exceptID && v.ArtistID == artistID && v.Title == title {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAlbum must be called with s.mu held.
+func (s *MemoryStorage) checkAlbum(id int) error {
+	if _, ok := s.albums[id]; id != 0 && !ok {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
+
+// songArtists is the in-process counterpart of Storage.songArtists, it must be called with s.mu held.
+func (s *MemoryStorage) songArtists(id int) []model.Credit {
+	song := s.songs[id]
+	credits := make([]model.Credit, 0, len(s.credits[id]))
+	for _, c := range s.credits[id] {
+		c.Name = s.artists[c.ArtistID]
+		credits = append(credits, c)
+	}
+	collator := collate.New(language.Russian)
+	slices.SortFunc(credits, func(a, b model.Credit) int {
+		if c := strings.Compare(a.Role, b.Role); c != 0 {
+			return c
+		}
+		if c := collator.CompareString(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return a.ArtistID - b.ArtistID
+	})
+	main := model.Credit{ArtistID: song.ArtistID, Name: song.Group, Role: model.RoleMain}
+	return append([]model.Credit{main}, credits...)
+}
+
+// credited reports whether an artist is the group of a song or credited on it in another role.
+// It must be called with s.mu held.
+func (s *MemoryStorage) credited(song model.Song, artistID int) bool {
+	if song.ArtistID == artistID {
+		return true
+	}
+	return slices.ContainsFunc(s.credits[song.ID], func(c model.Credit) bool {
+		return c.ArtistID == artistID
+	})
+}
+
+// playlist counts the entries a playlist shows, it must be called with s.mu held.
+func (s *MemoryStorage) playlist(id int) model.Playlist {
+	p := s.playlists[id]
+	p.EntryCount = len(s.playlistEntries(id))
+	return p
+}
+
+// playlistEntries returns the entries of a playlist that are not of songs in the trash, with their songs and
+// positions filled in. It must be called with s.mu held.
+func (s *MemoryStorage) playlistEntries(id int) []model.PlaylistEntry {
+	entries := make([]model.PlaylistEntry, 0, len(s.entries[id]))
+	for _, e := range s.entries[id] {
+		song, ok := s.songs[e.Song.ID]
+		if !ok {
+			continue
+		}
+		e.Song = song
+		e.Position = len(entries) + 1
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// playlistEntry must be called with s.mu held.
+func (s *MemoryStorage) playlistEntry(id, entryID int) (model.PlaylistEntry, error) {
+	for _, e := range s.playlistEntries(id) {
+		if e.ID == entryID {
+			return e, nil
+		}
+	}
+	return model.PlaylistEntry{}, ErrEntryNotFound
+}
+
+// insertEntry is the in-process counterpart of Storage.entryKey: the entry goes before the one shown at position,
+// or last. It must be called with s.mu held.
+func (s *MemoryStorage) insertEntry(id int, entry model.PlaylistEntry, position int) {
+	entries := s.entries[id]
+	at := len(entries)
+	shown := 0
+	for i, e := range entries {
+		if _, ok := s.songs[e.Song.ID]; !ok {
+			continue
+		}
+		if shown++; shown == position {
+			at = i
+			break
+		}
+	}
+	s.entries[id] = slices.Insert(entries, at, entry)
+	s.touchPlaylist(id)
+}
+
+// touchPlaylist must be called with s.mu held.
+func (s *MemoryStorage) touchPlaylist(id int) {
+	p := s.playlists[id]
+	p.UpdatedAt = time.Now().UTC()
+	s.playlists[id] = p
+}
+
+// tagged is the in-process counterpart of taggedExpr, it must be called with s.mu held.
+func (s *MemoryStorage) tagged(id int, filter model.SongFilter) bool {
+	if len(filter.Tags) == 0 {
+		return true
+	}
+	has := func(tag string) bool {
+		return slices.Contains(s.tags[id], tag)
+	}
+	if filter.TagMatch == model.TagMatchAny {
+		return slices.ContainsFunc(filter.Tags, has)
+	}
+	for _, tag := range filter.Tags {
+		if !has(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkOriginal must be called with s.mu held.
+func (s *MemoryStorage) checkOriginal(id int) error {
+	if _, ok := s.songs[id]; id != 0 && !ok {
+		return ErrOriginalNotFound
+	}
+	return nil
+}
+
+// exists must be called with s.mu held.
+func (s *MemoryStorage) exists(group, song string, exceptID int) bool {
+	for id, v := range s.songs {
+		if id != exceptID && v.Group == group && v.Song == song {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesFilter checks the release dates of the filter, the names are left to filterNames.
+func matchesFilter(song model.Song, filter model.SongFilter) bool {
+	switch {
+	case filter.ReleaseDate != "" && song.ReleaseDate != filter.ReleaseDate:
+		return false
+	case filter.ReleaseFrom != "" && (song.ReleaseDate == "" || song.ReleaseDate < filter.ReleaseFrom):
+		return false
+	case filter.ReleaseTo != "" && (song.ReleaseDate == "" || song.ReleaseDate > filter.ReleaseTo):
+		return false
+	case filter.Query != nil && !matchQuery(song, filter.Query):
+		return false
+	case filter.Album != 0 && song.AlbumID != filter.Album:
+		return false
+	}
+	return true
+}
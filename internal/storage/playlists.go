@@ -0,0 +1,411 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// liveEntries is the condition of the entries of songs not in the trash, the ones playlists show.
+const liveEntries = "song_id IN (SELECT id FROM songs WHERE deleted_at IS NULL)"
+
+// playlistsQuery selects playlists with the number of their entries.
+func playlistsQuery() squirrel.SelectBuilder {
+	return squirrel.Select("p.id", "p.name",
+		"(SELECT COUNT(*) FROM playlist_entries e WHERE e.playlist_id = p.id AND "+liveEntries+")",
+		"p.created_at", "p.updated_at").
+		From("playlists p")
+}
+
+func scanPlaylist(row rowScanner) (model.Playlist, error) {
+	var p model.Playlist
+	err := row.Scan(&p.ID, &p.Name, &p.EntryCount, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// GetPlaylists lists playlists in the order they were created.
+func (s *Storage) GetPlaylists(ctx context.Context, limit, offset int) ([]model.Playlist, error) {
+	s.logger.Debugw("Fetching playlists", "limit", limit, "offset", offset)
+
+	sqlString, args, err := playlistsQuery().OrderBy("p.id").Limit(uint64(limit)).Offset(uint64(offset)).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	playlists := make([]model.Playlist, 0)
+	for rows.Next() {
+		p, err := scanPlaylist(rows)
+		if err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		playlists = append(playlists, p)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	return playlists, nil
+}
+
+func (s *Storage) GetPlaylist(ctx context.Context, id int) (model.Playlist, error) {
+	s.logger.Debug("Fetching playlist by ID:", id)
+
+	p, err := s.playlist(ctx, s.db, id)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return p, err
+}
+
+// playlist reads a playlist with q, a database or a transaction.
+func (s *Storage) playlist(ctx context.Context, q queryRower, id int) (model.Playlist, error) {
+	sqlString, args, err := playlistsQuery().Where(squirrel.Eq{"p.id": id}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return model.Playlist{}, err
+	}
+	return scanPlaylist(q.QueryRowContext(ctx, sqlString, args...))
+}
+
+func (s *Storage) AddPlaylist(ctx context.Context, name string) (model.Playlist, error) {
+	s.logger.Debug("Adding new playlist:", name)
+
+	now := time.Now().UTC()
+	sqlString, args, err := squirrel.Insert("playlists").Columns("name", "created_at", "updated_at").
+		Values(name, now, now).
+		Suffix("RETURNING id").
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Playlist{}, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	var added model.Playlist
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		var id int
+		if err := tx.QueryRowContext(ctx, sqlString, args...).Scan(&id); err != nil {
+			return err
+		}
+		var err error
+		added, err = s.playlist(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Playlist{}, err
+	}
+	return added, nil
+}
+
+func (s *Storage) RenamePlaylist(ctx context.Context, id int, name string) (model.Playlist, error) {
+	s.logger.Debugw("Renaming playlist", "id", id, "name", name)
+
+	var renamed model.Playlist
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.touchPlaylist(ctx, tx, id, map[string]any{"name": name}); err != nil {
+			return err
+		}
+		var err error
+		renamed, err = s.playlist(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Playlist{}, err
+	}
+	return renamed, nil
+}
+
+// DeletePlaylist removes a playlist with its entries, the songs stay.
+func (s *Storage) DeletePlaylist(ctx context.Context, id int) error {
+	s.logger.Debug("Deleting playlist by ID:", id)
+
+	sqlString, args, err := squirrel.Delete("playlists").Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	res, err := s.db.ExecContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPlaylistEntries returns the entries of a playlist in order, leaving out the songs in the trash.
+func (s *Storage) GetPlaylistEntries(ctx context.Context, id int) ([]model.PlaylistEntry, error) {
+	s.logger.Debug("Fetching entries of playlist:", id)
+
+	if _, err := s.playlist(ctx, s.db, id); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	entries, err := s.playlistEntries(ctx, s.db, id)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return entries, err
+}
+
+// AddPlaylistEntry puts a live song in a playlist at a position, see model.NewPlaylistEntry.
+// ErrSongNotFound is returned when there is no such song.
+func (s *Storage) AddPlaylistEntry(ctx context.Context, id, songID, position int) (model.PlaylistEntry, error) {
+	s.logger.Debugw("Adding playlist entry", "id", id, "songID", songID, "position", position)
+
+	var added model.PlaylistEntry
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.touchPlaylist(ctx, tx, id, nil); err != nil {
+			return err
+		}
+		live, err := s.songExists(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+		if !live {
+			return ErrSongNotFound
+		}
+		key, err := s.entryKey(ctx, tx, id, position, 0)
+		if err != nil {
+			return err
+		}
+		sqlString, args, err := squirrel.Insert("playlist_entries").
+			Columns("playlist_id", "song_id", "position", "added_at").
+			Values(id, songID, key, time.Now().UTC()).
+			Suffix("RETURNING id").
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+		var entryID int
+		if err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&entryID); err != nil {
+			return err
+		}
+		added, err = s.playlistEntry(ctx, tx, id, entryID)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.PlaylistEntry{}, err
+	}
+	return added, nil
+}
+
+// MovePlaylistEntry moves an entry to a position, see model.PlaylistEntryMove.
+// ErrEntryNotFound is returned when the playlist shows no such entry.
+func (s *Storage) MovePlaylistEntry(ctx context.Context, id, entryID, position int) (model.PlaylistEntry, error) {
+	s.logger.Debugw("Moving playlist entry", "id", id, "entryID", entryID, "position", position)
+
+	var moved model.PlaylistEntry
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.touchPlaylist(ctx, tx, id, nil); err != nil {
+			return err
+		}
+		if _, err := s.playlistEntry(ctx, tx, id, entryID); err != nil {
+			return err
+		}
+		key, err := s.entryKey(ctx, tx, id, position, entryID)
+		if err != nil {
+			return err
+		}
+		sqlString, args, err := squirrel.Update("playlist_entries").Set("position", key).
+			Where(squirrel.Eq{"id": entryID}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+		if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+			return err
+		}
+		moved, err = s.playlistEntry(ctx, tx, id, entryID)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.PlaylistEntry{}, err
+	}
+	return moved, nil
+}
+
+// RemovePlaylistEntry takes an entry out of a playlist, the entries after it move up.
+// ErrEntryNotFound is returned when the playlist shows no such entry.
+func (s *Storage) RemovePlaylistEntry(ctx context.Context, id, entryID int) error {
+	s.logger.Debugw("Removing playlist entry", "id", id, "entryID", entryID)
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.touchPlaylist(ctx, tx, id, nil); err != nil {
+			return err
+		}
+		sqlString, args, err := squirrel.Delete("playlist_entries").
+			Where(squirrel.Eq{"id": entryID, "playlist_id": id}).
+			Where(liveEntries).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+		res, err := tx.ExecContext(ctx, sqlString, args...)
+		if err != nil {
+			return err
+		}
+		removed, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return ErrEntryNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return err
+}
+
+// touchPlaylist sets the update time of a playlist along with the columns in set, sql.ErrNoRows is returned when
+// there is no such playlist. Edits of a playlist touch it first, the row lock keeps them from interleaving.
+func (s *Storage) touchPlaylist(ctx context.Context, tx *sql.Tx, id int, set map[string]any) error {
+	query := squirrel.Update("playlists").Set("updated_at", time.Now().UTC()).Where(squirrel.Eq{"id": id})
+	if set != nil {
+		query = query.SetMap(set)
+	}
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	res, err := tx.ExecContext(ctx, sqlString, args...)
+	if err != nil {
+		return err
+	}
+	touched, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if touched == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// entryKey returns the stored position an entry put at position takes, making room for it: the stored position
+// of the entry shown there now, which moves down with the ones after it, or one past the last entry.
+// exceptID is the entry being moved, zero for a new one.
+func (s *Storage) entryKey(ctx context.Context, tx *sql.Tx, id, position, exceptID int) (int, error) {
+	if position > 0 {
+		sqlString, args, err := squirrel.Select("e.position").From("playlist_entries e").
+			Where(squirrel.Eq{"e.playlist_id": id}).
+			Where(squirrel.NotEq{"e.id": exceptID}).
+			Where(liveEntries).
+			OrderBy("e.position", "e.id").
+			Limit(1).Offset(uint64(position - 1)).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return 0, err
+		}
+		var key int
+		err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&key)
+		if err == nil {
+			sqlString, args, err = squirrel.Update("playlist_entries").
+				Set("position", squirrel.Expr("position + 1")).
+				Where(squirrel.Eq{"playlist_id": id}).
+				Where(squirrel.GtOrEq{"position": key}).
+				PlaceholderFormat(s.placeholder).ToSql()
+			if err != nil {
+				return 0, err
+			}
+			_, err = tx.ExecContext(ctx, sqlString, args...)
+			return key, err
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+	}
+
+	sqlString, args, err := squirrel.Select("COALESCE(MAX(position), 0) + 1").From("playlist_entries").
+		Where(squirrel.Eq{"playlist_id": id}).
+		Where(squirrel.NotEq{"id": exceptID}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	var key int
+	err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&key)
+	return key, err
+}
+
+// playlistEntries reads the entries a playlist shows with q, a database or a transaction, numbering their positions.
+func (s *Storage) playlistEntries(ctx context.Context, q querier, id int) ([]model.PlaylistEntry, error) {
+	sqlString, args, err := squirrel.Select("e.id", "e.added_at").Columns(qualified("s", songColumnList)...).
+		From("playlist_entries e").
+		Join("songs s ON s.id = e.song_id").
+		Where(squirrel.Eq{"e.playlist_id": id, "s.deleted_at": nil}).
+		OrderBy("e.position", "e.id").
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := q.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]model.PlaylistEntry, 0)
+	for rows.Next() {
+		entry := model.PlaylistEntry{Position: len(entries) + 1}
+		if err = rows.Scan(append([]any{&entry.ID, &entry.AddedAt}, songDests(&entry.Song, songColumnList)...)...); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// playlistEntry finds an entry among the ones a playlist shows, ErrEntryNotFound is returned when it is not there.
+func (s *Storage) playlistEntry(ctx context.Context, q querier, id, entryID int) (model.PlaylistEntry, error) {
+	entries, err := s.playlistEntries(ctx, q, id)
+	if err != nil {
+		return model.PlaylistEntry{}, err
+	}
+	for _, entry := range entries {
+		if entry.ID == entryID {
+			return entry, nil
+		}
+	}
+	return model.PlaylistEntry{}, ErrEntryNotFound
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"cmp"
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// queryColumns are the columns behind the query fields. Names are compared by their keys, see nameKey,
+// year is compiled to ranges of release_date.
+var queryColumns = map[string]string{
+	model.FieldID:          "id",
+	model.FieldGroup:       "group_key",
+	model.FieldSong:        "song_key",
+	model.FieldText:        "COALESCE(text, '')",
+	model.FieldLink:        "link",
+	model.FieldReleaseDate: "release_date",
+	model.FieldVersion:     "version",
+	model.FieldEnriched:    "enriched",
+}
+
+var queryOperators = map[string]string{
+	model.OpEq: "=",
+	model.OpNe: "<>",
+	model.OpLt: "<",
+	model.OpLe: "<=",
+	model.OpGt: ">",
+	model.OpGe: ">=",
+}
+
+// compileQuery turns a parsed query into the condition of songsQuery, lower is the SQL function folding
+// the case of text. No condition evaluates to NULL, a missing release date fails the comparisons instead,
+// so NOT matches the same songs as matchQuery does.
+func compileQuery(expr model.Expr, lower string) squirrel.Sqlizer {
+	switch e := withoutYears(expr).(type) {
+	case model.And:
+		return squirrel.And{compileQuery(e.Left, lower), compileQuery(e.Right, lower)}
+	case model.Or:
+		return squirrel.Or{compileQuery(e.Left, lower), compileQuery(e.Right, lower)}
+	case model.Not:
+		return notExpr{compileQuery(e.Expr, lower)}
+	case model.Range:
+		return squirrel.And{
+			compileQuery(model.Compare{Field: e.Field, Op: model.OpGe, Value: e.From}, lower),
+			compileQuery(model.Compare{Field: e.Field, Op: model.OpLe, Value: e.To}, lower),
+		}
+	case model.In:
+		args := make([]any, len(e.Values))
+		for i, v := range e.Values {
+			args[i] = queryArg(e.Field, v)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+		return notNull(e.Field, squirrel.Expr(queryColumns[e.Field]+" IN ("+placeholders+")", args...))
+	case model.Compare:
+		column, arg := queryColumns[e.Field], queryArg(e.Field, e.Value)
+		switch e.Op {
+		case model.OpContains:
+			return squirrel.Expr(lower+`(`+column+`) LIKE ? ESCAPE '\'`, "%"+likeEscape(strings.ToLower(arg.(string)))+"%")
+		case model.OpPrefix:
+			return squirrel.Expr(lower+`(`+column+`) LIKE ? ESCAPE '\'`, likePrefix(strings.ToLower(arg.(string))))
+		default:
+			return notNull(e.Field, squirrel.Expr(column+" "+queryOperators[e.Op]+" ?", arg))
+		}
+	}
+	panic(fmt.Sprintf("storage: unknown query expression %T", expr))
+}
+
+// notExpr negates a condition.
+type notExpr struct {
+	cond squirrel.Sqlizer
+}
+
+func (n notExpr) ToSql() (string, []any, error) {
+	sql, args, err := n.cond.ToSql()
+	return "NOT (" + sql + ")", args, err
+}
+
+// notNull guards the conditions on release_date, the only nullable column of the query fields.
+func notNull(field string, cond squirrel.Sqlizer) squirrel.Sqlizer {
+	if field != model.FieldReleaseDate {
+		return cond
+	}
+	return squirrel.And{squirrel.NotEq{"release_date": nil}, cond}
+}
+
+func queryArg(field string, value any) any {
+	if field == model.FieldGroup || field == model.FieldSong {
+		return nameKey(value.(string))
+	}
+	return value
+}
+
+// withoutYears rewrites a year term as a term of release_date, other expressions are returned as they are.
+func withoutYears(expr model.Expr) model.Expr {
+	date := func(op, value string) model.Expr {
+		return model.Compare{Field: model.FieldReleaseDate, Op: op, Value: value}
+	}
+	switch e := expr.(type) {
+	case model.Compare:
+		if e.Field != model.FieldYear {
+			return e
+		}
+		from, to := yearDates(e.Value.(int))
+		switch e.Op {
+		case model.OpNe:
+			return model.Or{Left: date(model.OpLt, from), Right: date(model.OpGt, to)}
+		case model.OpLt:
+			return date(model.OpLt, from)
+		case model.OpLe:
+			return date(model.OpLe, to)
+		case model.OpGt:
+			return date(model.OpGt, to)
+		case model.OpGe:
+			return date(model.OpGe, from)
+		default:
+			return model.Range{Field: model.FieldReleaseDate, From: from, To: to}
+		}
+	case model.Range:
+		if e.Field != model.FieldYear {
+			return e
+		}
+		from, _ := yearDates(e.From.(int))
+		_, to := yearDates(e.To.(int))
+		return model.Range{Field: model.FieldReleaseDate, From: from, To: to}
+	case model.In:
+		if e.Field != model.FieldYear {
+			return e
+		}
+		var res model.Expr
+		for _, v := range e.Values {
+			from, to := yearDates(v.(int))
+			var year model.Expr = model.Range{Field: model.FieldReleaseDate, From: from, To: to}
+			if res != nil {
+				year = model.Or{Left: res, Right: year}
+			}
+			res = year
+		}
+		return res
+	}
+	return expr
+}
+
+func yearDates(year int) (from, to string) {
+	return fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-12-31", year)
+}
+
+// matchQuery is the in-process counterpart of compileQuery.
+func matchQuery(song model.Song, expr model.Expr) bool {
+	switch e := expr.(type) {
+	case model.And:
+		return matchQuery(song, e.Left) && matchQuery(song, e.Right)
+	case model.Or:
+		return matchQuery(song, e.Left) || matchQuery(song, e.Right)
+	case model.Not:
+		return !matchQuery(song, e.Expr)
+	case model.Range:
+		return matchQuery(song, model.Compare{Field: e.Field, Op: model.OpGe, Value: e.From}) &&
+			matchQuery(song, model.Compare{Field: e.Field, Op: model.OpLe, Value: e.To})
+	case model.In:
+		for _, v := range e.Values {
+			if matchQuery(song, model.Compare{Field: e.Field, Op: model.OpEq, Value: v}) {
+				return true
+			}
+		}
+		return false
+	case model.Compare:
+		value, ok := songField(song, e.Field)
+		if !ok {
+			return false
+		}
+		arg := queryArg(e.Field, e.Value)
+		switch e.Op {
+		case model.OpContains:
+			return strings.Contains(strings.ToLower(value.(string)), strings.ToLower(arg.(string)))
+		case model.OpPrefix:
+			return strings.HasPrefix(strings.ToLower(value.(string)), strings.ToLower(arg.(string)))
+		}
+		res := compareValues(value, arg)
+		switch e.Op {
+		case model.OpNe:
+			return res != 0
+		case model.OpLt:
+			return res < 0
+		case model.OpLe:
+			return res <= 0
+		case model.OpGt:
+			return res > 0
+		case model.OpGe:
+			return res >= 0
+		default:
+			return res == 0
+		}
+	}
+	panic(fmt.Sprintf("storage: unknown query expression %T", expr))
+}
+
+// songField returns the value of a query field of the song, false for a missing release date.
+func songField(song model.Song, field string) (any, bool) {
+	switch field {
+	case model.FieldID:
+		return song.ID, true
+	case model.FieldGroup:
+		return nameKey(song.Group), true
+	case model.FieldSong:
+		return nameKey(song.Song), true
+	case model.FieldText:
+		return song.Text, true
+	case model.FieldLink:
+		return song.Link, true
+	case model.FieldReleaseDate:
+		return song.ReleaseDate, song.ReleaseDate != ""
+	case model.FieldYear:
+		if song.ReleaseDate == "" {
+			return nil, false
+		}
+		year, err := strconv.Atoi(song.ReleaseDate[:4])
+		return year, err == nil
+	case model.FieldVersion:
+		return song.Version, true
+	case model.FieldEnriched:
+		return song.Enriched, true
+	}
+	return nil, false
+}
+
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case int:
+		return cmp.Compare(a, b.(int))
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		if a == b.(bool) {
+			return 0
+		}
+		return 1
+	}
+	return 1
+}
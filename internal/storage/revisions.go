@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type actorKey struct{}
+
+// WithActor attaches the name of whoever makes the change to ctx, it is recorded in the song revisions.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+const revisionColumns = "revision, operation, actor, created_at, song_id, group_name, COALESCE(artist_id, 0), song, COALESCE(album_id, 0), COALESCE(track_number, 0), COALESCE(cover_of, 0), release_date, text, link, enriched, verse_count, version"
+
+// GetSongRevisions returns the history of a song oldest first. It is kept after the song is deleted.
+func (s *Storage) GetSongRevisions(ctx context.Context, id int) ([]model.SongRevision, error) {
+	s.logger.Debug("Fetching song revisions by ID:", id)
+
+	query := squirrel.Select(revisionColumns).From("song_revisions").Where(squirrel.Eq{"song_id": id}).OrderBy("revision")
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]model.SongRevision, 0)
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	s.logger.Debug("Fetched revisions:", len(revisions))
+	return revisions, nil
+}
+
+func (s *Storage) GetSongRevision(ctx context.Context, id, revision int) (model.SongRevision, error) {
+	s.logger.Debug("Fetching song revision by ID:", id, "revision:", revision)
+
+	res, err := s.songRevision(ctx, s.db, id, revision)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return res, err
+}
+
+// RevertSong writes the state of a past revision back as a new version of the song. A song in the trash is restored,
+// a purged one is recreated under its old id. A non-zero version makes the revert conditional on the current version.
+func (s *Storage) RevertSong(ctx context.Context, id, revision, version int) (model.Song, error) {
+	s.logger.Debugw("Reverting song", "id", id, "revision", revision, "version", version)
+
+	var reverted model.Song
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		target, err := s.songRevision(ctx, tx, id, revision)
+		if err != nil {
+			return err
+		}
+		song := target.Song
+
+		date, err := releaseDateArg(song.ReleaseDate)
+		if err != nil {
+			return err
+		}
+		verses := splitVerses(song.Text)
+
+		current, trashed, err := s.currentVersion(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if version > 0 && (trashed || current != version) {
+			return ErrVersionMismatch
+		}
+		taken, err := s.nameTaken(ctx, tx, song.Group, song.Song, id)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrSongExists
+		}
+		previous, err := s.artistIDs(ctx, tx, songArtistIDs(id))
+		if err != nil {
+			return err
+		}
+		if err = s.ensureArtist(ctx, tx, song.Group); err != nil {
+			return err
+		}
+		// the album of the revision may have been deleted since, the song is then left without one
+		if err = s.checkAlbum(ctx, tx, song.AlbumID); errors.Is(err, ErrAlbumNotFound) {
+			song.AlbumID, song.Track = 0, 0
+		} else if err != nil {
+			return err
+		}
+		// so may the original of a cover
+		if err = s.checkOriginal(ctx, tx, song.CoverOf); errors.Is(err, ErrOriginalNotFound) {
+			song.CoverOf = 0
+		} else if err != nil {
+			return err
+		}
+
+		var sqlString string
+		var args []any
+		if current == 0 {
+			// the song was purged, bring it back under its old id with a version following the last known one
+			last := squirrel.Expr("(SELECT MAX(version) + 1 FROM song_revisions WHERE song_id = ?)", id)
+			sqlString, args, err = squirrel.Insert("songs").
+				Columns("id", "group_name", "artist_id", "song", "album_id", "track_number", "cover_of", "group_key", "song_key", "release_date", "text", "link", "enriched", "verse_count", "version").
+				Values(id, song.Group, artistIDExpr(song.Group), song.Song, optionalIntArg(song.AlbumID), optionalIntArg(song.Track), optionalIntArg(song.CoverOf), nameKey(song.Group), nameKey(song.Song), date, song.Text, song.Link, song.Enriched, len(verses), last).
+				Suffix("RETURNING " + songColumns).
+				PlaceholderFormat(s.placeholder).ToSql()
+		} else {
+			sqlString, args, err = squirrel.Update("songs").
+				Set("group_name", song.Group).
+				Set("artist_id", artistIDExpr(song.Group)).
+				Set("song", song.Song).
+				Set("album_id", optionalIntArg(song.AlbumID)).
+				Set("track_number", optionalIntArg(song.Track)).
+				Set("cover_of", optionalIntArg(song.CoverOf)).
+				Set("group_key", nameKey(song.Group)).
+				Set("song_key", nameKey(song.Song)).
+				Set("release_date", date).
+				Set("text", song.Text).
+				Set("link", song.Link).
+				Set("enriched", song.Enriched).
+				Set("verse_count", len(verses)).
+				Set("version", squirrel.Expr("version + 1")).
+				Set("deleted_at", nil).
+				Where(squirrel.Eq{"id": id, "version": current}).
+				Suffix("RETURNING " + songColumns).
+				PlaceholderFormat(s.placeholder).ToSql()
+		}
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+		row := tx.QueryRowContext(ctx, sqlString, args...)
+		err = row.Scan(songDests(&reverted, songColumnList)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			// changed by someone else since currentVersion read it
+			return ErrVersionMismatch
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.writeVerses(ctx, tx, id, verses); err != nil {
+			return err
+		}
+		if err = s.deleteOrphanArtists(ctx, tx, previous); err != nil {
+			return err
+		}
+		return s.writeRevision(ctx, tx, model.RevisionRevert, reverted)
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Song{}, err
+	}
+	s.logger.Debug("Reverted song:", reverted)
+
+	return reverted, nil
+}
+
+// currentVersion returns the version of a song, including one in the trash, or 0 when it does not exist.
+func (s *Storage) currentVersion(ctx context.Context, tx *sql.Tx, id int) (version int, trashed bool, err error) {
+	sqlString, args, err := squirrel.Select("version", "deleted_at IS NOT NULL").From("songs").Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return 0, false, err
+	}
+	err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&version, &trashed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, trashed, err
+}
+
+// queryRower is implemented by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *Storage) songRevision(ctx context.Context, q queryRower, id, revision int) (model.SongRevision, error) {
+	sqlString, args, err := squirrel.Select(revisionColumns).From("song_revisions").
+		Where(squirrel.Eq{"song_id": id, "revision": revision}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return model.SongRevision{}, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	res, err := scanRevision(q.QueryRowContext(ctx, sqlString, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return res, ErrRevisionNotFound
+	}
+	return res, err
+}
+
+// writeRevision appends a snapshot of song to its history, numbering it after the last revision of the song.
+func (s *Storage) writeRevision(ctx context.Context, tx *sql.Tx, operation string, song model.Song) error {
+	date, err := releaseDateArg(song.ReleaseDate)
+	if err != nil {
+		return err
+	}
+	next := squirrel.Expr("(SELECT COALESCE(MAX(revision), 0) + 1 FROM song_revisions WHERE song_id = ?)", song.ID)
+	sqlString, args, err := squirrel.Insert("song_revisions").
+		Columns("song_id", "revision", "operation", "group_name", "artist_id", "song", "album_id", "track_number", "cover_of", "release_date", "text", "link", "enriched", "verse_count", "version", "actor", "created_at").
+		Values(song.ID, next, operation, song.Group, song.ArtistID, song.Song, optionalIntArg(song.AlbumID), optionalIntArg(song.Track), optionalIntArg(song.CoverOf), date, song.Text, song.Link, song.Enriched, song.VerseCount, song.Version, actorFrom(ctx), time.Now().UTC()).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+	_, err = tx.ExecContext(ctx, sqlString, args...)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRevision(row rowScanner) (model.SongRevision, error) {
+	var r model.SongRevision
+	err := row.Scan(&r.Revision, &r.Operation, &r.Actor, &r.CreatedAt,
+		&r.Song.ID, &r.Song.Group, &r.Song.ArtistID, &r.Song.Song, &r.Song.AlbumID, &r.Song.Track, &r.Song.CoverOf, releaseDate(&r.Song.ReleaseDate), &r.Song.Text, &r.Song.Link, &r.Song.Enriched, &r.Song.VerseCount, &r.Song.Version)
+	return r, err
+}
@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"go_test_effective_mobile/internal/model"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+const (
+	highlightStart = "<b>"
+	highlightStop  = "</b>"
+)
+
+// matchStart and matchStop mark the matches in the snippets built by the databases. They are private use
+// characters rather than the highlight tags, so the snippet can be HTML-escaped before the tags are put in.
+const (
+	matchStart = "\uE000"
+	matchStop  = "\uE001"
+)
+
+var snippetMarkers = strings.NewReplacer(matchStart, highlightStart, matchStop, highlightStop)
+
+// renderSnippet HTML-escapes a snippet of lyrics and turns its match markers into highlight tags.
+func renderSnippet(snippet string) string {
+	return snippetMarkers.Replace(html.EscapeString(snippet))
+}
+
+// searchTSQuery is the tsquery of a search, its arguments are searchTSQueryArgs.
+const searchTSQuery = "websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?) || websearch_to_tsquery('simple', ?)"
+
+func searchTSQueryArgs(search model.SongSearch) []any {
+	return []any{search.Query, search.Query, nameKey(search.Query)}
+}
+
+// searchFilter narrows the songs s of a search by the filters of it.
+func searchFilter(search model.SongSearch) squirrel.And {
+	cond := squirrel.And{}
+	if search.Group != "" {
+		cond = append(cond, squirrel.Eq{"s.group_key": nameKey(search.Group)})
+	}
+	if search.ReleaseFrom != "" {
+		cond = append(cond, squirrel.GtOrEq{"s.release_date": search.ReleaseFrom})
+	}
+	if search.ReleaseTo != "" {
+		cond = append(cond, squirrel.LtOrEq{"s.release_date": search.ReleaseTo})
+	}
+	return cond
+}
+
+// matchesSearchFilter is the in-process counterpart of searchFilter.
+func matchesSearchFilter(song model.Song, search model.SongSearch) bool {
+	switch {
+	case search.Group != "" && nameKey(song.Group) != nameKey(search.Group):
+		return false
+	case search.ReleaseFrom != "" && (song.ReleaseDate == "" || song.ReleaseDate < search.ReleaseFrom):
+		return false
+	case search.ReleaseTo != "" && (song.ReleaseDate == "" || song.ReleaseDate > search.ReleaseTo):
+		return false
+	}
+	return true
+}
+
+// SearchSongs ranks the songs matching a full-text query over titles, groups and lyrics,
+// together with a highlighted snippet of the best matching verse. Titles and groups are also
+// found by the transliteration of the query, see nameKey.
+func (s *Storage) SearchSongs(ctx context.Context, search model.SongSearch, limit, offset int) ([]model.SearchResult, error) {
+	s.logger.Debugw("Searching songs", "search", search, "limit", limit, "offset", offset)
+
+	query := squirrel.Select(qualified("s", songColumnList)...).Columns(
+		"ts_rank(s.search_vector, q.query) AS rank",
+		"v.position", "v.kind", "v.label",
+		"ts_headline('russian', v.text, q.query, 'StartSel="+matchStart+", StopSel="+matchStop+", MinWords=5, MaxWords=20')",
+	).
+		Prefix("WITH q AS (SELECT "+searchTSQuery+" AS query)", searchTSQueryArgs(search)...).
+		From("songs s").
+		CrossJoin("q").
+		JoinClause(`LEFT JOIN LATERAL (
+			SELECT position, kind, label, text FROM song_verses
+			WHERE song_id = s.id AND search_vector @@ q.query
+			ORDER BY ts_rank(search_vector, q.query) DESC, position
+			LIMIT 1) v ON true`).
+		Where("s.search_vector @@ q.query").
+		Where(squirrel.Eq{"s.deleted_at": nil}).
+		Where(searchFilter(search)).
+		OrderBy("rank DESC", "s.id").Limit(uint64(limit)).Offset(uint64(offset))
+
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.SearchResult, 0)
+	for rows.Next() {
+		var (
+			res         model.SearchResult
+			song        = &res.Song
+			position    sql.NullInt64
+			kind, label sql.NullString
+			snippet     sql.NullString
+		)
+		if err = rows.Scan(append(songDests(song, songColumnList), &res.Rank, &position, &kind, &label, &snippet)...); err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		if position.Valid {
+			res.Match = &model.VerseMatch{Number: int(position.Int64), Type: kind.String, Label: label.String, Snippet: renderSnippet(snippet.String)}
+		}
+		results = append(results, res)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Debug("Found songs:", len(results))
+	return results, nil
+}
+
+// SearchSongs uses the FTS5 tables of the SQLite schema, bm25 weights mirror the tsvector weights of Postgres.
+func (s *SQLiteStorage) SearchSongs(ctx context.Context, search model.SongSearch, limit, offset int) ([]model.SearchResult, error) {
+	s.logger.Debugw("Searching songs", "search", search, "limit", limit, "offset", offset)
+
+	match := ftsQuery(search.Query)
+	if match == "" {
+		return []model.SearchResult{}, nil
+	}
+
+	query := squirrel.Select(qualified("s", songColumnList)...).Columns(
+		"-bm25(song_search, 2.0, 5.0, 1.0) AS rank",
+	).
+		From("song_search").
+		Join("songs s ON s.id = song_search.rowid").
+		Where("song_search MATCH ?", match).
+		Where(squirrel.Eq{"s.deleted_at": nil}).
+		Where(searchFilter(search)).
+		OrderBy("rank DESC", "s.id").Limit(uint64(limit)).Offset(uint64(offset))
+
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.SearchResult, 0)
+	ids := make([]int, 0)
+	for rows.Next() {
+		var res model.SearchResult
+		song := &res.Song
+		if err = rows.Scan(append(songDests(song, songColumnList), &res.Rank)...); err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		results = append(results, res)
+		ids = append(ids, song.ID)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	matches, err := s.verseMatches(ctx, match, ids)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	for i := range results {
+		results[i].Match = matches[results[i].ID]
+	}
+
+	s.logger.Debug("Found songs:", len(results))
+	return results, nil
+}
+
+// verseMatches finds the best matching verse of each of the songs.
+func (s *SQLiteStorage) verseMatches(ctx context.Context, match string, ids []int) (map[int]*model.VerseMatch, error) {
+	query := squirrel.Select("v.song_id", "v.position", "v.kind", "v.label",
+		"snippet(verse_search, 2, '"+matchStart+"', '"+matchStop+"', '…', 20)").
+		From("verse_search").
+		Join("song_verses v ON v.song_id = verse_search.song_id AND v.position = verse_search.position").
+		Where("verse_search MATCH ?", match).
+		Where(squirrel.Eq{"verse_search.song_id": ids}).
+		OrderBy("bm25(verse_search)", "v.position")
+
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make(map[int]*model.VerseMatch, len(ids))
+	for rows.Next() {
+		var songID int
+		var m model.VerseMatch
+		if err = rows.Scan(&songID, &m.Number, &m.Type, &m.Label, &m.Snippet); err != nil {
+			return nil, err
+		}
+		// rows come best first
+		if _, ok := matches[songID]; !ok {
+			m.Snippet = renderSnippet(m.Snippet)
+			matches[songID] = &m
+		}
+	}
+	return matches, rows.Err()
+}
+
+// searchTerms splits a query into lower-case words, punctuation and search operators are dropped.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ftsQuery turns a free-text query into an FTS5 query requiring every word, quoted so none is read as an operator.
+// A word with Cyrillic letters also matches its transliteration.
+func ftsQuery(query string) string {
+	terms := searchTerms(query)
+	for i, term := range terms {
+		if key := nameKey(term); key != term {
+			terms[i] = `("` + term + `" OR "` + key + `")`
+		} else {
+			terms[i] = `"` + term + `"`
+		}
+	}
+	return strings.Join(terms, " AND ")
+}
+
+// Field weights of the in-process search, in line with the A, B and C weights of the Postgres search vector.
+const (
+	titleWeight = 1.0
+	groupWeight = 0.4
+	textWeight  = 0.2
+)
+
+// matchSong is the in-process counterpart of the full-text search. A term matches a word it is a prefix of,
+// which stands in for stemming, titles and groups are compared by their keys. Every term has to match somewhere in the song.
+func matchSong(song model.Song, verses []model.Verse, terms []string) (model.SearchResult, bool) {
+	if len(terms) == 0 {
+		return model.SearchResult{}, false
+	}
+
+	res := model.SearchResult{Song: song}
+	title, group, text := searchTerms(nameKey(song.Song)), searchTerms(nameKey(song.Group)), searchTerms(song.Text)
+	for _, term := range terms {
+		hits := []int{countMatches(title, nameKey(term)), countMatches(group, nameKey(term)), countMatches(text, term)}
+		if hits[0]+hits[1]+hits[2] == 0 {
+			return model.SearchResult{}, false
+		}
+		res.Rank += titleWeight*float64(hits[0]) + groupWeight*float64(hits[1]) + textWeight*float64(hits[2])
+	}
+
+	best, bestScore := -1, 0
+	for i, v := range verses {
+		words := searchTerms(v.Text)
+		score := 0
+		for _, term := range terms {
+			score += countMatches(words, term)
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best >= 0 {
+		v := verses[best]
+		res.Match = &model.VerseMatch{Number: v.Number, Type: v.Type, Label: v.Label, Snippet: highlight(v.Text, terms)}
+	}
+	return res, true
+}
+
+func countMatches(words []string, term string) int {
+	n := 0
+	for _, w := range words {
+		if strings.HasPrefix(w, term) {
+			n++
+		}
+	}
+	return n
+}
+
+// highlight HTML-escapes text and wraps the words matched by any of the terms in highlightStart and highlightStop.
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+			j++
+		}
+		word := string(runes[i:j])
+		lower := strings.ToLower(word)
+		if slices.ContainsFunc(terms, func(term string) bool { return strings.HasPrefix(lower, term) }) {
+			b.WriteString(matchStart + word + matchStop)
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+	return renderSnippet(b.String())
+}
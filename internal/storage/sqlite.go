@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"go_test_effective_mobile/db"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"go.uber.org/zap"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	sqlitedriver "modernc.org/sqlite"
+)
+
+// SQLiteStorage is the single-file backend for deployments without Postgres.
+// It reuses the queries of Storage and only differs in the connection, placeholders and migration set.
+type SQLiteStorage struct {
+	Storage
+}
+
+func (s *SQLiteStorage) InitStorage(logger *zap.SugaredLogger, EndPointDB string) error {
+	var err error
+	logger.Debug("Initializing SQLite storage with DB endpoint:", EndPointDB)
+	s.db, err = sql.Open("sqlite", sqliteDSN(EndPointDB))
+	if err != nil {
+		logger.Info(zap.Error(err))
+		return err
+	}
+	// SQLite allows a single writer, sharing one connection also keeps ":memory:" databases alive
+	s.db.SetMaxOpenConns(1)
+	s.logger = logger
+	s.placeholder = squirrel.Question
+	s.sortExprs = sqliteSortExprs
+	s.facetExprs = sqliteFacetExprs
+	s.lowerFunc = "songs_lower"
+	if err = s.initMigrations(); err != nil {
+		return err
+	}
+	if err = s.resplitStaleVerses(context.Background()); err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	if err = s.warnRawReleaseDates(context.Background()); err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) initMigrations() error {
+	s.logger.Debug("Initializing SQLite migrations...")
+	driver, err := sqlite.WithInstance(s.db, &sqlite.Config{})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	source, err := iofs.New(db.SQLiteMigrations, "migrations_sqlite")
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite", driver)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	s.logger.Debug("Running migrations...")
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	s.logger.Debug("Migrations completed successfully")
+	return nil
+}
+
+// sqliteDSN turns "sqlite://path/to/file.db" into a modernc.org/sqlite data source with foreign keys enabled.
+func sqliteDSN(endPointDB string) string {
+	dsn := endPointDB
+	for _, prefix := range []string{"sqlite3://", "sqlite://"} {
+		if strings.HasPrefix(dsn, prefix) {
+			dsn = strings.TrimPrefix(dsn, prefix)
+			break
+		}
+	}
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
+
+// collators are shared by the calls of songs_collation_key, a collator cannot be used concurrently.
+var collators = sync.Pool{New: func() any { return collate.New(language.Russian) }}
+
+// songs_collation_key(text) returns a blob that sorts like the text does by the rules of Russian,
+// SQLite itself only sorts text by code points. songs_lower(text) lower-cases all of Unicode where the
+// built-in lower only folds ASCII, the contains and prefix terms of song queries rely on it. The built-in
+// is left alone as the functions are registered for every modernc.org/sqlite connection of the process.
+func init() {
+	sqlitedriver.MustRegisterDeterministicScalarFunction("songs_collation_key", 1,
+		func(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+			text, ok := args[0].(string)
+			if !ok {
+				return args[0], nil
+			}
+			c := collators.Get().(*collate.Collator)
+			defer collators.Put(c)
+			var buf collate.Buffer
+			return c.KeyFromString(&buf, text), nil
+		})
+	sqlitedriver.MustRegisterDeterministicScalarFunction("songs_lower", 1,
+		func(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+			text, ok := args[0].(string)
+			if !ok {
+				return args[0], nil
+			}
+			return strings.ToLower(text), nil
+		})
+}
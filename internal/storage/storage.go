@@ -4,35 +4,236 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"go_test_effective_mobile/db"
 	"go_test_effective_mobile/internal/model"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/pgx"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+var (
+	ErrVerseNotFound    = errors.New("verse not found")
+	ErrVersionMismatch  = errors.New("song version does not match")
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrSongExists       = errors.New("song with this group and name already exists")
+	ErrInvalidCursor    = errors.New("cursor does not match the sort order")
+	ErrArtistExists     = errors.New("artist with this name already exists")
+	ErrAlbumExists      = errors.New("album with this artist and title already exists")
+	ErrAlbumNotFound    = errors.New("album not found")
+	ErrTrackNoAlbum     = errors.New("track number of a song without an album")
+	ErrOriginalNotFound = errors.New("original song not found")
+	ErrTagNotFound      = errors.New("song has no such tag")
+	ErrSongNotFound     = errors.New("song not found")
+	ErrEntryNotFound    = errors.New("playlist entry not found")
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
 type IStorage interface {
 	InitStorage(logger *zap.SugaredLogger, EndPointDB string) error
 	initMigrations() error
 	Ping(ctx context.Context) error
-	GetSongs(ctx context.Context, group, song, releaseDate string, limit, offset int) ([]model.Song, error)
+	GetSongs(ctx context.Context, filter model.SongFilter, after *model.SongCursor, limit int) ([]model.Song, int, error)
+	GetSongFacets(ctx context.Context, filter model.SongFilter, facets []string) (map[string][]model.FacetCount, error)
+	SearchSongs(ctx context.Context, search model.SongSearch, limit, offset int) ([]model.SearchResult, error)
+	SearchFacets(ctx context.Context, search model.SongSearch, facets []string) (map[string][]model.FacetCount, error)
 	AddSong(ctx context.Context, song model.Song) (model.Song, error)
 	GetSongByID(ctx context.Context, id string) (model.Song, error)
-	DeleteSong(ctx context.Context, id string) error
-	UpdateSong(ctx context.Context, song model.Song) (model.Song, error)
+	DeleteSong(ctx context.Context, id string, version int) error
+	UpdateSong(ctx context.Context, song model.Song, version int) (model.Song, error)
+	PatchSong(ctx context.Context, id, version int, patch model.SongPatch) (model.Song, error)
 	GetSongVerseByID(ctx context.Context, id, verse int) (string, error)
-	GetInfo(ctx context.Context, group, song string) (model.Song, error)
+	GetSongVerses(ctx context.Context, id int, sectionType string, collapse bool, limit, offset int) ([]model.Verse, int, error)
+	GetInfo(ctx context.Context, group, song, match string) (model.SongDetail, error)
+	GetSongRevisions(ctx context.Context, id int) ([]model.SongRevision, error)
+	GetSongRevision(ctx context.Context, id, revision int) (model.SongRevision, error)
+	RevertSong(ctx context.Context, id, revision, version int) (model.Song, error)
+	GetTrash(ctx context.Context, limit, offset int) ([]model.TrashedSong, error)
+	RestoreSong(ctx context.Context, id int) (model.Song, error)
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+	GetArtists(ctx context.Context, limit, offset int) ([]model.Artist, error)
+	GetArtist(ctx context.Context, id int) (model.Artist, error)
+	RenameArtist(ctx context.Context, id int, name string) (model.Artist, error)
+	MergeArtists(ctx context.Context, id, from int) (model.Artist, error)
+	GetAlbums(ctx context.Context, artistID, limit, offset int) ([]model.Album, error)
+	GetAlbum(ctx context.Context, id int) (model.Album, error)
+	AddAlbum(ctx context.Context, album model.Album) (model.Album, error)
+	UpdateAlbum(ctx context.Context, album model.Album) (model.Album, error)
+	DeleteAlbum(ctx context.Context, id int) error
+	GetAlbumSongs(ctx context.Context, id int) ([]model.Song, error)
+	GetSongArtists(ctx context.Context, id int) ([]model.Credit, error)
+	SetSongArtists(ctx context.Context, id int, credits []model.Credit) ([]model.Credit, error)
+	GetSongCovers(ctx context.Context, id int) ([]model.Song, error)
+	GetTags(ctx context.Context, limit, offset int) ([]model.Tag, error)
+	GetSongTags(ctx context.Context, id int) ([]string, error)
+	AddSongTags(ctx context.Context, id int, tags []string) ([]string, error)
+	RemoveSongTag(ctx context.Context, id int, tag string) error
+	GetPlaylists(ctx context.Context, limit, offset int) ([]model.Playlist, error)
+	GetPlaylist(ctx context.Context, id int) (model.Playlist, error)
+	AddPlaylist(ctx context.Context, name string) (model.Playlist, error)
+	RenamePlaylist(ctx context.Context, id int, name string) (model.Playlist, error)
+	DeletePlaylist(ctx context.Context, id int) error
+	GetPlaylistEntries(ctx context.Context, id int) ([]model.PlaylistEntry, error)
+	AddPlaylistEntry(ctx context.Context, id, songID, position int) (model.PlaylistEntry, error)
+	MovePlaylistEntry(ctx context.Context, id, entryID, position int) (model.PlaylistEntry, error)
+	RemovePlaylistEntry(ctx context.Context, id, entryID int) error
 	Close() error
 }
 
+// NewStorage returns an uninitialized storage for the given driver, InitStorage must be called before use.
+// When driver is empty it is picked from the scheme of the database endpoint.
+func NewStorage(driver, endPointDB string) (IStorage, error) {
+	if driver == "" {
+		driver = driverFromEndPoint(endPointDB)
+	}
+	switch driver {
+	case DriverPostgres:
+		return &Storage{}, nil
+	case DriverSQLite:
+		return &SQLiteStorage{}, nil
+	case DriverMemory:
+		return &MemoryStorage{}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+func driverFromEndPoint(endPointDB string) string {
+	scheme, _, _ := strings.Cut(endPointDB, ":")
+	switch strings.ToLower(scheme) {
+	case "sqlite", "sqlite3", "file":
+		return DriverSQLite
+	case "memory":
+		return DriverMemory
+	default:
+		return DriverPostgres
+	}
+}
+
+// songColumnList are the columns of a model.Song, songColumns the list of them to select.
+var (
+	songColumnList = []string{"id", "group_name", "artist_id", "song", "album_id", "track_number", "cover_of", "release_date", "text", "link", "enriched", "verse_count", "version"}
+	songColumns    = strings.Join(songColumnList, ", ")
+)
+
+// fieldColumns are the columns behind model.SongFields.
+var fieldColumns = map[string]string{
+	"id":          "id",
+	"group":       "group_name",
+	"artistId":    "artist_id",
+	"song":        "song",
+	"albumId":     "album_id",
+	"track":       "track_number",
+	"coverOf":     "cover_of",
+	"releaseDate": "release_date",
+	"text":        "text",
+	"link":        "link",
+	"enriched":    "enriched",
+	"verseCount":  "verse_count",
+	"version":     "version",
+}
+
+// songDest returns where a column is scanned into the song, nil for a column that is not a field of it.
+func songDest(song *model.Song, column string) any {
+	switch column {
+	case "id":
+		return &song.ID
+	case "group_name":
+		return &song.Group
+	case "artist_id":
+		return &song.ArtistID
+	case "song":
+		return &song.Song
+	case "album_id":
+		return optionalInt(&song.AlbumID)
+	case "track_number":
+		return optionalInt(&song.Track)
+	case "cover_of":
+		return optionalInt(&song.CoverOf)
+	case "release_date":
+		return releaseDate(&song.ReleaseDate)
+	case "text":
+		return &song.Text
+	case "link":
+		return &song.Link
+	case "enriched":
+		return &song.Enriched
+	case "verse_count":
+		return &song.VerseCount
+	case "version":
+		return &song.Version
+	case sortColumns[sortScore]:
+		return &song.Score
+	}
+	return nil
+}
+
+// songDests returns the destinations of columns in the song, in the order of the columns.
+func songDests(song *model.Song, columns []string) []any {
+	dests := make([]any, len(columns))
+	for i, column := range columns {
+		dests[i] = songDest(song, column)
+	}
+	return dests
+}
+
+// qualified prefixes columns with the name of their table.
+func qualified(table string, columns []string) []string {
+	res := make([]string, len(columns))
+	for i, column := range columns {
+		res[i] = table + "." + column
+	}
+	return res
+}
+
+// pageColumns are the columns GetSongs selects: those of the fields of filter and of the sort order,
+// which the cursor of the next page is made of.
+func pageColumns(filter model.SongFilter, order []model.SortField) []string {
+	if len(filter.Fields) == 0 {
+		return songColumnList
+	}
+	columns := make([]string, 0, len(filter.Fields)+len(order))
+	add := func(column string) {
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	for _, f := range order {
+		if f.Field != sortScore {
+			add(sortColumns[f.Field])
+		}
+	}
+	for _, f := range filter.Fields {
+		add(fieldColumns[f])
+	}
+	return columns
+}
+
 type Storage struct {
-	db     *sql.DB
-	logger *zap.SugaredLogger
+	db          *sql.DB
+	logger      *zap.SugaredLogger
+	placeholder squirrel.PlaceholderFormat
+	sortExprs   map[string]string
+	facetExprs  map[string]string
+	// lowerFunc is the SQL function lower-casing all of Unicode
+	lowerFunc string
 }
 
 func (s *Storage) InitStorage(logger *zap.SugaredLogger, EndPointDB string) error {
@@ -44,7 +245,35 @@ func (s *Storage) InitStorage(logger *zap.SugaredLogger, EndPointDB string) erro
 		return err
 	}
 	s.logger = logger
-	return s.initMigrations()
+	s.placeholder = squirrel.Dollar
+	s.sortExprs = postgresSortExprs
+	s.facetExprs = postgresFacetExprs
+	s.lowerFunc = "lower"
+	if err = s.initMigrations(); err != nil {
+		return err
+	}
+	if err = s.resplitStaleVerses(context.Background()); err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	if err = s.warnRawReleaseDates(context.Background()); err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// warnRawReleaseDates logs the release dates the migrations could not convert to dates,
+// their text is kept in songs_release_date_raw to be fixed by hand.
+func (s *Storage) warnRawReleaseDates(ctx context.Context) error {
+	var count int
+	if err := s.db.QueryRowContext(ctx, "SELECT count(*) FROM songs_release_date_raw").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		s.logger.Warnw("Some release dates could not be converted and were cleared, their text is kept in songs_release_date_raw", "songs", count)
+	}
+	return nil
 }
 
 func (s *Storage) initMigrations() error {
@@ -54,7 +283,12 @@ func (s *Storage) initMigrations() error {
 		s.logger.Info(zap.Error(err))
 		return err
 	}
-	m, err := migrate.NewWithDatabaseInstance("file://db/migrations", "postgres", driver)
+	source, err := iofs.New(db.PostgresMigrations, "migrations")
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return err
+	}
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return err
@@ -77,24 +311,112 @@ func (s *Storage) Ping(ctx context.Context) error {
 	return err
 }
 
-func (s *Storage) GetSongs(ctx context.Context, group, song, releaseDate string, limit, offset int) ([]model.Song, error) {
-	s.logger.Debugw("Fetching songs with filters", "group", group, "song", song, "releaseDate", releaseDate, "limit", limit, "offset", offset)
+// GetSongs returns up to limit songs matching filter that come after the cursor, the first page when it is nil,
+// together with the number of all matching songs. Paging by the sort key rather than an offset neither repeats
+// nor skips songs when others are added or deleted in between. ErrInvalidCursor is returned for a cursor
+// of another sort order.
+func (s *Storage) GetSongs(ctx context.Context, filter model.SongFilter, after *model.SongCursor, limit int) ([]model.Song, int, error) {
+	s.logger.Debugw("Fetching songs with filters", "filter", filter, "after", after, "limit", limit)
+
+	query, scored := s.songsQuery(filter)
+
+	sqlString, args, err := squirrel.Select("count(*)").FromSelect(query, "s").PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, 0, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	var total int
+	if err = s.db.QueryRowContext(ctx, sqlString, args...).Scan(&total); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, 0, err
+	}
+
+	// the score is a column of the subquery, so the cursor can be compared with it
+	order := songOrder(filter)
+	page := squirrel.Select(pageColumns(filter, order)...).FromSelect(query, "s").OrderBy(s.orderBy(order)...).Limit(uint64(limit))
+	if scored {
+		page = page.Column("score")
+	}
+	if after != nil {
+		cond, err := s.songsAfter(order, after)
+		if err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, 0, err
+		}
+		page = page.Where(cond)
+	}
+	songs, err := s.querySongs(ctx, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	return songs, total, nil
+}
+
+// songsQuery selects the live songs matching filter. Names are compared by their keys, see nameKey.
+// Fuzzy name matches also select their similarity as a score column, scored reports whether the column is there.
+func (s *Storage) songsQuery(filter model.SongFilter) (query squirrel.SelectBuilder, scored bool) {
+	query = squirrel.Select(songColumns).From("songs").Where(squirrel.Eq{"deleted_at": nil})
+
+	var scores []string
+	var scoreArgs []any
+	for _, f := range []struct{ column, value string }{{"group_key", filter.Group}, {"song_key", filter.Song}} {
+		if f.value == "" {
+			continue
+		}
+		key := nameKey(f.value)
+		switch filter.Match {
+		case model.MatchPrefix:
+			query = query.Where(f.column+` LIKE ? ESCAPE '\'`, likePrefix(key))
+		case model.MatchFuzzy:
+			// % uses the trigram index, it keeps the rows with a similarity of at least pg_trgm.similarity_threshold
+			query = query.Where(f.column+" % ?", key)
+			scores = append(scores, "similarity("+f.column+", ?)")
+			scoreArgs = append(scoreArgs, key)
+		default:
+			query = query.Where(squirrel.Eq{f.column: key})
+		}
+	}
+
+	if filter.ReleaseDate != "" {
+		query = query.Where(squirrel.Eq{"release_date": filter.ReleaseDate})
+	}
+
+	if filter.ReleaseFrom != "" {
+		query = query.Where(squirrel.GtOrEq{"release_date": filter.ReleaseFrom})
+	}
+
+	if filter.ReleaseTo != "" {
+		query = query.Where(squirrel.LtOrEq{"release_date": filter.ReleaseTo})
+	}
+
+	if filter.Query != nil {
+		query = query.Where(compileQuery(filter.Query, s.lowerFunc))
+	}
 
-	query := squirrel.Select("*").From("songs").OrderBy("id").Limit(uint64(limit)).Offset(uint64(offset))
+	if filter.Album != 0 {
+		query = query.Where(squirrel.Eq{"album_id": filter.Album})
+	}
 
-	if group != "" {
-		query = query.Where(squirrel.Eq{"group_name": group})
+	if filter.Artist != 0 {
+		query = query.Where(creditedExpr(filter.Artist))
 	}
 
-	if song != "" {
-		query = query.Where(squirrel.Eq{"song": song})
+	if len(filter.Tags) > 0 {
+		query = query.Where(taggedExpr(filter.Tags, filter.TagMatch))
 	}
 
-	if releaseDate != "" {
-		query = query.Where(squirrel.Eq{"release_date": releaseDate})
+	if len(scores) > 0 {
+		score := fmt.Sprintf("(%s) / %d AS score", strings.Join(scores, " + "), len(scores))
+		query = query.Column(score, scoreArgs...)
 	}
+	return query, len(scores) > 0
+}
 
-	sqlString, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+// querySongs runs a select of songs, the columns are scanned by their names, see songDest.
+func (s *Storage) querySongs(ctx context.Context, query squirrel.SelectBuilder) ([]model.Song, error) {
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return nil, err
@@ -108,10 +430,15 @@ func (s *Storage) GetSongs(ctx context.Context, group, song, releaseDate string,
 	}
 	defer rows.Close()
 
+	columns, err := rows.Columns()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
 	songs := make([]model.Song, 0)
 	for rows.Next() {
 		var song model.Song
-		if err = rows.Scan(&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Text, &song.Link); err != nil {
+		if err = rows.Scan(songDests(&song, columns)...); err != nil {
 			return nil, err
 		}
 		s.logger.Debug("Scanned song:", song)
@@ -124,20 +451,49 @@ func (s *Storage) GetSongs(ctx context.Context, group, song, releaseDate string,
 func (s *Storage) AddSong(ctx context.Context, song model.Song) (model.Song, error) {
 	s.logger.Debugw("Adding new song", "song", song)
 
-	query := squirrel.Insert("songs").Columns("group_name", "song", "release_date", "text", "link").
-		Values(song.Group, song.Song, song.ReleaseDate, song.Text, song.Link).
-		Suffix("ON CONFLICT (group_name, song) DO NOTHING RETURNING id, group_name, song, release_date, text, link;")
+	date, err := releaseDateArg(song.ReleaseDate)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return song, err
+	}
+	verses := splitVerses(song.Text)
+	query := squirrel.Insert("songs").Columns("group_name", "artist_id", "song", "album_id", "track_number", "cover_of", "group_key", "song_key", "release_date", "text", "link", "enriched", "verse_count").
+		Values(song.Group, artistIDExpr(song.Group), song.Song, optionalIntArg(song.AlbumID), optionalIntArg(song.Track), optionalIntArg(song.CoverOf), nameKey(song.Group), nameKey(song.Song), date, song.Text, song.Link, song.Enriched, len(verses)).
+		Suffix("ON CONFLICT (group_name, song) WHERE deleted_at IS NULL DO NOTHING RETURNING " + songColumns)
 
-	sqlString, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return song, err
 	}
 	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
 
-	row := s.db.QueryRowContext(ctx, sqlString, args...)
 	var addedSong model.Song
-	if err := row.Scan(&addedSong.ID, &addedSong.Group, &addedSong.Song, &addedSong.ReleaseDate, &addedSong.Text, &addedSong.Link); err != nil {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.ensureArtist(ctx, tx, song.Group); err != nil {
+			return err
+		}
+		if err := s.checkAlbum(ctx, tx, song.AlbumID); err != nil {
+			return err
+		}
+		if err := s.checkOriginal(ctx, tx, song.CoverOf); err != nil {
+			return err
+		}
+		// ON CONFLICT DO NOTHING returns no row for a duplicate
+		row := tx.QueryRowContext(ctx, sqlString, args...)
+		err := row.Scan(songDests(&addedSong, songColumnList)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSongExists
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.writeVerses(ctx, tx, addedSong.ID, verses); err != nil {
+			return err
+		}
+		return s.writeRevision(ctx, tx, model.RevisionCreate, addedSong)
+	})
+	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return song, err
 	}
@@ -149,8 +505,8 @@ func (s *Storage) AddSong(ctx context.Context, song model.Song) (model.Song, err
 func (s *Storage) GetSongByID(ctx context.Context, id string) (model.Song, error) {
 	s.logger.Debug("Fetching song by ID:", id)
 
-	query := squirrel.Select("*").From("songs").Where(squirrel.Eq{"id": id})
-	sqlString, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+	query := squirrel.Select(songColumns).From("songs").Where(squirrel.Eq{"id": id, "deleted_at": nil})
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return model.Song{}, err
@@ -159,7 +515,7 @@ func (s *Storage) GetSongByID(ctx context.Context, id string) (model.Song, error
 
 	row := s.db.QueryRowContext(ctx, sqlString, args...)
 	var song model.Song
-	if err := row.Scan(&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Text, &song.Link); err != nil {
+	if err := row.Scan(songDests(&song, songColumnList)...); err != nil {
 		s.logger.Info(zap.Error(err))
 		return song, err
 	}
@@ -168,46 +524,115 @@ func (s *Storage) GetSongByID(ctx context.Context, id string) (model.Song, error
 	return song, nil
 }
 
-func (s *Storage) DeleteSong(ctx context.Context, id string) error {
-	s.logger.Debug("Deleting song by ID:", id)
+// DeleteSong moves a song to the trash, it stays restorable until PurgeTrash removes it.
+// A non-zero version makes the delete conditional on the current version.
+func (s *Storage) DeleteSong(ctx context.Context, id string, version int) error {
+	s.logger.Debug("Deleting song by ID:", id, "version:", version)
 
-	query := squirrel.Delete("songs").Where(squirrel.Eq{"id": id})
-	sqlString, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+	query := squirrel.Update("songs").Set("deleted_at", time.Now().UTC()).
+		Where(squirrel.Eq{"id": id, "deleted_at": nil}).
+		Suffix("RETURNING " + songColumns)
+	if version > 0 {
+		query = query.Where(squirrel.Eq{"version": version})
+	}
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return err
 	}
 	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
 
-	_, err = s.db.ExecContext(ctx, sqlString, args...)
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		var deleted model.Song
+		row := tx.QueryRowContext(ctx, sqlString, args...)
+		err := row.Scan(songDests(&deleted, songColumnList)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.missingOrStale(ctx, tx, id, version)
+		}
+		if err != nil {
+			return err
+		}
+		return s.writeRevision(ctx, tx, model.RevisionDelete, deleted)
+	})
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 	}
 	return err
 }
 
-func (s *Storage) UpdateSong(ctx context.Context, song model.Song) (model.Song, error) {
-	s.logger.Debugw("Updating song", "song", song)
+// UpdateSong replaces a song. A non-zero version makes the update conditional on the current version.
+func (s *Storage) UpdateSong(ctx context.Context, song model.Song, version int) (model.Song, error) {
+	s.logger.Debugw("Updating song", "song", song, "version", version)
 
+	date, err := releaseDateArg(song.ReleaseDate)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return song, err
+	}
+	verses := splitVerses(song.Text)
 	query := squirrel.Update("songs").
 		Set("group_name", song.Group).
+		Set("artist_id", artistIDExpr(song.Group)).
 		Set("song", song.Song).
-		Set("release_date", song.ReleaseDate).
+		Set("album_id", optionalIntArg(song.AlbumID)).
+		Set("track_number", optionalIntArg(song.Track)).
+		Set("cover_of", optionalIntArg(song.CoverOf)).
+		Set("group_key", nameKey(song.Group)).
+		Set("song_key", nameKey(song.Song)).
+		Set("release_date", date).
 		Set("text", song.Text).
 		Set("link", song.Link).
-		Where(squirrel.Eq{"id": song.ID}).
-		Suffix("RETURNING id, group_name, song, release_date, text, link;")
+		Set("verse_count", len(verses)).
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{"id": song.ID, "deleted_at": nil}).
+		Suffix("RETURNING " + songColumns)
+
+	if version > 0 {
+		query = query.Where(squirrel.Eq{"version": version})
+	}
 
-	sqlString, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return song, err
 	}
 	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
 
-	row := s.db.QueryRowContext(ctx, sqlString, args...)
 	var updatedSong model.Song
-	if err := row.Scan(&updatedSong.ID, &updatedSong.Group, &updatedSong.Song, &updatedSong.ReleaseDate, &updatedSong.Text, &updatedSong.Link); err != nil {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		previous, err := s.artistIDs(ctx, tx, songArtistIDs(song.ID))
+		if err != nil {
+			return err
+		}
+		if err := s.ensureArtist(ctx, tx, song.Group); err != nil {
+			return err
+		}
+		if err := s.checkAlbum(ctx, tx, song.AlbumID); err != nil {
+			return err
+		}
+		if err := s.checkOriginal(ctx, tx, song.CoverOf); err != nil {
+			return err
+		}
+		row := tx.QueryRowContext(ctx, sqlString, args...)
+		err = row.Scan(songDests(&updatedSong, songColumnList)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.missingOrStale(ctx, tx, song.ID, version)
+		}
+		if isUniqueViolation(err) {
+			return ErrSongExists
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.writeVerses(ctx, tx, updatedSong.ID, verses); err != nil {
+			return err
+		}
+		if err = s.deleteOrphanArtists(ctx, tx, previous); err != nil {
+			return err
+		}
+		return s.writeRevision(ctx, tx, model.RevisionUpdate, updatedSong)
+	})
+	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return song, err
 	}
@@ -216,11 +641,185 @@ func (s *Storage) UpdateSong(ctx context.Context, song model.Song) (model.Song,
 	return updatedSong, nil
 }
 
+// PatchSong updates only the fields present in the patch in a single statement.
+// A non-zero version makes the update conditional on the current version.
+func (s *Storage) PatchSong(ctx context.Context, id, version int, patch model.SongPatch) (model.Song, error) {
+	s.logger.Debugw("Patching song", "id", id, "version", version, "patch", patch)
+
+	if patch.Empty() {
+		song, err := s.GetSongByID(ctx, strconv.Itoa(id))
+		if err == nil && version > 0 && song.Version != version {
+			return model.Song{}, ErrVersionMismatch
+		}
+		return song, err
+	}
+
+	query := squirrel.Update("songs").Where(squirrel.Eq{"id": id, "deleted_at": nil}).
+		Set("version", squirrel.Expr("version + 1")).
+		Suffix("RETURNING " + songColumns)
+	if patch.Group != nil {
+		query = query.Set("group_name", *patch.Group).Set("artist_id", artistIDExpr(*patch.Group)).Set("group_key", nameKey(*patch.Group))
+	}
+	if patch.Song != nil {
+		query = query.Set("song", *patch.Song).Set("song_key", nameKey(*patch.Song))
+	}
+	if patch.ReleaseDate != nil {
+		date, err := releaseDateArg(*patch.ReleaseDate)
+		if err != nil {
+			s.logger.Info(zap.Error(err))
+			return model.Song{}, err
+		}
+		query = query.Set("release_date", date)
+	}
+	var verses []model.Verse
+	if patch.Text != nil {
+		verses = splitVerses(*patch.Text)
+		query = query.Set("text", *patch.Text).Set("verse_count", len(verses))
+	}
+	if patch.Link != nil {
+		query = query.Set("link", *patch.Link)
+	}
+	if patch.AlbumID != nil {
+		query = query.Set("album_id", optionalIntArg(*patch.AlbumID))
+	}
+	if patch.Track != nil {
+		query = query.Set("track_number", optionalIntArg(*patch.Track))
+	}
+	if patch.CoverOf != nil {
+		query = query.Set("cover_of", optionalIntArg(*patch.CoverOf))
+	}
+	if version > 0 {
+		query = query.Where(squirrel.Eq{"version": version})
+	}
+
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Song{}, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	var patchedSong model.Song
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		var previous []int
+		if patch.Group != nil {
+			var err error
+			if previous, err = s.artistIDs(ctx, tx, songArtistIDs(id)); err != nil {
+				return err
+			}
+			if err = s.ensureArtist(ctx, tx, *patch.Group); err != nil {
+				return err
+			}
+		}
+		if patch.AlbumID != nil {
+			if err := s.checkAlbum(ctx, tx, *patch.AlbumID); err != nil {
+				return err
+			}
+		}
+		if patch.CoverOf != nil {
+			if err := s.checkOriginal(ctx, tx, *patch.CoverOf); err != nil {
+				return err
+			}
+		}
+		row := tx.QueryRowContext(ctx, sqlString, args...)
+		err := row.Scan(songDests(&patchedSong, songColumnList)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.missingOrStale(ctx, tx, id, version)
+		}
+		if isUniqueViolation(err) {
+			return ErrSongExists
+		}
+		if err != nil {
+			return err
+		}
+		// a track number alone may be patched onto a song without an album
+		if patchedSong.Track != 0 && patchedSong.AlbumID == 0 {
+			return ErrTrackNoAlbum
+		}
+		if patch.Text != nil {
+			if err = s.writeVerses(ctx, tx, patchedSong.ID, verses); err != nil {
+				return err
+			}
+		}
+		if err = s.deleteOrphanArtists(ctx, tx, previous); err != nil {
+			return err
+		}
+		return s.writeRevision(ctx, tx, model.RevisionUpdate, patchedSong)
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Song{}, err
+	}
+	s.logger.Debug("Patched song:", patchedSong)
+
+	return patchedSong, nil
+}
+
+// missingOrStale explains why a conditional write matched no rows: the song is gone or its version moved on.
+func (s *Storage) missingOrStale(ctx context.Context, tx *sql.Tx, id any, version int) error {
+	if version == 0 {
+		return sql.ErrNoRows
+	}
+	sqlString, args, err := squirrel.Select("version").From("songs").Where(squirrel.Eq{"id": id, "deleted_at": nil}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	var current int
+	if err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&current); err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation of Postgres or SQLite.
+// On songs only the group and name of live songs are unique.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	var sqliteErr *sqlitedriver.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
+
+// writeVerses replaces the stored verses of a song.
+func (s *Storage) writeVerses(ctx context.Context, tx *sql.Tx, songID int, verses []model.Verse) error {
+	sqlString, args, err := squirrel.Delete("song_verses").Where(squirrel.Eq{"song_id": songID}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+		return err
+	}
+	if len(verses) == 0 {
+		return nil
+	}
+
+	query := squirrel.Insert("song_verses").Columns("song_id", "position", "kind", "label", "text")
+	for _, verse := range verses {
+		query = query.Values(songID, verse.Number, verse.Type, verse.Label, verse.Text)
+	}
+	sqlString, args, err = query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+	_, err = tx.ExecContext(ctx, sqlString, args...)
+	return err
+}
+
 func (s *Storage) GetSongVerseByID(ctx context.Context, id, verse int) (string, error) {
 	s.logger.Debug("Fetching song verse by ID:", id, "verse:", verse)
 
-	query := squirrel.Select("text").From("songs").Where(squirrel.Eq{"id": id})
-	sqlString, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+	query := squirrel.Select("v.text").From("songs s").
+		LeftJoin("song_verses v ON v.song_id = s.id AND v.position = ?", verse).
+		Where(squirrel.Eq{"s.id": id, "s.deleted_at": nil})
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 		return "", err
@@ -228,39 +827,108 @@ func (s *Storage) GetSongVerseByID(ctx context.Context, id, verse int) (string,
 	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
 
 	row := s.db.QueryRowContext(ctx, sqlString, args...)
-	var text string
+	var text sql.NullString
 	if err := row.Scan(&text); err != nil {
 		s.logger.Info(zap.Error(err))
 		return "", err
 	}
-	s.logger.Debug("Fetched song text:", text)
+	if !text.Valid {
+		s.logger.Info(zap.Error(ErrVerseNotFound))
+		return "", ErrVerseNotFound
+	}
+
+	s.logger.Debug("Fetched verse:", text.String)
+	return text.String, nil
+}
+
+// GetSongVerses returns a page of sections in order together with the number of sections matching the filter.
+// sectionType limits the result to one kind of section, collapse drops choruses repeating an earlier one.
+func (s *Storage) GetSongVerses(ctx context.Context, id int, sectionType string, collapse bool, limit, offset int) ([]model.Verse, int, error) {
+	s.logger.Debugw("Fetching song verses", "id", id, "type", sectionType, "collapse", collapse, "limit", limit, "offset", offset)
+
+	filter := squirrel.And{}
+	if sectionType != "" {
+		filter = append(filter, squirrel.Eq{"v.kind": sectionType})
+	}
+	if collapse {
+		filter = append(filter, squirrel.Expr(`NOT (v.kind = ? AND EXISTS (
+			SELECT 1 FROM song_verses p
+			WHERE p.song_id = v.song_id AND p.kind = v.kind AND p.text = v.text AND p.position < v.position))`, model.SectionChorus))
+	}
 
-	verses := strings.Split(text, "\n\n")
+	count := squirrel.Select("count(*)").From("song_verses v").Where("v.song_id = s.id").Where(filter)
+	sqlString, args, err := squirrel.Select().Column(squirrel.Alias(count, "total")).From("songs s").
+		Where(squirrel.Eq{"s.id": id, "s.deleted_at": nil}).PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, 0, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
 
-	if len(verses) < verse {
-		err = errors.New("verse not found")
+	var total int
+	if err = s.db.QueryRowContext(ctx, sqlString, args...).Scan(&total); err != nil {
 		s.logger.Info(zap.Error(err))
-		return "", err
+		return nil, 0, err
+	}
+
+	query := squirrel.Select("v.position", "v.kind", "v.label", "v.text").From("song_verses v").
+		Where(squirrel.Eq{"v.song_id": id}).Where(filter).
+		OrderBy("v.position").Limit(uint64(limit)).Offset(uint64(offset))
+	sqlString, args, err = query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, 0, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	verses := make([]model.Verse, 0, limit)
+	for rows.Next() {
+		var verse model.Verse
+		if err = rows.Scan(&verse.Number, &verse.Type, &verse.Label, &verse.Text); err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, 0, err
+		}
+		verses = append(verses, verse)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, 0, err
 	}
 
-	s.logger.Debug("Fetched verse:", verses[verse-1])
-	return verses[verse-1], nil
+	s.logger.Debug("Fetched verses:", verses, "total:", total)
+	return verses, total, nil
 }
 
-func (s *Storage) GetInfo(ctx context.Context, group, song string) (model.Song, error) {
-	s.logger.Debug("Fetching song info", "group", group, "song", song)
+// GetInfo returns the details of a song by its group and name, compared by their keys. A song spelled exactly
+// as asked wins over a transliteration. With a prefix or fuzzy match the details of the best matching song are returned.
+func (s *Storage) GetInfo(ctx context.Context, group, song, match string) (model.SongDetail, error) {
+	s.logger.Debug("Fetching song info", "group", group, "song", song, "match", match)
 
-	query := squirrel.Select("release_date", "text", "link").From("songs").Where(squirrel.Eq{"group_name": group, "song": song})
-	sqlString, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if match != "" && match != model.MatchExact {
+		songs, _, err := s.GetSongs(ctx, model.SongFilter{Group: group, Song: song, Match: match}, nil, 1)
+		return songInfo(songs, err)
+	}
+
+	query := squirrel.Select("release_date", "text", "link").From("songs").
+		Where(squirrel.Eq{"group_key": nameKey(group), "song_key": nameKey(song), "deleted_at": nil}).
+		OrderByClause("(group_name = ? AND song = ?) DESC", group, song).OrderBy("id").Limit(1)
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
 	if err != nil {
 		s.logger.Info(zap.Error(err))
-		return model.Song{}, err
+		return model.SongDetail{}, err
 	}
 	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
 
 	row := s.db.QueryRowContext(ctx, sqlString, args...)
-	var res model.Song
-	err = row.Scan(&res.ReleaseDate, &res.Text, &res.Link)
+	var res model.SongDetail
+	err = row.Scan(releaseDate(&res.ReleaseDate), &res.Text, &res.Link)
 	if err != nil {
 		s.logger.Info(zap.Error(err))
 	}
@@ -269,6 +937,90 @@ func (s *Storage) GetInfo(ctx context.Context, group, song string) (model.Song,
 	return res, err
 }
 
+// songInfo picks the details of the first of the songs, sql.ErrNoRows is returned when there are none.
+func songInfo(songs []model.Song, err error) (model.SongDetail, error) {
+	if err != nil {
+		return model.SongDetail{}, err
+	}
+	if len(songs) == 0 {
+		return model.SongDetail{}, sql.ErrNoRows
+	}
+	return model.SongDetail{ReleaseDate: songs[0].ReleaseDate, Text: songs[0].Text, Link: songs[0].Link}, nil
+}
+
+// releaseDateArg converts a release date to a query argument, empty dates are stored as NULL.
+func releaseDateArg(date string) (any, error) {
+	date, err := model.NormalizeDate(date)
+	if err != nil || date == "" {
+		return nil, err
+	}
+	return date, nil
+}
+
+// dateScanner reads a release date into a DateLayout string whether the driver returns a DATE, text or NULL.
+type dateScanner struct {
+	dst *string
+}
+
+func releaseDate(dst *string) *dateScanner {
+	return &dateScanner{dst: dst}
+}
+
+func (d *dateScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d.dst = ""
+	case time.Time:
+		*d.dst = v.Format(model.DateLayout)
+	case string:
+		*d.dst = v
+	case []byte:
+		*d.dst = string(v)
+	default:
+		return fmt.Errorf("unsupported release date type %T", src)
+	}
+	return nil
+}
+
+// intScanner reads a nullable integer column, NULL is read as zero.
+type intScanner struct {
+	dst *int
+}
+
+func optionalInt(dst *int) *intScanner {
+	return &intScanner{dst: dst}
+}
+
+func (n *intScanner) Scan(src any) error {
+	var v sql.NullInt64
+	if err := v.Scan(src); err != nil {
+		return err
+	}
+	*n.dst = int(v.Int64)
+	return nil
+}
+
+// optionalIntArg stores zero as NULL, the counterpart of optionalInt.
+func optionalIntArg(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// inTx runs fn in a transaction that is committed when fn succeeds and rolled back otherwise.
+func (s *Storage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 func (s *Storage) Close() error {
 	s.logger.Debug("Closing database connection...")
 	err := s.db.Close()
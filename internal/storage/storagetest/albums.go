@@ -0,0 +1,247 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func mustAddAlbum(t *testing.T, s storage.IStorage, album model.Album) model.Album {
+	t.Helper()
+	added, err := s.AddAlbum(context.Background(), album)
+	if err != nil {
+		t.Fatalf("AddAlbum(%q, %q): %v", album.Group, album.Title, err)
+	}
+	return added
+}
+
+func albumIDs(albums []model.Album) []int {
+	res := make([]int, 0, len(albums))
+	for _, a := range albums {
+		res = append(res, a.ID)
+	}
+	return res
+}
+
+func testAlbums(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	album := model.Album{Title: "Black Holes and Revelations", Group: group, ReleaseDate: "2006-07-03", CoverLink: "c"}
+	added := mustAddAlbum(t, s, album)
+	if added.ID == 0 || added.ArtistID == 0 {
+		t.Fatalf("AddAlbum = %+v, want an ID and an artist", added)
+	}
+	album.ID, album.ArtistID = added.ID, added.ArtistID
+	if added != album {
+		t.Errorf("AddAlbum = %+v, want %+v", added, album)
+	}
+	if _, err := s.AddAlbum(ctx, model.Album{Title: album.Title, Group: group}); !errors.Is(err, storage.ErrAlbumExists) {
+		t.Errorf("AddAlbum duplicate: got %v, want storage.ErrAlbumExists", err)
+	}
+
+	// the album and the songs of a group share the artist
+	third := mustAdd(t, s, model.Song{Group: group, Song: "Starlight", Link: "l", AlbumID: added.ID, Track: 3})
+	bonus := mustAdd(t, s, model.Song{Group: group, Song: "Bonus", Link: "l", AlbumID: added.ID})
+	first := mustAdd(t, s, model.Song{Group: group, Song: "Take a Bow", Link: "l", AlbumID: added.ID, Track: 1})
+	other := mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Link: "l"})
+	if third.ArtistID != added.ArtistID || third.AlbumID != added.ID || third.Track != 3 {
+		t.Errorf("song on the album = %+v, want artist %d, album %d and track 3", third, added.ArtistID, added.ID)
+	}
+
+	songs, err := s.GetAlbumSongs(ctx, added.ID)
+	if err != nil {
+		t.Fatalf("GetAlbumSongs: %v", err)
+	}
+	if got, want := ids(songs), []int{first.ID, third.ID, bonus.ID}; !equalIDs(got, want) {
+		t.Errorf("GetAlbumSongs = %v, want %v", got, want)
+	}
+	filter := model.SongFilter{Album: added.ID}
+	songs, total, err := s.GetSongs(ctx, filter, nil, 10)
+	if err != nil || total != 3 || !equalIDs(ids(songs), []int{third.ID, bonus.ID, first.ID}) {
+		t.Errorf("GetSongs(%+v) = %v, %d, %v, want %v", filter, ids(songs), total, err, []int{third.ID, bonus.ID, first.ID})
+	}
+
+	// songs in the trash are not counted
+	if err = s.DeleteSong(ctx, strconv.Itoa(bonus.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	album.SongCount = 2
+	if got, err := s.GetAlbum(ctx, added.ID); err != nil || got != album {
+		t.Errorf("GetAlbum = %+v, %v, want %+v", got, err, album)
+	}
+
+	// songs move between albums and leave them
+	moved, err := s.PatchSong(ctx, other.ID, 0, model.SongPatch{AlbumID: &added.ID, Track: ptrInt(2)})
+	if err != nil || moved.AlbumID != added.ID || moved.Track != 2 {
+		t.Errorf("PatchSong onto the album = %+v, %v, want album %d and track 2", moved, err, added.ID)
+	}
+	left, err := s.PatchSong(ctx, first.ID, 0, model.SongPatch{AlbumID: ptrInt(0), Track: ptrInt(0)})
+	if err != nil || left.AlbumID != 0 || left.Track != 0 {
+		t.Errorf("PatchSong off the album = %+v, %v, want no album", left, err)
+	}
+	if songs, err = s.GetAlbumSongs(ctx, added.ID); err != nil || !equalIDs(ids(songs), []int{other.ID, third.ID}) {
+		t.Errorf("GetAlbumSongs after the moves = %v, %v, want %v", ids(songs), err, []int{other.ID, third.ID})
+	}
+
+	if _, err = s.PatchSong(ctx, first.ID, 0, model.SongPatch{Track: ptrInt(4)}); !errors.Is(err, storage.ErrTrackNoAlbum) {
+		t.Errorf("PatchSong of the track of a song without an album: got %v, want storage.ErrTrackNoAlbum", err)
+	}
+
+	missing := added.ID + 1000000
+	if _, err = s.AddSong(ctx, model.Song{Group: group, Song: "Nowhere", Link: "l", AlbumID: missing}); !errors.Is(err, storage.ErrAlbumNotFound) {
+		t.Errorf("AddSong on a missing album: got %v, want storage.ErrAlbumNotFound", err)
+	}
+	update := third
+	update.AlbumID = missing
+	if _, err = s.UpdateSong(ctx, update, 0); !errors.Is(err, storage.ErrAlbumNotFound) {
+		t.Errorf("UpdateSong on a missing album: got %v, want storage.ErrAlbumNotFound", err)
+	}
+	if _, err = s.PatchSong(ctx, third.ID, 0, model.SongPatch{AlbumID: &missing}); !errors.Is(err, storage.ErrAlbumNotFound) {
+		t.Errorf("PatchSong on a missing album: got %v, want storage.ErrAlbumNotFound", err)
+	}
+	if _, err = s.GetAlbum(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetAlbum missing: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.GetAlbumSongs(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetAlbumSongs missing: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testGetAlbums(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	later := mustAddAlbum(t, s, model.Album{Title: "The Resistance", Group: group, ReleaseDate: "2009-09-14"})
+	undated := mustAddAlbum(t, s, model.Album{Title: "Demos", Group: group})
+	earlier := mustAddAlbum(t, s, model.Album{Title: "Absolution", Group: group, ReleaseDate: "2003-09-15"})
+	sameDay := mustAddAlbum(t, s, model.Album{Title: "Absolution Live", Group: group, ReleaseDate: "2003-09-15"})
+	mustAddAlbum(t, s, model.Album{Title: "Absolution", Group: unique("Placebo")})
+
+	// a discography starts with the albums without a release date
+	albums, err := s.GetAlbums(ctx, later.ArtistID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAlbums: %v", err)
+	}
+	want := []int{undated.ID, earlier.ID, sameDay.ID, later.ID}
+	if got := albumIDs(albums); !equalIDs(got, want) {
+		t.Errorf("GetAlbums = %v, want %v", got, want)
+	}
+	if albums, err = s.GetAlbums(ctx, later.ArtistID, 2, 1); err != nil || !equalIDs(albumIDs(albums), want[1:3]) {
+		t.Errorf("GetAlbums(2, 1) = %v, %v, want %v", albumIDs(albums), err, want[1:3])
+	}
+
+	all, err := s.GetAlbums(ctx, 0, 1000000, 0)
+	if err != nil {
+		t.Fatalf("GetAlbums of all groups: %v", err)
+	}
+	found := 0
+	for _, a := range all {
+		if a.ArtistID == later.ArtistID {
+			found++
+		}
+	}
+	if found != len(want) || len(all) <= found {
+		t.Errorf("GetAlbums of all groups has %d of %d albums of the group among %d", found, len(want), len(all))
+	}
+}
+
+func testUpdateAlbum(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	album := mustAddAlbum(t, s, model.Album{Title: "Showbiz", Group: unique("Muse"), ReleaseDate: "1999-10-04"})
+	taken := mustAddAlbum(t, s, model.Album{Title: "Origin of Symmetry", Group: album.Group})
+	song := mustAdd(t, s, model.Song{Group: album.Group, Song: "Sunburn", Link: "l", AlbumID: album.ID, Track: 2})
+
+	// an album can move to another artist, the songs keep theirs
+	update := model.Album{ID: album.ID, Title: "Showbiz (Remastered)", Group: unique("Placebo"), CoverLink: "c"}
+	updated, err := s.UpdateAlbum(ctx, update)
+	if err != nil {
+		t.Fatalf("UpdateAlbum: %v", err)
+	}
+	if updated.ArtistID == 0 || updated.ArtistID == album.ArtistID {
+		t.Errorf("UpdateAlbum artist = %d, want a new one, not %d", updated.ArtistID, album.ArtistID)
+	}
+	update.ArtistID, update.SongCount = updated.ArtistID, 1
+	if updated != update {
+		t.Errorf("UpdateAlbum = %+v, want %+v", updated, update)
+	}
+	if got, err := s.GetSongByID(ctx, strconv.Itoa(song.ID)); err != nil || got != song {
+		t.Errorf("song after UpdateAlbum = %+v, %v, want %+v", got, err, song)
+	}
+
+	if _, err = s.UpdateAlbum(ctx, model.Album{ID: taken.ID, Title: update.Title, Group: update.Group}); !errors.Is(err, storage.ErrAlbumExists) {
+		t.Errorf("UpdateAlbum to a taken title: got %v, want storage.ErrAlbumExists", err)
+	}
+	if _, err = s.UpdateAlbum(ctx, model.Album{ID: album.ID + 1000000, Title: "x", Group: "y"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateAlbum missing: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testDeleteAlbum(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	album := mustAddAlbum(t, s, model.Album{Title: "Drones", Group: unique("Muse")})
+	song := mustAdd(t, s, model.Song{Group: album.Group, Song: "Dead Inside", Link: "l", AlbumID: album.ID, Track: 1})
+	trashed := mustAdd(t, s, model.Song{Group: album.Group, Song: "Psycho", Link: "l", AlbumID: album.ID, Track: 2})
+	if err := s.DeleteSong(ctx, strconv.Itoa(trashed.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	if err := s.DeleteAlbum(ctx, album.ID); err != nil {
+		t.Fatalf("DeleteAlbum: %v", err)
+	}
+	if _, err := s.GetAlbum(ctx, album.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetAlbum after DeleteAlbum: got %v, want sql.ErrNoRows", err)
+	}
+	if err := s.DeleteAlbum(ctx, album.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteAlbum again: got %v, want sql.ErrNoRows", err)
+	}
+
+	// the songs stay without the album as a new version
+	want := song
+	want.AlbumID, want.Track, want.Version = 0, 0, song.Version+1
+	if got, err := s.GetSongByID(ctx, strconv.Itoa(song.ID)); err != nil || got != want {
+		t.Errorf("song after DeleteAlbum = %+v, %v, want %+v", got, err, want)
+	}
+	restored, err := s.RestoreSong(ctx, trashed.ID)
+	if err != nil || restored.AlbumID != 0 || restored.Track != 0 {
+		t.Errorf("restored song = %+v, %v, want no album", restored, err)
+	}
+
+	// a revision from before keeps the album, reverting to it does not bring the album back
+	revision, err := s.GetSongRevision(ctx, song.ID, 1)
+	if err != nil || revision.Song.AlbumID != album.ID || revision.Song.Track != 1 {
+		t.Fatalf("first revision = %+v, %v, want album %d and track 1", revision, err, album.ID)
+	}
+	reverted, err := s.RevertSong(ctx, song.ID, 1, 0)
+	if err != nil || reverted.AlbumID != 0 || reverted.Track != 0 {
+		t.Errorf("RevertSong = %+v, %v, want no album", reverted, err)
+	}
+}
+
+func testMergeArtistsAlbums(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	target := mustAddAlbum(t, s, model.Album{Title: "Absolution", Group: unique("Muse")})
+	moved := mustAddAlbum(t, s, model.Album{Title: "Hullabaloo", Group: unique("MUSE")})
+	clash := mustAddAlbum(t, s, model.Album{Title: target.Title, Group: moved.Group})
+
+	if _, err := s.MergeArtists(ctx, target.ArtistID, moved.ArtistID); !errors.Is(err, storage.ErrAlbumExists) {
+		t.Fatalf("MergeArtists with an album clash: got %v, want storage.ErrAlbumExists", err)
+	}
+	if err := s.DeleteAlbum(ctx, clash.ID); err != nil {
+		t.Fatalf("DeleteAlbum: %v", err)
+	}
+	if _, err := s.MergeArtists(ctx, target.ArtistID, moved.ArtistID); err != nil {
+		t.Fatalf("MergeArtists: %v", err)
+	}
+	albums, err := s.GetAlbums(ctx, target.ArtistID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAlbums: %v", err)
+	}
+	moved.ArtistID, moved.Group = target.ArtistID, target.Group
+	if want := []model.Album{target, moved}; !reflect.DeepEqual(albums, want) {
+		t.Errorf("GetAlbums after the merge = %+v, want %+v", albums, want)
+	}
+}
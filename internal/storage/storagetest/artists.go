@@ -0,0 +1,284 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// findArtist looks an artist up in the list of all artists.
+func findArtist(t *testing.T, s storage.IStorage, id int) (model.Artist, bool) {
+	t.Helper()
+	artists, err := s.GetArtists(context.Background(), 1000000, 0)
+	if err != nil {
+		t.Fatalf("GetArtists: %v", err)
+	}
+	for _, a := range artists {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return model.Artist{}, false
+}
+
+func testArtists(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	first := mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Link: "l"})
+	second := mustAdd(t, s, model.Song{Group: group, Song: "Resistance", Link: "l"})
+	gone := mustAdd(t, s, model.Song{Group: group, Song: "Gone", Link: "l"})
+	if first.ArtistID == 0 || second.ArtistID != first.ArtistID || gone.ArtistID != first.ArtistID {
+		t.Fatalf("artists of songs of one group = %d, %d, %d, want the same one", first.ArtistID, second.ArtistID, gone.ArtistID)
+	}
+	// songs in the trash are not counted
+	if err := s.DeleteSong(ctx, strconv.Itoa(gone.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	want := model.Artist{ID: first.ArtistID, Name: group, SongCount: 2}
+	got, err := s.GetArtist(ctx, want.ID)
+	if err != nil || got != want {
+		t.Fatalf("GetArtist = %+v, %v, want %+v", got, err, want)
+	}
+	if listed, ok := findArtist(t, s, want.ID); !ok || listed != want {
+		t.Errorf("GetArtists lists %+v, want %+v", listed, want)
+	}
+
+	// an artist whose live songs all moved away stays with no songs while one is in the trash
+	if _, err = s.PatchSong(ctx, first.ID, 0, model.SongPatch{Group: ptr(unique("Placebo"))}); err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	if _, err = s.PatchSong(ctx, second.ID, 0, model.SongPatch{Group: ptr(unique("Placebo"))}); err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	if got, err = s.GetArtist(ctx, want.ID); err != nil || got.SongCount != 0 {
+		t.Errorf("GetArtist after the songs moved = %+v, %v, want no songs", got, err)
+	}
+
+	if _, err = s.GetArtist(ctx, want.ID+1000000); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetArtist missing: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+// artistGone checks that an artist was removed once nothing refers to it any more.
+func artistGone(t *testing.T, s storage.IStorage, id int, after string) {
+	t.Helper()
+	if _, err := s.GetArtist(context.Background(), id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetArtist after %s: got %v, want sql.ErrNoRows", after, err)
+	}
+	if a, ok := findArtist(t, s, id); ok {
+		t.Errorf("GetArtists after %s lists %+v", after, a)
+	}
+}
+
+func testOrphanArtists(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+
+	patched := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	if _, err := s.PatchSong(ctx, patched.ID, 0, model.SongPatch{Group: ptr(unique("Placebo"))}); err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	artistGone(t, s, patched.ArtistID, "patching the group of its only song")
+
+	updated := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	moved := updated
+	moved.Group = unique("Placebo")
+	if _, err := s.UpdateSong(ctx, moved, 0); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	artistGone(t, s, updated.ArtistID, "updating the group of its only song")
+
+	reverted := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	renamed, err := s.PatchSong(ctx, reverted.ID, 0, model.SongPatch{Group: ptr(unique("Placebo"))})
+	if err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	if _, err = s.RevertSong(ctx, reverted.ID, 1, 0); err != nil {
+		t.Fatalf("RevertSong: %v", err)
+	}
+	artistGone(t, s, renamed.ArtistID, "reverting its only song to another group")
+
+	// a song in the trash keeps its artist until it is purged
+	trashed := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	if err = s.DeleteSong(ctx, strconv.Itoa(trashed.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if _, err = s.GetArtist(ctx, trashed.ArtistID); err != nil {
+		t.Errorf("GetArtist with a song in the trash: %v", err)
+	}
+	if _, err = s.PurgeTrash(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	artistGone(t, s, trashed.ArtistID, "purging its only song")
+
+	// so does an album
+	album, err := s.AddAlbum(ctx, model.Album{Title: "Absolution", Group: unique("Muse")})
+	if err != nil {
+		t.Fatalf("AddAlbum: %v", err)
+	}
+	if _, err = s.GetArtist(ctx, album.ArtistID); err != nil {
+		t.Errorf("GetArtist with an album: %v", err)
+	}
+	if err = s.DeleteAlbum(ctx, album.ID); err != nil {
+		t.Fatalf("DeleteAlbum: %v", err)
+	}
+	artistGone(t, s, album.ArtistID, "deleting its only album")
+
+	// and a credit
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	credits, err := s.SetSongArtists(ctx, song.ID, []model.Credit{{Name: unique("Rihanna"), Role: model.RoleFeaturing}})
+	if err != nil {
+		t.Fatalf("SetSongArtists: %v", err)
+	}
+	var featured int
+	for _, c := range credits {
+		if c.Role == model.RoleFeaturing {
+			featured = c.ArtistID
+		}
+	}
+	if _, err = s.SetSongArtists(ctx, song.ID, nil); err != nil {
+		t.Fatalf("SetSongArtists: %v", err)
+	}
+	artistGone(t, s, featured, "dropping its only credit")
+	if _, err = s.GetArtist(ctx, song.ArtistID); err != nil {
+		t.Errorf("GetArtist of the group after dropping the credits: %v", err)
+	}
+}
+
+func testArtistsPagination(t *testing.T, s storage.IStorage) {
+	all, err := s.GetArtists(context.Background(), 1000000, 0)
+	if err != nil {
+		t.Fatalf("GetArtists: %v", err)
+	}
+	if len(all) < 3 {
+		mustAdd(t, s, model.Song{Group: unique("A"), Song: "a", Link: "l"})
+		mustAdd(t, s, model.Song{Group: unique("B"), Song: "b", Link: "l"})
+		mustAdd(t, s, model.Song{Group: unique("C"), Song: "c", Link: "l"})
+		if all, err = s.GetArtists(context.Background(), 1000000, 0); err != nil {
+			t.Fatalf("GetArtists: %v", err)
+		}
+	}
+	page, err := s.GetArtists(context.Background(), 2, 1)
+	if err != nil {
+		t.Fatalf("GetArtists page: %v", err)
+	}
+	if len(page) != 2 || page[0] != all[1] || page[1] != all[2] {
+		t.Errorf("GetArtists(2, 1) = %+v, want %+v", page, all[1:3])
+	}
+}
+
+func testRenameArtist(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	trashed := mustAdd(t, s, model.Song{Group: song.Group, Song: "Gone", Link: "l"})
+	if err := s.DeleteSong(ctx, strconv.Itoa(trashed.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	name := unique("Кино")
+	renamed, err := s.RenameArtist(ctx, song.ArtistID, name)
+	if err != nil {
+		t.Fatalf("RenameArtist: %v", err)
+	}
+	if want := (model.Artist{ID: song.ArtistID, Name: name, SongCount: 1}); renamed != want {
+		t.Errorf("RenameArtist = %+v, want %+v", renamed, want)
+	}
+
+	// the songs follow the name as a new version with a revision, their keys too
+	got, err := s.GetSongByID(ctx, strconv.Itoa(song.ID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.Group != name || got.ArtistID != song.ArtistID || got.Version != song.Version+1 {
+		t.Errorf("renamed song = %+v, want group %q, artist %d and version %d", got, name, song.ArtistID, song.Version+1)
+	}
+	revisions, err := s.GetSongRevisions(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongRevisions: %v", err)
+	}
+	if last := revisions[len(revisions)-1]; last.Operation != model.RevisionUpdate || last.Song != got {
+		t.Errorf("last revision = %+v, want an update to %+v", last, got)
+	}
+	songs, _, err := s.GetSongs(ctx, model.SongFilter{Group: "Kino" + name[len("Кино"):]}, nil, 10)
+	if err != nil || !equalIDs(ids(songs), []int{song.ID}) {
+		t.Errorf("GetSongs by the transliterated name = %v, %v, want %v", ids(songs), err, []int{song.ID})
+	}
+	restored, err := s.RestoreSong(ctx, trashed.ID)
+	if err != nil || restored.Group != name {
+		t.Errorf("restored song = %+v, %v, want group %q", restored, err, name)
+	}
+
+	// keeping the name changes nothing
+	if again, err := s.RenameArtist(ctx, song.ArtistID, name); err != nil || again.Name != name {
+		t.Errorf("RenameArtist to the same name = %+v, %v", again, err)
+	}
+	if got, err = s.GetSongByID(ctx, strconv.Itoa(song.ID)); err != nil || got.Version != song.Version+1 {
+		t.Errorf("song after renaming to the same name = %+v, %v, want version %d", got, err, song.Version+1)
+	}
+
+	other := mustAdd(t, s, model.Song{Group: unique("Placebo"), Song: "Bitter End", Link: "l"})
+	if _, err = s.RenameArtist(ctx, song.ArtistID, other.Group); !errors.Is(err, storage.ErrArtistExists) {
+		t.Errorf("RenameArtist to a taken name: got %v, want storage.ErrArtistExists", err)
+	}
+	if _, err = s.RenameArtist(ctx, song.ArtistID+1000000, unique("Ghost")); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RenameArtist missing: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testMergeArtists(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	target := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	moved := mustAdd(t, s, model.Song{Group: unique("MUSE"), Song: "Resistance", Link: "l"})
+	clash := mustAdd(t, s, model.Song{Group: moved.Group, Song: target.Song, Link: "l"})
+
+	// both artists have an Uprising
+	if _, err := s.MergeArtists(ctx, target.ArtistID, moved.ArtistID); !errors.Is(err, storage.ErrSongExists) {
+		t.Fatalf("MergeArtists with a clash: got %v, want storage.ErrSongExists", err)
+	}
+	if got, err := s.GetSongByID(ctx, strconv.Itoa(moved.ID)); err != nil || got != moved {
+		t.Fatalf("song after a failed merge = %+v, %v, want %+v", got, err, moved)
+	}
+
+	// a clash in the trash is fine, restoring it is left to RestoreSong
+	if err := s.DeleteSong(ctx, strconv.Itoa(clash.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	merged, err := s.MergeArtists(ctx, target.ArtistID, moved.ArtistID)
+	if err != nil {
+		t.Fatalf("MergeArtists: %v", err)
+	}
+	if want := (model.Artist{ID: target.ArtistID, Name: target.Group, SongCount: 2}); merged != want {
+		t.Errorf("MergeArtists = %+v, want %+v", merged, want)
+	}
+	got, err := s.GetSongByID(ctx, strconv.Itoa(moved.ID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.Group != target.Group || got.ArtistID != target.ArtistID || got.Version != moved.Version+1 {
+		t.Errorf("merged song = %+v, want group %q, artist %d and version %d", got, target.Group, target.ArtistID, moved.Version+1)
+	}
+	if _, err = s.GetArtist(ctx, moved.ArtistID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetArtist of the merged artist: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.RestoreSong(ctx, clash.ID); !errors.Is(err, storage.ErrSongExists) {
+		t.Errorf("RestoreSong of the clash: got %v, want storage.ErrSongExists", err)
+	}
+
+	// reverting to a revision from before the merge brings the old artist back under a new ID
+	reverted, err := s.RevertSong(ctx, moved.ID, 1, 0)
+	if err != nil {
+		t.Fatalf("RevertSong: %v", err)
+	}
+	if reverted.Group != moved.Group || reverted.ArtistID == 0 || reverted.ArtistID == moved.ArtistID || reverted.ArtistID == target.ArtistID {
+		t.Errorf("reverted song = %+v, want group %q with a new artist", reverted, moved.Group)
+	}
+
+	if _, err = s.MergeArtists(ctx, target.ArtistID, moved.ArtistID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("MergeArtists from a missing artist: got %v, want sql.ErrNoRows", err)
+	}
+}
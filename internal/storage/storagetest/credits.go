@@ -0,0 +1,194 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testSongArtists(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	featuring, producer := unique("Rihanna"), unique("Rich Costey")
+
+	main := model.Credit{ArtistID: song.ArtistID, Name: song.Group, Role: model.RoleMain}
+	got, err := s.GetSongArtists(ctx, song.ID)
+	if err != nil || !slices.Equal(got, []model.Credit{main}) {
+		t.Fatalf("GetSongArtists of a new song = %+v, %v, want only %+v", got, err, main)
+	}
+
+	// repeated credits are kept once, the artists are added by name
+	got, err = s.SetSongArtists(ctx, song.ID, []model.Credit{
+		{Name: producer, Role: model.RoleProducer},
+		{Name: featuring, Role: model.RoleFeaturing},
+		{Name: featuring, Role: model.RoleFeaturing},
+	})
+	if err != nil {
+		t.Fatalf("SetSongArtists: %v", err)
+	}
+	if len(got) != 3 || got[0] != main || got[1].Name != featuring || got[1].Role != model.RoleFeaturing ||
+		got[2].Name != producer || got[2].Role != model.RoleProducer {
+		t.Fatalf("SetSongArtists = %+v, want the group, then %q featuring and %q producing", got, featuring, producer)
+	}
+	for _, c := range got[1:] {
+		if a, err := s.GetArtist(ctx, c.ArtistID); err != nil || a.Name != c.Name {
+			t.Errorf("GetArtist(%d) = %+v, %v, want the artist %q", c.ArtistID, a, err, c.Name)
+		}
+	}
+	if again, err := s.GetSongArtists(ctx, song.ID); err != nil || !slices.Equal(again, got) {
+		t.Errorf("GetSongArtists = %+v, %v, want %+v", again, err, got)
+	}
+
+	// the group of the song follows the song, the other credits stay
+	patched, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{Group: ptr(unique("Placebo"))})
+	if err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	want := append([]model.Credit{{ArtistID: patched.ArtistID, Name: patched.Group, Role: model.RoleMain}}, got[1:]...)
+	if got, err = s.GetSongArtists(ctx, song.ID); err != nil || !slices.Equal(got, want) {
+		t.Errorf("GetSongArtists after a new group = %+v, %v, want %+v", got, err, want)
+	}
+
+	if got, err = s.SetSongArtists(ctx, song.ID, nil); err != nil || len(got) != 1 {
+		t.Errorf("SetSongArtists with no credits = %+v, %v, want only the group", got, err)
+	}
+
+	missing := missingID(t, s)
+	if _, err = s.GetSongArtists(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetSongArtists of a missing song: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.SetSongArtists(ctx, missing, []model.Credit{{Name: featuring, Role: model.RoleFeaturing}}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("SetSongArtists of a missing song: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testGetSongsArtist(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Rihanna")
+	own := mustAdd(t, s, model.Song{Group: group, Song: "Umbrella", Link: "l"})
+	featured := mustAdd(t, s, model.Song{Group: unique("Eminem"), Song: "Love the Way You Lie", Link: "l"})
+	produced := mustAdd(t, s, model.Song{Group: unique("Calvin Harris"), Song: "We Found Love", Link: "l"})
+	other := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+
+	if _, err := s.SetSongArtists(ctx, featured.ID, []model.Credit{{Name: group, Role: model.RoleFeaturing}}); err != nil {
+		t.Fatalf("SetSongArtists: %v", err)
+	}
+	if _, err := s.SetSongArtists(ctx, produced.ID, []model.Credit{{Name: group, Role: model.RoleProducer}}); err != nil {
+		t.Fatalf("SetSongArtists: %v", err)
+	}
+
+	filter := model.SongFilter{Artist: own.ArtistID}
+	songs, total, err := s.GetSongs(ctx, filter, nil, 100)
+	if err != nil {
+		t.Fatalf("GetSongs(%+v): %v", filter, err)
+	}
+	if want := []int{own.ID, featured.ID, produced.ID}; !equalIDs(ids(songs), want) || total != len(want) {
+		t.Errorf("GetSongs(%+v) = %v of %d, want %v", filter, ids(songs), total, want)
+	}
+
+	filter = model.SongFilter{Artist: own.ArtistID, Song: "Umbrella"}
+	if songs, _, err = s.GetSongs(ctx, filter, nil, 100); err != nil || !equalIDs(ids(songs), []int{own.ID}) {
+		t.Errorf("GetSongs(%+v) = %v, %v, want %v", filter, ids(songs), err, []int{own.ID})
+	}
+	filter = model.SongFilter{Artist: other.ArtistID}
+	if songs, _, err = s.GetSongs(ctx, filter, nil, 100); err != nil || !equalIDs(ids(songs), []int{other.ID}) {
+		t.Errorf("GetSongs(%+v) = %v, %v, want %v", filter, ids(songs), err, []int{other.ID})
+	}
+}
+
+func testCovers(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	original := mustAdd(t, s, model.Song{Group: unique("Nine Inch Nails"), Song: "Hurt", Link: "l"})
+	cover := mustAdd(t, s, model.Song{Group: unique("Johnny Cash"), Song: "Hurt", Link: "l", CoverOf: original.ID})
+	if cover.CoverOf != original.ID {
+		t.Fatalf("AddSong cover = %+v, want a cover of %d", cover, original.ID)
+	}
+	other := mustAdd(t, s, model.Song{Group: unique("Leona Lewis"), Song: "Hurt", Link: "l"})
+	other, err := s.PatchSong(ctx, other.ID, 0, model.SongPatch{CoverOf: ptrInt(original.ID)})
+	if err != nil || other.CoverOf != original.ID {
+		t.Fatalf("PatchSong to a cover = %+v, %v, want a cover of %d", other, err, original.ID)
+	}
+
+	covers, err := s.GetSongCovers(ctx, original.ID)
+	if err != nil || !equalIDs(ids(covers), []int{cover.ID, other.ID}) {
+		t.Fatalf("GetSongCovers = %v, %v, want %v", ids(covers), err, []int{cover.ID, other.ID})
+	}
+	if covers, err = s.GetSongCovers(ctx, cover.ID); err != nil || len(covers) != 0 {
+		t.Errorf("GetSongCovers of a song with no covers = %v, %v, want none", ids(covers), err)
+	}
+
+	// a cover of a missing song is refused
+	missing := missingID(t, s)
+	if _, err = s.AddSong(ctx, model.Song{Group: unique("Muse"), Song: "Hurt", Link: "l", CoverOf: missing}); !errors.Is(err, storage.ErrOriginalNotFound) {
+		t.Errorf("AddSong with a missing original: got %v, want storage.ErrOriginalNotFound", err)
+	}
+	updated := other
+	updated.CoverOf = missing
+	if _, err = s.UpdateSong(ctx, updated, 0); !errors.Is(err, storage.ErrOriginalNotFound) {
+		t.Errorf("UpdateSong with a missing original: got %v, want storage.ErrOriginalNotFound", err)
+	}
+	if _, err = s.PatchSong(ctx, other.ID, 0, model.SongPatch{CoverOf: ptrInt(missing)}); !errors.Is(err, storage.ErrOriginalNotFound) {
+		t.Errorf("PatchSong with a missing original: got %v, want storage.ErrOriginalNotFound", err)
+	}
+	if _, err = s.GetSongCovers(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetSongCovers of a missing song: got %v, want sql.ErrNoRows", err)
+	}
+
+	if other, err = s.PatchSong(ctx, other.ID, 0, model.SongPatch{CoverOf: ptrInt(0)}); err != nil || other.CoverOf != 0 {
+		t.Errorf("PatchSong clearing the original = %+v, %v, want no original", other, err)
+	}
+
+	// covers of a song in the trash keep pointing at it until it is purged
+	if err = s.DeleteSong(ctx, strconv.Itoa(original.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if got, err := s.GetSongByID(ctx, strconv.Itoa(cover.ID)); err != nil || got.CoverOf != original.ID {
+		t.Errorf("cover of a song in the trash = %+v, %v, want a cover of %d", got, err, original.ID)
+	}
+	if _, err = s.PurgeTrash(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	got, err := s.GetSongByID(ctx, strconv.Itoa(cover.ID))
+	if err != nil || got.CoverOf != 0 || got.Version != cover.Version+1 {
+		t.Errorf("cover of a purged song = %+v, %v, want no original and version %d", got, err, cover.Version+1)
+	}
+}
+
+func testMergeArtistsCredits(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	target := mustAdd(t, s, model.Song{Group: unique("Jay-Z"), Song: "Run This Town", Link: "l"})
+	moved := mustAdd(t, s, model.Song{Group: unique("JAY-Z"), Song: "Empire State of Mind", Link: "l"})
+	song := mustAdd(t, s, model.Song{Group: unique("Rihanna"), Song: "Umbrella", Link: "l"})
+
+	// the song credits both artists as featuring, after the merge it credits the target once
+	_, err := s.SetSongArtists(ctx, song.ID, []model.Credit{
+		{Name: target.Group, Role: model.RoleFeaturing},
+		{Name: moved.Group, Role: model.RoleFeaturing},
+		{Name: moved.Group, Role: model.RoleProducer},
+	})
+	if err != nil {
+		t.Fatalf("SetSongArtists: %v", err)
+	}
+	if _, err = s.MergeArtists(ctx, target.ArtistID, moved.ArtistID); err != nil {
+		t.Fatalf("MergeArtists: %v", err)
+	}
+
+	got, err := s.GetSongArtists(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongArtists: %v", err)
+	}
+	want := []model.Credit{
+		{ArtistID: song.ArtistID, Name: song.Group, Role: model.RoleMain},
+		{ArtistID: target.ArtistID, Name: target.Group, Role: model.RoleFeaturing},
+		{ArtistID: target.ArtistID, Name: target.Group, Role: model.RoleProducer},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetSongArtists after the merge = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package storagetest
+
+import (
+	"context"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"reflect"
+	"testing"
+)
+
+func testGetSongFacets(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	word := searchWord()
+	muse, placebo := "Muse "+word+" A", "Placebo "+word+" B"
+	mustAdd(t, s, model.Song{Group: muse, Song: "Supermassive Black Hole", ReleaseDate: "2006-06-19", Link: "l"})
+	mustAdd(t, s, model.Song{Group: muse, Song: "Knights of Cydonia", ReleaseDate: "2006-11-27", Link: "l"})
+	mustAdd(t, s, model.Song{Group: muse, Song: "Madness", ReleaseDate: "2012-08-20", Link: "l"})
+	mustAdd(t, s, model.Song{Group: muse, Song: "Unreleased", Link: "l"})
+	mustAdd(t, s, model.Song{Group: placebo, Song: "For What It's Worth", ReleaseDate: "2009-06-08", Link: "l"})
+
+	facets := []string{model.FacetGroup, model.FacetYear, model.FacetDecade}
+	tests := []struct {
+		name   string
+		filter model.SongFilter
+		want   map[string][]model.FacetCount
+	}{
+		// songs without a release date have no year nor decade
+		{"one group", model.SongFilter{Group: muse}, map[string][]model.FacetCount{
+			model.FacetGroup:  {{Value: muse, Count: 4}},
+			model.FacetYear:   {{Value: "2006", Count: 2}, {Value: "2012", Count: 1}},
+			model.FacetDecade: {{Value: "2000", Count: 2}, {Value: "2010", Count: 1}},
+		}},
+		{"prefix", model.SongFilter{Group: "muse " + word + " ", Match: model.MatchPrefix, ReleaseTo: "2010-01-01"}, map[string][]model.FacetCount{
+			model.FacetGroup:  {{Value: muse, Count: 2}},
+			model.FacetYear:   {{Value: "2006", Count: 2}},
+			model.FacetDecade: {{Value: "2000", Count: 2}},
+		}},
+		// equal counts are ordered by value
+		{"both groups", model.SongFilter{ReleaseFrom: "2009-01-01", Query: model.In{Field: model.FieldGroup, Values: []any{muse, placebo}}},
+			map[string][]model.FacetCount{
+				model.FacetGroup:  {{Value: muse, Count: 1}, {Value: placebo, Count: 1}},
+				model.FacetYear:   {{Value: "2009", Count: 1}, {Value: "2012", Count: 1}},
+				model.FacetDecade: {{Value: "2000", Count: 1}, {Value: "2010", Count: 1}},
+			}},
+		{"none", model.SongFilter{Group: muse, ReleaseDate: "2001-01-01"}, map[string][]model.FacetCount{
+			model.FacetGroup:  {},
+			model.FacetYear:   {},
+			model.FacetDecade: {},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetSongFacets(ctx, tt.filter, facets)
+			if err != nil {
+				t.Fatalf("GetSongFacets: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSongFacets(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+
+	got, err := s.GetSongFacets(ctx, model.SongFilter{Group: muse}, []string{model.FacetDecade})
+	if err != nil {
+		t.Fatalf("GetSongFacets: %v", err)
+	}
+	if len(got) != 1 || len(got[model.FacetDecade]) != 2 {
+		t.Errorf("GetSongFacets(decade) = %v, want the decades only", got)
+	}
+}
+
+func testSearchFacets(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	word := searchWord()
+	muse, placebo := unique("Muse"), unique("Placebo")
+	hole := mustAdd(t, s, model.Song{Group: muse, Song: "Supermassive Black Hole", ReleaseDate: "2006-06-19", Link: "l", Text: word + " night"})
+	mustAdd(t, s, model.Song{Group: muse, Song: "Madness", ReleaseDate: "2012-08-20", Link: "l", Text: word + " day"})
+	mustAdd(t, s, model.Song{Group: placebo, Song: "Running Up That Hill", ReleaseDate: "2003-05-26", Link: "l", Text: word})
+	mustAdd(t, s, model.Song{Group: placebo, Song: "Other", Link: "l", Text: "nothing"})
+
+	got, err := s.SearchFacets(ctx, model.SongSearch{Query: word}, []string{model.FacetGroup, model.FacetDecade})
+	if err != nil {
+		t.Fatalf("SearchFacets: %v", err)
+	}
+	want := map[string][]model.FacetCount{
+		model.FacetGroup:  {{Value: muse, Count: 2}, {Value: placebo, Count: 1}},
+		model.FacetDecade: {{Value: "2000", Count: 2}, {Value: "2010", Count: 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchFacets = %v, want %v", got, want)
+	}
+
+	// the facet values are filters of the search too
+	search := model.SongSearch{Query: word, Group: muse, ReleaseFrom: "2000-01-01", ReleaseTo: "2009-12-31"}
+	results, err := s.SearchSongs(ctx, search, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs(%+v): %v", search, err)
+	}
+	if len(results) != 1 || results[0].ID != hole.ID {
+		t.Errorf("SearchSongs(%+v) = %+v, want song %d", search, results, hole.ID)
+	}
+	got, err = s.SearchFacets(ctx, search, []string{model.FacetYear})
+	if err != nil {
+		t.Fatalf("SearchFacets(%+v): %v", search, err)
+	}
+	if want := []model.FacetCount{{Value: "2006", Count: 1}}; !reflect.DeepEqual(got[model.FacetYear], want) {
+		t.Errorf("SearchFacets(%+v) = %v, want %v", search, got, want)
+	}
+}
@@ -0,0 +1,255 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"strings"
+	"testing"
+)
+
+// ownIDs keeps the IDs of the songs a case created, in the order they were returned.
+// Name matches other than exact can also pick up songs of other cases.
+func ownIDs(songs []model.Song, own ...model.Song) []int {
+	res := make([]int, 0, len(own))
+	for _, song := range songs {
+		for _, o := range own {
+			if song.ID == o.ID {
+				res = append(res, song.ID)
+			}
+		}
+	}
+	return res
+}
+
+func testGetSongsPrefix(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	word := searchWord()
+	theMuse := mustAdd(t, s, model.Song{Group: "The Muse " + word, Song: "Uprising", Link: "l"})
+	muse := mustAdd(t, s, model.Song{Group: "Muse " + word, Song: "Starlight", Link: "l"})
+	placebo := mustAdd(t, s, model.Song{Group: "Placebo " + word, Song: "Uprising", Link: "l"})
+	all := []model.Song{theMuse, muse, placebo}
+
+	tests := []struct {
+		filter model.SongFilter
+		want   []int
+	}{
+		{model.SongFilter{Group: "muse " + word, Match: model.MatchPrefix}, []int{muse.ID}},
+		{model.SongFilter{Group: "MUSE " + strings.ToUpper(word), Match: model.MatchPrefix}, []int{muse.ID}},
+		{model.SongFilter{Group: "muse " + word}, []int{muse.ID}},
+		{model.SongFilter{Group: "Muse " + word, Match: model.MatchExact}, []int{muse.ID}},
+		{model.SongFilter{Group: "Muse", Match: model.MatchExact}, []int{}},
+		{model.SongFilter{Group: "Placebo " + word[:4], Song: "upris", Match: model.MatchPrefix}, []int{placebo.ID}},
+		// LIKE wildcards are matched literally
+		{model.SongFilter{Group: "%" + word, Match: model.MatchPrefix}, []int{}},
+		{model.SongFilter{Group: "_use " + word, Match: model.MatchPrefix}, []int{}},
+	}
+	for _, tt := range tests {
+		songs, _, err := s.GetSongs(ctx, tt.filter, nil, 100)
+		if err != nil {
+			t.Fatalf("GetSongs(%+v): %v", tt.filter, err)
+		}
+		if got := ownIDs(songs, all...); !equalIDs(got, tt.want) {
+			t.Errorf("GetSongs(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+		for _, song := range songs {
+			if song.Score != 0 {
+				t.Errorf("GetSongs(%+v) scored song %d with %v, want no score", tt.filter, song.ID, song.Score)
+			}
+		}
+	}
+}
+
+func testGetSongsFuzzy(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	word := searchWord()
+	theMuse := mustAdd(t, s, model.Song{Group: "The Muse " + word, Song: "Uprising", Link: "l"})
+	muse := mustAdd(t, s, model.Song{Group: "Muse " + word, Song: "Starlight", Link: "l", Text: "Far away"})
+	placebo := mustAdd(t, s, model.Song{Group: "Placebo " + word, Song: "Uprising", Link: "l"})
+	all := []model.Song{theMuse, muse, placebo}
+
+	// a typo still matches, the closest names come first
+	filter := model.SongFilter{Group: "musee " + word, Match: model.MatchFuzzy}
+	songs, _, err := s.GetSongs(ctx, filter, nil, 100)
+	if err != nil {
+		t.Fatalf("GetSongs(%+v): %v", filter, err)
+	}
+	if got, want := ownIDs(songs, all...), []int{muse.ID, theMuse.ID, placebo.ID}; !equalIDs(got, want) {
+		t.Fatalf("GetSongs(%+v) = %v, want %v", filter, got, want)
+	}
+	for i, song := range songs {
+		if song.Score <= 0 || song.Score > 1 {
+			t.Errorf("score of song %d = %v, want within (0, 1]", song.ID, song.Score)
+		}
+		if i > 0 && song.Score > songs[i-1].Score {
+			t.Errorf("song %d scored %v comes after a lower score %v", song.ID, song.Score, songs[i-1].Score)
+		}
+	}
+
+	// pages of fuzzy matches follow the score, the cursor carries it
+	var paged []int
+	var after *model.SongCursor
+	for {
+		page, _, err := s.GetSongs(ctx, filter, after, 2)
+		if err != nil {
+			t.Fatalf("GetSongs(%+v, after=%+v): %v", filter, after, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, ids(page)...)
+		last := page[len(page)-1]
+		after = &model.SongCursor{Score: last.Score, ID: last.ID}
+	}
+	if got := ids(songs); !equalIDs(paged, got) {
+		t.Errorf("paged fuzzy matches = %v, want %v", paged, got)
+	}
+
+	// the score of both names is their mean similarity
+	filter = model.SongFilter{Group: "musee " + word, Song: "Uprisng", Match: model.MatchFuzzy}
+	songs, _, err = s.GetSongs(ctx, filter, nil, 100)
+	if err != nil {
+		t.Fatalf("GetSongs(%+v): %v", filter, err)
+	}
+	if got, want := ownIDs(songs, all...), []int{theMuse.ID, placebo.ID}; !equalIDs(got, want) {
+		t.Errorf("GetSongs(%+v) = %v, want %v", filter, got, want)
+	}
+
+	filter = model.SongFilter{Group: "Radiohead", Song: "Starlight", Match: model.MatchFuzzy}
+	songs, _, err = s.GetSongs(ctx, filter, nil, 100)
+	if err != nil {
+		t.Fatalf("GetSongs(%+v): %v", filter, err)
+	}
+	if got := ownIDs(songs, all...); len(got) != 0 {
+		t.Errorf("GetSongs(%+v) = %v, want none of the songs", filter, got)
+	}
+
+	info, err := s.GetInfo(ctx, "Musee "+word, "Starlite", model.MatchFuzzy)
+	if err != nil {
+		t.Fatalf("GetInfo fuzzy: %v", err)
+	}
+	if info.Text != muse.Text {
+		t.Errorf("GetInfo fuzzy = %+v, want the details of %q", info, muse.Song)
+	}
+	if _, err = s.GetInfo(ctx, "Musee "+word, "Starlite", model.MatchExact); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetInfo exact with a typo: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testTransliteration(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	word := searchWord()
+	cyrillic := mustAdd(t, s, model.Song{Group: "Кино " + word, Song: "Группа крови", Link: "l", Text: "Тёплое место"})
+	latin := mustAdd(t, s, model.Song{Group: "Kino " + word, Song: "Gruppa krovi", Link: "l", Text: "Warm place"})
+	star := mustAdd(t, s, model.Song{Group: "Kino " + word, Song: "Zvezda po imeni Solntse", Link: "l"})
+	all := []model.Song{cyrillic, latin, star}
+
+	tests := []struct {
+		filter model.SongFilter
+		want   []int
+	}{
+		{model.SongFilter{Group: "Kino " + word}, []int{cyrillic.ID, latin.ID, star.ID}},
+		{model.SongFilter{Group: "Кино " + word, Song: "Звезда по имени Солнце"}, []int{star.ID}},
+		{model.SongFilter{Group: "Кино " + word, Song: "Gruppa krovi"}, []int{cyrillic.ID, latin.ID}},
+		{model.SongFilter{Song: "группа", Group: "кино " + word, Match: model.MatchPrefix}, []int{cyrillic.ID, latin.ID}},
+		{model.SongFilter{Group: "Кина " + word, Song: "Звезда по имени Сонце", Match: model.MatchFuzzy}, []int{star.ID}},
+	}
+	for _, tt := range tests {
+		songs, _, err := s.GetSongs(ctx, tt.filter, nil, 100)
+		if err != nil {
+			t.Fatalf("GetSongs(%+v): %v", tt.filter, err)
+		}
+		if got := ownIDs(songs, all...); !equalIDs(got, tt.want) {
+			t.Errorf("GetSongs(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	// the exact spelling wins over a transliteration
+	for _, song := range []model.Song{cyrillic, latin} {
+		info, err := s.GetInfo(ctx, song.Group, song.Song, model.MatchExact)
+		if err != nil {
+			t.Fatalf("GetInfo(%q, %q): %v", song.Group, song.Song, err)
+		}
+		if info.Text != song.Text {
+			t.Errorf("GetInfo(%q, %q) = %+v, want text %q", song.Group, song.Song, info, song.Text)
+		}
+	}
+
+	results, err := s.SearchSongs(ctx, model.SongSearch{Query: "звезда " + word}, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != star.ID {
+		t.Errorf("SearchSongs in Cyrillic = %+v, want song %d", results, star.ID)
+	}
+
+	// keys follow the names when they change
+	renamed, err := s.PatchSong(ctx, star.ID, 0, model.SongPatch{Song: ptr("Пачка сигарет")})
+	if err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	filter := model.SongFilter{Group: "Kino " + word, Song: "Pachka sigaret"}
+	songs, _, err := s.GetSongs(ctx, filter, nil, 100)
+	if err != nil {
+		t.Fatalf("GetSongs(%+v): %v", filter, err)
+	}
+	if got := ownIDs(songs, all...); !equalIDs(got, []int{renamed.ID}) {
+		t.Errorf("GetSongs(%+v) = %v, want %v", filter, got, []int{renamed.ID})
+	}
+}
+
+func testNameCase(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	word := searchWord()
+	muse := mustAdd(t, s, model.Song{Group: "Muse " + word, Song: "Starlight", Link: "l", Text: "Far away"})
+	loud := mustAdd(t, s, model.Song{Group: "MUSE " + word, Song: "STARLIGHT", Link: "l", Text: "FAR AWAY"})
+	kino := mustAdd(t, s, model.Song{Group: "Кино " + word, Song: "Группа крови", Link: "l"})
+	all := []model.Song{muse, loud, kino}
+
+	tests := []struct {
+		filter model.SongFilter
+		want   []int
+	}{
+		{model.SongFilter{Group: "muse " + word, Song: "starlight"}, []int{muse.ID, loud.ID}},
+		{model.SongFilter{Group: "mUsE " + strings.ToUpper(word), Match: model.MatchExact}, []int{muse.ID, loud.ID}},
+		{model.SongFilter{Group: "КИНО " + strings.ToUpper(word), Song: "группа КРОВИ"}, []int{kino.ID}},
+		{model.SongFilter{Group: "KINO " + word, Song: "GRUPPA KROVI"}, []int{kino.ID}},
+	}
+	for _, tt := range tests {
+		songs, _, err := s.GetSongs(ctx, tt.filter, nil, 100)
+		if err != nil {
+			t.Fatalf("GetSongs(%+v): %v", tt.filter, err)
+		}
+		if got := ownIDs(songs, all...); !equalIDs(got, tt.want) {
+			t.Errorf("GetSongs(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	info, err := s.GetInfo(ctx, "muse "+word, "starlight", model.MatchExact)
+	if err != nil {
+		t.Fatalf("GetInfo in lower case: %v", err)
+	}
+	if info.Text != muse.Text {
+		t.Errorf("GetInfo in lower case = %+v, want the text of song %d", info, muse.ID)
+	}
+	// the exact spelling wins over another casing
+	for _, song := range []model.Song{muse, loud} {
+		info, err := s.GetInfo(ctx, song.Group, song.Song, model.MatchExact)
+		if err != nil {
+			t.Fatalf("GetInfo(%q, %q): %v", song.Group, song.Song, err)
+		}
+		if info.Text != song.Text {
+			t.Errorf("GetInfo(%q, %q) = %+v, want text %q", song.Group, song.Song, info, song.Text)
+		}
+	}
+
+	results, err := s.SearchSongs(ctx, model.SongSearch{Query: "группа", Group: "кино " + strings.ToUpper(word)}, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != kino.ID {
+		t.Errorf("SearchSongs with the group in another case = %+v, want song %d", results, kino.ID)
+	}
+}
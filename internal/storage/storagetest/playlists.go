@@ -0,0 +1,243 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func mustAddPlaylist(t *testing.T, s storage.IStorage, name string) model.Playlist {
+	t.Helper()
+	added, err := s.AddPlaylist(context.Background(), name)
+	if err != nil {
+		t.Fatalf("AddPlaylist(%q): %v", name, err)
+	}
+	return added
+}
+
+func mustAddEntry(t *testing.T, s storage.IStorage, playlist, song, position int) model.PlaylistEntry {
+	t.Helper()
+	added, err := s.AddPlaylistEntry(context.Background(), playlist, song, position)
+	if err != nil {
+		t.Fatalf("AddPlaylistEntry(%d, %d, %d): %v", playlist, song, position, err)
+	}
+	return added
+}
+
+// entryIDs returns the IDs of the entries of a playlist in order, checking their positions count from 1.
+func entryIDs(t *testing.T, s storage.IStorage, playlist int) []int {
+	t.Helper()
+	entries, err := s.GetPlaylistEntries(context.Background(), playlist)
+	if err != nil {
+		t.Fatalf("GetPlaylistEntries: %v", err)
+	}
+	res := make([]int, 0, len(entries))
+	for i, e := range entries {
+		if e.Position != i+1 {
+			t.Errorf("entry %d is at position %d, want %d", e.ID, e.Position, i+1)
+		}
+		res = append(res, e.ID)
+	}
+	return res
+}
+
+func testPlaylists(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	name := unique("Road trip")
+	added := mustAddPlaylist(t, s, name)
+	if added.ID == 0 || added.Name != name || added.EntryCount != 0 || added.CreatedAt.IsZero() {
+		t.Fatalf("AddPlaylist = %+v, want a new playlist %q with no entries", added, name)
+	}
+	got, err := s.GetPlaylist(ctx, added.ID)
+	if err != nil || got.ID != added.ID || got.Name != name {
+		t.Errorf("GetPlaylist = %+v, %v, want %+v", got, err, added)
+	}
+
+	renamed, err := s.RenamePlaylist(ctx, added.ID, name+" 2")
+	if err != nil || renamed.Name != name+" 2" || renamed.UpdatedAt.Before(added.UpdatedAt) {
+		t.Errorf("RenamePlaylist = %+v, %v, want the name %q", renamed, err, name+" 2")
+	}
+
+	other := mustAddPlaylist(t, s, unique("Workout"))
+	playlists, err := s.GetPlaylists(ctx, 1000000, 0)
+	if err != nil {
+		t.Fatalf("GetPlaylists: %v", err)
+	}
+	var listed []int
+	for _, p := range playlists {
+		if p.ID == added.ID || p.ID == other.ID {
+			listed = append(listed, p.ID)
+		}
+	}
+	if want := []int{added.ID, other.ID}; !equalIDs(listed, want) {
+		t.Errorf("GetPlaylists lists %v, want %v in order", listed, want)
+	}
+
+	// deleting a playlist leaves its songs
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	mustAddEntry(t, s, added.ID, song.ID, 0)
+	if err = s.DeletePlaylist(ctx, added.ID); err != nil {
+		t.Fatalf("DeletePlaylist: %v", err)
+	}
+	if _, err = s.GetPlaylist(ctx, added.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetPlaylist of a deleted playlist: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.GetSongByID(ctx, strconv.Itoa(song.ID)); err != nil {
+		t.Errorf("GetSongByID of a song of a deleted playlist: %v", err)
+	}
+
+	missing := added.ID
+	if err = s.DeletePlaylist(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeletePlaylist of a missing playlist: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.RenamePlaylist(ctx, missing, name); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RenamePlaylist of a missing playlist: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.GetPlaylistEntries(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetPlaylistEntries of a missing playlist: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.AddPlaylistEntry(ctx, missing, song.ID, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("AddPlaylistEntry to a missing playlist: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testPlaylistEntries(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	p := mustAddPlaylist(t, s, unique("Mix"))
+	a := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	b := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Resistance", Link: "l"})
+
+	// a song may be in a playlist twice, entries are appended by default
+	first := mustAddEntry(t, s, p.ID, a.ID, 0)
+	second := mustAddEntry(t, s, p.ID, b.ID, 0)
+	third := mustAddEntry(t, s, p.ID, a.ID, 0)
+	if first.Position != 1 || second.Position != 2 || third.Position != 3 || third.Song.ID != a.ID || first.ID == third.ID {
+		t.Fatalf("entries = %+v, %+v, %+v, want positions 1, 2 and 3 and song %d twice", first, second, third, a.ID)
+	}
+	if third.Song.Song != a.Song {
+		t.Errorf("entry song = %+v, want %+v", third.Song, a)
+	}
+
+	// inserting at a position moves the entries from there down, past the end appends
+	fourth := mustAddEntry(t, s, p.ID, b.ID, 1)
+	if fourth.Position != 1 {
+		t.Errorf("entry added at 1 = %+v, want position 1", fourth)
+	}
+	fifth := mustAddEntry(t, s, p.ID, b.ID, 100)
+	if fifth.Position != 5 {
+		t.Errorf("entry added past the end = %+v, want position 5", fifth)
+	}
+	if got, want := entryIDs(t, s, p.ID), []int{fourth.ID, first.ID, second.ID, third.ID, fifth.ID}; !equalIDs(got, want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+
+	moves := []struct {
+		entry, position int
+		want            []int
+	}{
+		{fifth.ID, 1, []int{fifth.ID, fourth.ID, first.ID, second.ID, third.ID}},
+		{fifth.ID, 3, []int{fourth.ID, first.ID, fifth.ID, second.ID, third.ID}},
+		{fourth.ID, 100, []int{first.ID, fifth.ID, second.ID, third.ID, fourth.ID}},
+		{third.ID, 4, []int{first.ID, fifth.ID, second.ID, third.ID, fourth.ID}},
+		{second.ID, 2, []int{first.ID, second.ID, fifth.ID, third.ID, fourth.ID}},
+	}
+	for _, m := range moves {
+		moved, err := s.MovePlaylistEntry(ctx, p.ID, m.entry, m.position)
+		if err != nil {
+			t.Fatalf("MovePlaylistEntry(%d, %d): %v", m.entry, m.position, err)
+		}
+		if want := min(m.position, len(m.want)); moved.ID != m.entry || moved.Position != want {
+			t.Errorf("MovePlaylistEntry(%d, %d) = %+v, want position %d", m.entry, m.position, moved, want)
+		}
+		if got := entryIDs(t, s, p.ID); !equalIDs(got, m.want) {
+			t.Fatalf("entries after MovePlaylistEntry(%d, %d) = %v, want %v", m.entry, m.position, got, m.want)
+		}
+	}
+
+	if err := s.RemovePlaylistEntry(ctx, p.ID, fifth.ID); err != nil {
+		t.Fatalf("RemovePlaylistEntry: %v", err)
+	}
+	if got, want := entryIDs(t, s, p.ID), []int{first.ID, second.ID, third.ID, fourth.ID}; !equalIDs(got, want) {
+		t.Errorf("entries after RemovePlaylistEntry = %v, want %v", got, want)
+	}
+	if got, err := s.GetPlaylist(ctx, p.ID); err != nil || got.EntryCount != 4 {
+		t.Errorf("GetPlaylist = %+v, %v, want 4 entries", got, err)
+	}
+
+	if err := s.RemovePlaylistEntry(ctx, p.ID, fifth.ID); !errors.Is(err, storage.ErrEntryNotFound) {
+		t.Errorf("RemovePlaylistEntry of a removed entry: got %v, want storage.ErrEntryNotFound", err)
+	}
+	if _, err := s.MovePlaylistEntry(ctx, p.ID, fifth.ID, 1); !errors.Is(err, storage.ErrEntryNotFound) {
+		t.Errorf("MovePlaylistEntry of a removed entry: got %v, want storage.ErrEntryNotFound", err)
+	}
+	// entries belong to their playlist
+	other := mustAddPlaylist(t, s, unique("Other"))
+	if err := s.RemovePlaylistEntry(ctx, other.ID, first.ID); !errors.Is(err, storage.ErrEntryNotFound) {
+		t.Errorf("RemovePlaylistEntry through another playlist: got %v, want storage.ErrEntryNotFound", err)
+	}
+	if _, err := s.AddPlaylistEntry(ctx, p.ID, missingID(t, s), 0); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("AddPlaylistEntry of a missing song: got %v, want storage.ErrSongNotFound", err)
+	}
+}
+
+func testPlaylistDeletedSongs(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	p := mustAddPlaylist(t, s, unique("Mix"))
+	kept := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	gone := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Resistance", Link: "l"})
+	first := mustAddEntry(t, s, p.ID, kept.ID, 0)
+	second := mustAddEntry(t, s, p.ID, gone.ID, 0)
+	third := mustAddEntry(t, s, p.ID, kept.ID, 0)
+
+	// a song in the trash leaves the playlists, the positions close up
+	if err := s.DeleteSong(ctx, strconv.Itoa(gone.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if got, want := entryIDs(t, s, p.ID), []int{first.ID, third.ID}; !equalIDs(got, want) {
+		t.Fatalf("entries with a song in the trash = %v, want %v", got, want)
+	}
+	if got, err := s.GetPlaylist(ctx, p.ID); err != nil || got.EntryCount != 2 {
+		t.Errorf("GetPlaylist = %+v, %v, want 2 entries", got, err)
+	}
+	if _, err := s.MovePlaylistEntry(ctx, p.ID, second.ID, 1); !errors.Is(err, storage.ErrEntryNotFound) {
+		t.Errorf("MovePlaylistEntry of a song in the trash: got %v, want storage.ErrEntryNotFound", err)
+	}
+
+	// positions are counted over the songs shown
+	moved, err := s.MovePlaylistEntry(ctx, p.ID, third.ID, 1)
+	if err != nil || moved.Position != 1 {
+		t.Fatalf("MovePlaylistEntry = %+v, %v, want position 1", moved, err)
+	}
+	added := mustAddEntry(t, s, p.ID, kept.ID, 2)
+	if got, want := entryIDs(t, s, p.ID), []int{third.ID, added.ID, first.ID}; !equalIDs(got, want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+
+	// restoring the song brings its entry back, purging it removes the entry
+	if _, err = s.RestoreSong(ctx, gone.ID); err != nil {
+		t.Fatalf("RestoreSong: %v", err)
+	}
+	if got := entryIDs(t, s, p.ID); len(got) != 4 || !equalIDs(got[:3], []int{third.ID, added.ID, first.ID}) || got[3] != second.ID {
+		t.Errorf("entries after RestoreSong = %v, want the entry %d back last", got, second.ID)
+	}
+	if err = s.DeleteSong(ctx, strconv.Itoa(gone.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if _, err = s.PurgeTrash(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	if _, err = s.RestoreSong(ctx, gone.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("RestoreSong of a purged song: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.RevertSong(ctx, gone.ID, 1, 0); err != nil {
+		t.Fatalf("RevertSong of a purged song: %v", err)
+	}
+	if got, want := entryIDs(t, s, p.ID), []int{third.ID, added.ID, first.ID}; !equalIDs(got, want) {
+		t.Errorf("entries after the purge = %v, want %v", got, want)
+	}
+}
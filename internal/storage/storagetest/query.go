@@ -0,0 +1,85 @@
+package storagetest
+
+import (
+	"context"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/query"
+	"go_test_effective_mobile/internal/storage"
+	"strconv"
+	"testing"
+)
+
+func testGetSongsQuery(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	hole := mustAdd(t, s, model.Song{Group: group, Song: "Supermassive Black Hole", ReleaseDate: "2006-06-19",
+		Text: "Glaciers melting in the dead of night", Link: "https://example.com/hole"})
+	uprising := mustAdd(t, s, model.Song{Group: group, Song: "Uprising", ReleaseDate: "2009-09-07",
+		Text: "They will not force us", Link: "https://example.com/uprising"})
+	undated := mustAdd(t, s, model.Song{Group: group, Song: "Unreleased", Link: "https://example.com/unreleased"})
+	cyrillic := mustAdd(t, s, model.Song{Group: group, Song: "Звезда", ReleaseDate: "2005-01-01",
+		Text: "ЗВЁЗДЫ над городом", Link: "https://example.com/zvezda"})
+
+	tests := []struct {
+		q    string
+		want []int
+	}{
+		{`year>=2006`, []int{hole.ID, uprising.ID}},
+		{`year:2006 OR year:2009`, []int{hole.ID, uprising.ID}},
+		{`year IN (2005, 2009)`, []int{uprising.ID, cyrillic.ID}},
+		{`year:2005..2006`, []int{hole.ID, cyrillic.ID}},
+		{`release_date:2006-01-01..2009-09-06`, []int{hole.ID}},
+		{`release_date<19.06.2006`, []int{cyrillic.ID}},
+		// a song without a release date matches no date comparison, only their negation
+		{`year!=2006`, []int{uprising.ID, cyrillic.ID}},
+		{`NOT year:2006`, []int{uprising.ID, undated.ID, cyrillic.ID}},
+		{`NOT release_date>2000-01-01`, []int{undated.ID}},
+		{`text~"BLACK hole" OR song~"black hole"`, []int{hole.ID}},
+		{`text~"dead of"`, []int{hole.ID}},
+		{`song:up*`, []int{uprising.ID}},
+		{`song:"up*"`, []int{}},
+		{`song:Zvezda`, []int{cyrillic.ID}},
+		{`text~звёзды`, []int{cyrillic.ID}},
+		{`song IN (Uprising, "Supermassive Black Hole")`, []int{hole.ID, uprising.ID}},
+		{`link:"https://example.com/unreleased"`, []int{undated.ID}},
+		{`(song:Uprising OR song:Unreleased) AND NOT year:2009`, []int{undated.ID}},
+		{`NOT song:Uprising AND NOT song:Unreleased`, []int{hole.ID, cyrillic.ID}},
+		{`text:""`, []int{undated.ID}},
+		{`id:` + strconv.Itoa(uprising.ID), []int{uprising.ID}},
+		{`id>` + strconv.Itoa(uprising.ID) + ` AND enriched:false AND version:1`, []int{undated.ID, cyrillic.ID}},
+		{`enriched:true`, []int{}},
+		// LIKE wildcards are matched literally
+		{`text~"%"`, []int{}},
+	}
+	for _, tt := range tests {
+		expr, err := query.Parse(tt.q)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.q, err)
+		}
+		filter := model.SongFilter{Group: group, Query: expr}
+		songs, total, err := s.GetSongs(ctx, filter, nil, 100)
+		if err != nil {
+			t.Fatalf("GetSongs(q=%q): %v", tt.q, err)
+		}
+		if got := ids(songs); !equalIDs(got, tt.want) {
+			t.Errorf("GetSongs(q=%q) = %v, want %v", tt.q, got, tt.want)
+		}
+		if total != len(tt.want) {
+			t.Errorf("GetSongs(q=%q) total = %d, want %d", tt.q, total, len(tt.want))
+		}
+	}
+
+	// the query also applies with names matched in process
+	expr, err := query.Parse(`year<2009`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	filter := model.SongFilter{Group: group[:len(group)-1], Match: model.MatchPrefix, Query: expr}
+	songs, _, err := s.GetSongs(ctx, filter, nil, 100)
+	if err != nil {
+		t.Fatalf("GetSongs(%+v): %v", filter, err)
+	}
+	if got, want := ownIDs(songs, hole, uprising, undated, cyrillic), []int{hole.ID, cyrillic.ID}; !equalIDs(got, want) {
+		t.Errorf("GetSongs(%+v) = %v, want %v", filter, got, want)
+	}
+}
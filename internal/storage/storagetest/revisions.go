@@ -0,0 +1,188 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func operations(revisions []model.SongRevision) []string {
+	res := make([]string, 0, len(revisions))
+	for _, r := range revisions {
+		res = append(res, r.Operation)
+	}
+	return res
+}
+
+func testSongRevisions(t *testing.T, s storage.IStorage) {
+	ctx := storage.WithActor(context.Background(), "editor")
+	added, err := s.AddSong(ctx, model.Song{Group: unique("Muse"), Song: "Uprising", Text: "one\n\ntwo", Link: "l"})
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	updated := added
+	updated.Link = "updated"
+	if _, err = s.UpdateSong(ctx, updated, 0); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	patched, err := s.PatchSong(context.Background(), added.ID, 0, model.SongPatch{Text: ptr("three")})
+	if err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	if err = s.DeleteSong(ctx, strconv.Itoa(added.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	revisions, err := s.GetSongRevisions(context.Background(), added.ID)
+	if err != nil {
+		t.Fatalf("GetSongRevisions: %v", err)
+	}
+	wantOps := []string{model.RevisionCreate, model.RevisionUpdate, model.RevisionUpdate, model.RevisionDelete}
+	if got := operations(revisions); fmt.Sprint(got) != fmt.Sprint(wantOps) {
+		t.Fatalf("revision operations = %v, want %v", got, wantOps)
+	}
+	for i, r := range revisions {
+		if r.Revision != i+1 {
+			t.Errorf("revision %d is numbered %d", i+1, r.Revision)
+		}
+		if r.CreatedAt.IsZero() {
+			t.Errorf("revision %d has no timestamp", r.Revision)
+		}
+	}
+	if revisions[0].Song != added {
+		t.Errorf("create snapshot = %+v, want %+v", revisions[0].Song, added)
+	}
+	if revisions[0].Actor != "editor" || revisions[2].Actor != "" {
+		t.Errorf("actors = %q, %q, want \"editor\" and none", revisions[0].Actor, revisions[2].Actor)
+	}
+	// a delete records the last state of the song
+	if revisions[3].Song != patched {
+		t.Errorf("delete snapshot = %+v, want %+v", revisions[3].Song, patched)
+	}
+
+	got, err := s.GetSongRevision(context.Background(), added.ID, 2)
+	if err != nil {
+		t.Fatalf("GetSongRevision: %v", err)
+	}
+	if got.Song.Link != "updated" || got.Song.Version != 2 {
+		t.Errorf("revision 2 = %+v, want link \"updated\" and version 2", got.Song)
+	}
+	if _, err = s.GetSongRevision(context.Background(), added.ID, 5); !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Errorf("GetSongRevision past the end error = %v, want storage.ErrRevisionNotFound", err)
+	}
+	// deleted songs keep their history, only an id that was never used has none
+	if _, err = s.GetSongRevisions(context.Background(), math.MaxInt32); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetSongRevisions of a song that never existed error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testRevisionDiff(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	original := mustAdd(t, s, model.Song{Group: unique("Cher"), Song: "Bang Bang", Link: "l"})
+	album := mustAddAlbum(t, s, model.Album{Title: "Absolution", Group: group})
+	song := mustAdd(t, s, model.Song{Group: group, Song: "Hysteria", Link: "l"})
+	if _, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{AlbumID: &album.ID, Track: ptrInt(8)}); err != nil {
+		t.Fatalf("PatchSong onto the album: %v", err)
+	}
+	if _, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{AlbumID: ptrInt(0), Track: ptrInt(0)}); err != nil {
+		t.Fatalf("PatchSong off the album: %v", err)
+	}
+	if _, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{CoverOf: &original.ID}); err != nil {
+		t.Fatalf("PatchSong to a cover: %v", err)
+	}
+	if _, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{CoverOf: ptrInt(0)}); err != nil {
+		t.Fatalf("PatchSong clearing the original: %v", err)
+	}
+
+	revisions, err := s.GetSongRevisions(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongRevisions: %v", err)
+	}
+	if len(revisions) != 5 {
+		t.Fatalf("got %d revisions, want 5", len(revisions))
+	}
+	tests := []struct {
+		name     string
+		from, to int
+		want     []model.FieldChange
+	}{
+		{"onto the album", 0, 1, []model.FieldChange{
+			{Field: "albumId", From: 0, To: album.ID},
+			{Field: "track", From: 0, To: 8},
+		}},
+		{"off the album", 1, 2, []model.FieldChange{
+			{Field: "albumId", From: album.ID, To: 0},
+			{Field: "track", From: 8, To: 0},
+		}},
+		{"to a cover", 2, 3, []model.FieldChange{{Field: "coverOf", From: 0, To: original.ID}}},
+		{"clearing the original", 3, 4, []model.FieldChange{{Field: "coverOf", From: original.ID, To: 0}}},
+	}
+	for _, tt := range tests {
+		if got := model.DiffSongs(revisions[tt.from].Song, revisions[tt.to].Song); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("DiffSongs %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testRevertSong(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	added := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Text: "one\n\ntwo", Link: "l"})
+	if _, err := s.PatchSong(ctx, added.ID, 0, model.SongPatch{Text: ptr("three"), Link: ptr("x")}); err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+
+	if _, err := s.RevertSong(ctx, added.ID, 1, 1); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("RevertSong with a stale version error = %v, want storage.ErrVersionMismatch", err)
+	}
+	if _, err := s.RevertSong(ctx, added.ID, 9, 0); !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Fatalf("RevertSong to a missing revision error = %v, want storage.ErrRevisionNotFound", err)
+	}
+
+	reverted, err := s.RevertSong(ctx, added.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("RevertSong: %v", err)
+	}
+	want := added
+	want.Version = 3
+	if reverted != want {
+		t.Fatalf("RevertSong = %+v, want %+v", reverted, want)
+	}
+	verse, err := s.GetSongVerseByID(ctx, added.ID, 2)
+	if err != nil || verse != "two" {
+		t.Fatalf("verse 2 after revert = %q, %v, want \"two\"", verse, err)
+	}
+
+	// a deleted song comes back under its old id
+	if err = s.DeleteSong(ctx, strconv.Itoa(added.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if _, err = s.RevertSong(ctx, added.ID, 2, 3); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("conditional RevertSong of a deleted song error = %v, want storage.ErrVersionMismatch", err)
+	}
+	restored, err := s.RevertSong(ctx, added.ID, 2, 0)
+	if err != nil {
+		t.Fatalf("RevertSong of a deleted song: %v", err)
+	}
+	if restored.ID != added.ID || restored.Link != "x" || restored.Version != 4 || restored.VerseCount != 1 {
+		t.Fatalf("restored song = %+v, want id %d, link \"x\", version 4 and 1 verse", restored, added.ID)
+	}
+	got, err := s.GetSongByID(ctx, strconv.Itoa(added.ID))
+	if err != nil || got != restored {
+		t.Fatalf("GetSongByID after restore = %+v, %v, want %+v", got, err, restored)
+	}
+
+	revisions, err := s.GetSongRevisions(ctx, added.ID)
+	if err != nil {
+		t.Fatalf("GetSongRevisions: %v", err)
+	}
+	if last := revisions[len(revisions)-1]; last.Revision != 5 || last.Operation != model.RevisionRevert || last.Song != restored {
+		t.Fatalf("last revision = %+v, want revision 5 reverting to %+v", last, restored)
+	}
+}
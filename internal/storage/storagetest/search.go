@@ -0,0 +1,133 @@
+package storagetest
+
+import (
+	"context"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// searchWord makes a word no other song contains, so results are not affected by data of other cases.
+func searchWord() string {
+	return "zq" + runID + "x" + strconv.FormatInt(counter.Add(1), 10)
+}
+
+func testSearchSongs(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	word := searchWord()
+	group := unique("Muse")
+	lyricHit := mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Link: "l",
+		Text: "[Verse 1]\nParanoia is in bloom\n\n[Chorus]\nThey will not " + word + " us\nThey will stop degrading us"})
+	titleHit := mustAdd(t, s, model.Song{Group: group, Song: "Song " + word, Link: "l", Text: "Nothing to see here"})
+	mustAdd(t, s, model.Song{Group: group, Song: "Starlight", Link: "l", Text: "Far away\n\nThis ship is taking me"})
+
+	results, err := s.SearchSongs(ctx, model.SongSearch{Query: word}, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	got := make([]int, 0, len(results))
+	for _, r := range results {
+		got = append(got, r.ID)
+	}
+	// a title match outranks a lyrics match
+	if want := []int{titleHit.ID, lyricHit.ID}; !equalIDs(got, want) {
+		t.Fatalf("SearchSongs(%q) = %v, want %v", word, got, want)
+	}
+	if results[0].Rank <= results[1].Rank {
+		t.Errorf("ranks = %v, %v, want the first one higher", results[0].Rank, results[1].Rank)
+	}
+	if results[1].Song != lyricHit {
+		t.Errorf("found song = %+v, want %+v", results[1].Song, lyricHit)
+	}
+
+	match := results[1].Match
+	if match == nil {
+		t.Fatal("lyrics match has no matching verse")
+	}
+	if match.Number != 2 || match.Type != model.SectionChorus || match.Label != "Chorus" {
+		t.Errorf("matching verse = %+v, want the chorus, verse 2", match)
+	}
+	if !strings.Contains(match.Snippet, "<b>"+word+"</b>") {
+		t.Errorf("snippet %q does not highlight %q", match.Snippet, word)
+	}
+
+	// every word has to match, but not necessarily in the same verse
+	results, err = s.SearchSongs(ctx, model.SongSearch{Query: "bloom " + word}, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != lyricHit.ID {
+		t.Errorf("SearchSongs of two words = %+v, want only song %d", results, lyricHit.ID)
+	}
+
+	results, err = s.SearchSongs(ctx, model.SongSearch{Query: word}, 1, 1)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != lyricHit.ID {
+		t.Errorf("second page of SearchSongs = %+v, want song %d", results, lyricHit.ID)
+	}
+
+	if err = s.DeleteSong(ctx, strconv.Itoa(titleHit.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	results, err = s.SearchSongs(ctx, model.SongSearch{Query: word}, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != lyricHit.ID {
+		t.Errorf("SearchSongs after delete = %+v, want only song %d", results, lyricHit.ID)
+	}
+}
+
+func testSearchSnippetEscaped(t *testing.T, s storage.IStorage) {
+	word := searchWord()
+	mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l",
+		Text: "<script>alert(1)</script> they will not " + word + " us & <img src=x onerror=alert(2)>"})
+
+	results, err := s.SearchSongs(context.Background(), model.SongSearch{Query: word}, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 || results[0].Match == nil {
+		t.Fatalf("SearchSongs(%q) = %+v, want one song with a matching verse", word, results)
+	}
+	snippet := results[0].Match.Snippet
+	if !strings.Contains(snippet, "<b>"+word+"</b>") {
+		t.Errorf("snippet %q does not highlight %q", snippet, word)
+	}
+	// only the highlight tags are markup, the lyrics are escaped
+	rest := strings.ReplaceAll(strings.ReplaceAll(snippet, "<b>", ""), "</b>", "")
+	if strings.ContainsAny(rest, "<>") {
+		t.Errorf("snippet %q has unescaped markup of the lyrics", snippet)
+	}
+	if strings.Contains(snippet, "script") && !strings.Contains(snippet, "&lt;script&gt;") {
+		t.Errorf("snippet %q does not escape <script>", snippet)
+	}
+}
+
+func testSearchSongsUpdated(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	before, after := searchWord(), searchWord()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l", Text: "first " + before})
+	if _, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{Text: ptr("first\n\nsecond " + after)}); err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+
+	if results, err := s.SearchSongs(ctx, model.SongSearch{Query: before}, 10, 0); err != nil || len(results) != 0 {
+		t.Errorf("SearchSongs of replaced lyrics = %+v, %v, want nothing", results, err)
+	}
+	results, err := s.SearchSongs(ctx, model.SongSearch{Query: after}, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 || results[0].Match == nil || results[0].Match.Number != 2 {
+		t.Errorf("SearchSongs of new lyrics = %+v, want song %d matching in verse 2", results, song.ID)
+	}
+
+	if results, err = s.SearchSongs(ctx, model.SongSearch{Query: "?!"}, 10, 0); err != nil || len(results) != 0 {
+		t.Errorf("SearchSongs without words = %+v, %v, want nothing", results, err)
+	}
+}
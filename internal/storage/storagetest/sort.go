@@ -0,0 +1,80 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"testing"
+)
+
+func testGetSongsSort(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Кино")
+	// in code point order Ё comes before А, by the rules of Russian it sorts with Е
+	yolka := mustAdd(t, s, model.Song{Group: group, Song: "Ёлка", ReleaseDate: "2001-12-31", Link: "l"})
+	yabloko := mustAdd(t, s, model.Song{Group: group, Song: "Яблоко", Link: "l"})
+	yel := mustAdd(t, s, model.Song{Group: group, Song: "Ель", ReleaseDate: "2001-12-31", Link: "l"})
+	arbuz := mustAdd(t, s, model.Song{Group: group, Song: "Арбуз", ReleaseDate: "2005-06-01", Link: "l"})
+	vishnya := mustAdd(t, s, model.Song{Group: group, Song: "Вишня", ReleaseDate: "2005-06-01", Link: "l"})
+
+	tests := []struct {
+		name string
+		sort []model.SortField
+		want []int
+	}{
+		{"by ID", nil, []int{yolka.ID, yabloko.ID, yel.ID, arbuz.ID, vishnya.ID}},
+		{"by song", []model.SortField{{Field: model.SortSong}}, []int{arbuz.ID, vishnya.ID, yolka.ID, yel.ID, yabloko.ID}},
+		{"by song descending", []model.SortField{{Field: model.SortSong, Desc: true}}, []int{yabloko.ID, yel.ID, yolka.ID, vishnya.ID, arbuz.ID}},
+		// ties are broken by ID, songs without a release date come first
+		{"by release date", []model.SortField{{Field: model.SortReleaseDate}}, []int{yabloko.ID, yolka.ID, yel.ID, arbuz.ID, vishnya.ID}},
+		{"newest first then by song", []model.SortField{{Field: model.SortReleaseDate, Desc: true}, {Field: model.SortSong}},
+			[]int{arbuz.ID, vishnya.ID, yolka.ID, yel.ID, yabloko.ID}},
+		{"by ID descending", []model.SortField{{Field: model.SortID, Desc: true}}, []int{vishnya.ID, arbuz.ID, yel.ID, yabloko.ID, yolka.ID}},
+		{"by group then ID descending", []model.SortField{{Field: model.SortGroup}, {Field: model.SortID, Desc: true}},
+			[]int{vishnya.ID, arbuz.ID, yel.ID, yabloko.ID, yolka.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := model.SongFilter{Group: group, Sort: tt.sort}
+			songs, total, err := s.GetSongs(ctx, filter, nil, 10)
+			if err != nil {
+				t.Fatalf("GetSongs: %v", err)
+			}
+			if got := ids(songs); !equalIDs(got, tt.want) {
+				t.Fatalf("GetSongs(sort=%+v) IDs = %v, want %v", tt.sort, got, tt.want)
+			}
+			if total != len(tt.want) {
+				t.Errorf("total = %d, want %d", total, len(tt.want))
+			}
+
+			// paging one song at a time keeps the order
+			var paged []int
+			var after *model.SongCursor
+			for range tt.want {
+				page, _, err := s.GetSongs(ctx, filter, after, 1)
+				if err != nil {
+					t.Fatalf("GetSongs(after=%+v): %v", after, err)
+				}
+				if len(page) != 1 {
+					t.Fatalf("GetSongs(after=%+v) = %d songs, want 1", after, len(page))
+				}
+				paged = append(paged, page[0].ID)
+				cursor := model.CursorAfter(page[0], tt.sort)
+				after = &cursor
+			}
+			if !equalIDs(paged, tt.want) {
+				t.Errorf("paged IDs = %v, want %v", paged, tt.want)
+			}
+			if rest, _, err := s.GetSongs(ctx, filter, after, 1); err != nil || len(rest) != 0 {
+				t.Errorf("GetSongs after the last song = %v, %v, want no songs", ids(rest), err)
+			}
+		})
+	}
+
+	// a cursor of another sort order
+	filter := model.SongFilter{Group: group, Sort: []model.SortField{{Field: model.SortSong}}}
+	if _, _, err := s.GetSongs(ctx, filter, &model.SongCursor{ID: yolka.ID}, 1); !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("GetSongs with a cursor without the song: got %v, want storage.ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,839 @@
+// Package storagetest is a conformance suite for storage.IStorage implementations.
+// Every backend runs the same cases so their semantics cannot silently diverge.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Factory returns an initialized storage. Run closes it when the case is over.
+// Storages may be shared between cases: the suite only relies on the data it creates itself.
+type Factory func(t *testing.T) storage.IStorage
+
+var (
+	runID   = strconv.FormatInt(time.Now().UnixNano(), 36)
+	counter atomic.Int64
+)
+
+// unique makes names that do not collide with other cases or earlier runs against the same database.
+func unique(base string) string {
+	return fmt.Sprintf("%s %s-%d", base, runID, counter.Add(1))
+}
+
+func Run(t *testing.T, newStorage Factory) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, s storage.IStorage)
+	}{
+		{"Ping", testPing},
+		{"AddSong", testAddSong},
+		{"AddSongDuplicate", testAddSongDuplicate},
+		{"AddSongConcurrent", testAddSongConcurrent},
+		{"GetSongsFilters", testGetSongsFilters},
+		{"GetSongsPagination", testGetSongsPagination},
+		{"GetSongsCursorStable", testGetSongsCursorStable},
+		{"GetSongsFields", testGetSongsFields},
+		{"GetSongsSort", testGetSongsSort},
+		{"GetSongsQuery", testGetSongsQuery},
+		{"GetSongFacets", testGetSongFacets},
+		{"GetSongsPrefix", testGetSongsPrefix},
+		{"GetSongsFuzzy", testGetSongsFuzzy},
+		{"Transliteration", testTransliteration},
+		{"NameCase", testNameCase},
+		{"SearchSongs", testSearchSongs},
+		{"SearchSongsUpdated", testSearchSongsUpdated},
+		{"SearchSnippetEscaped", testSearchSnippetEscaped},
+		{"SearchFacets", testSearchFacets},
+		{"ReleaseDates", testReleaseDates},
+		{"GetSongByIDMissing", testGetSongByIDMissing},
+		{"UpdateSong", testUpdateSong},
+		{"UpdateSongMissing", testUpdateSongMissing},
+		{"UpdateSongDuplicate", testUpdateSongDuplicate},
+		{"PatchSongDuplicate", testPatchSongDuplicate},
+		{"PatchSong", testPatchSong},
+		{"PatchSongMissing", testPatchSongMissing},
+		{"Versions", testVersions},
+		{"DeleteSong", testDeleteSong},
+		{"DeleteSongMissing", testDeleteSongMissing},
+		{"GetSongVerseByID", testGetSongVerseByID},
+		{"GetSongVerseByIDOutOfRange", testGetSongVerseByIDOutOfRange},
+		{"UpdateSongVerses", testUpdateSongVerses},
+		{"GetSongVerses", testGetSongVerses},
+		{"GetSongVersesSections", testGetSongVersesSections},
+		{"GetInfo", testGetInfo},
+		{"SongRevisions", testSongRevisions},
+		{"RevertSong", testRevertSong},
+		{"RevisionDiff", testRevisionDiff},
+		{"Trash", testTrash},
+		{"TrashNameReuse", testTrashNameReuse},
+		{"PurgeTrash", testPurgeTrash},
+		{"Artists", testArtists},
+		{"ArtistsPagination", testArtistsPagination},
+		{"RenameArtist", testRenameArtist},
+		{"MergeArtists", testMergeArtists},
+		{"OrphanArtists", testOrphanArtists},
+		{"Albums", testAlbums},
+		{"GetAlbums", testGetAlbums},
+		{"UpdateAlbum", testUpdateAlbum},
+		{"DeleteAlbum", testDeleteAlbum},
+		{"MergeArtistsAlbums", testMergeArtistsAlbums},
+		{"SongArtists", testSongArtists},
+		{"GetSongsArtist", testGetSongsArtist},
+		{"Covers", testCovers},
+		{"MergeArtistsCredits", testMergeArtistsCredits},
+		{"SongTags", testSongTags},
+		{"Tags", testTags},
+		{"GetSongsTags", testGetSongsTags},
+		{"Playlists", testPlaylists},
+		{"PlaylistEntries", testPlaylistEntries},
+		{"PlaylistDeletedSongs", testPlaylistDeletedSongs},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+			c.fn(t, s)
+		})
+	}
+}
+
+func mustAdd(t *testing.T, s storage.IStorage, song model.Song) model.Song {
+	t.Helper()
+	added, err := s.AddSong(context.Background(), song)
+	if err != nil {
+		t.Fatalf("AddSong(%q, %q): %v", song.Group, song.Song, err)
+	}
+	return added
+}
+
+// missingID returns the ID of a song that existed and was deleted.
+func missingID(t *testing.T, s storage.IStorage) int {
+	t.Helper()
+	song := mustAdd(t, s, model.Song{Group: unique("Ghost"), Song: "Gone", Link: "l"})
+	if err := s.DeleteSong(context.Background(), strconv.Itoa(song.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	return song.ID
+}
+
+func ids(songs []model.Song) []int {
+	res := make([]int, 0, len(songs))
+	for _, song := range songs {
+		res = append(res, song.ID)
+	}
+	return res
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testPing(t *testing.T, s storage.IStorage) {
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func testAddSong(t *testing.T, s storage.IStorage) {
+	want := model.Song{
+		Group:       unique("Muse"),
+		Song:        "Supermassive Black Hole",
+		ReleaseDate: "2006-07-16",
+		Text:        "Ooh baby\n\nI thought I was a fool",
+		Link:        "https://www.youtube.com/watch?v=Xsp3_a-PMTw",
+		Enriched:    true,
+	}
+	added := mustAdd(t, s, want)
+	if added.ID == 0 {
+		t.Fatal("AddSong did not assign an ID")
+	}
+	if added.ArtistID == 0 {
+		t.Fatal("AddSong did not assign an artist")
+	}
+	want.ID = added.ID
+	want.ArtistID = added.ArtistID
+	want.VerseCount = 2
+	want.Version = 1
+	if added != want {
+		t.Fatalf("AddSong = %+v, want %+v", added, want)
+	}
+
+	got, err := s.GetSongByID(context.Background(), strconv.Itoa(added.ID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got != want {
+		t.Fatalf("GetSongByID = %+v, want %+v", got, want)
+	}
+}
+
+func testAddSongDuplicate(t *testing.T, s storage.IStorage) {
+	group := unique("Muse")
+	mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Link: "l"})
+
+	_, err := s.AddSong(context.Background(), model.Song{Group: group, Song: "Uprising", Link: "other"})
+	if !errors.Is(err, storage.ErrSongExists) {
+		t.Fatalf("duplicate AddSong error = %v, want storage.ErrSongExists", err)
+	}
+
+	// the same title by another group is a different song
+	mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+}
+
+func testAddSongConcurrent(t *testing.T, s storage.IStorage) {
+	group := unique("Muse")
+	const n = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.AddSong(context.Background(), model.Song{Group: group, Song: strconv.Itoa(i), Link: "l"})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent AddSong: %v", err)
+		}
+	}
+
+	songs, _, err := s.GetSongs(context.Background(), model.SongFilter{Group: group}, nil, n+1)
+	if err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	seen := make(map[int]bool)
+	for _, song := range songs {
+		seen[song.ID] = true
+	}
+	if len(songs) != n || len(seen) != n {
+		t.Fatalf("got %d songs with %d distinct IDs, want %d", len(songs), len(seen), n)
+	}
+}
+
+func testGetSongsFilters(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	muse, radiohead := unique("Muse"), unique("Radiohead")
+	a := mustAdd(t, s, model.Song{Group: muse, Song: "Uprising", ReleaseDate: "2009-09-07", Link: "l"})
+	b := mustAdd(t, s, model.Song{Group: muse, Song: "Starlight", ReleaseDate: "2006-09-04", Link: "l"})
+	c := mustAdd(t, s, model.Song{Group: radiohead, Song: "Creep", ReleaseDate: "1992-09-21", Link: "l"})
+	d := mustAdd(t, s, model.Song{Group: radiohead, Song: "Starlight", ReleaseDate: "2006-09-04", Link: "l"})
+
+	e := mustAdd(t, s, model.Song{Group: muse, Song: "Unreleased", Link: "l"})
+
+	tests := []struct {
+		name   string
+		filter model.SongFilter
+		want   []int
+	}{
+		{"group", model.SongFilter{Group: muse}, []int{a.ID, b.ID, e.ID}},
+		{"group and song", model.SongFilter{Group: radiohead, Song: "Creep"}, []int{c.ID}},
+		{"group and release date", model.SongFilter{Group: radiohead, ReleaseDate: "2006-09-04"}, []int{d.ID}},
+		{"all fields", model.SongFilter{Group: muse, Song: "Starlight", ReleaseDate: "2006-09-04"}, []int{b.ID}},
+		{"no match", model.SongFilter{Group: muse, Song: "Creep"}, []int{}},
+		{"released from", model.SongFilter{Group: muse, ReleaseFrom: "2006-09-05"}, []int{a.ID}},
+		{"released from inclusive", model.SongFilter{Group: muse, ReleaseFrom: "2006-09-04"}, []int{a.ID, b.ID}},
+		{"released to inclusive", model.SongFilter{Group: radiohead, ReleaseTo: "2006-09-04"}, []int{c.ID, d.ID}},
+		{"release range", model.SongFilter{Group: muse, ReleaseFrom: "2006-01-01", ReleaseTo: "2006-12-31"}, []int{b.ID}},
+		{"empty range", model.SongFilter{Group: muse, ReleaseFrom: "2010-01-01", ReleaseTo: "2009-01-01"}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			songs, _, err := s.GetSongs(ctx, tt.filter, nil, 10)
+			if err != nil {
+				t.Fatalf("GetSongs: %v", err)
+			}
+			if songs == nil {
+				t.Fatal("GetSongs returned a nil slice")
+			}
+			if got := ids(songs); !equalIDs(got, tt.want) {
+				t.Fatalf("GetSongs IDs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testReleaseDates(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{"canonical", "2006-07-16", "2006-07-16"},
+		{"upstream format", "16.07.2006", "2006-07-16"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Starlight", ReleaseDate: tt.in, Link: "l"})
+			if song.ReleaseDate != tt.want {
+				t.Fatalf("AddSong release date = %q, want %q", song.ReleaseDate, tt.want)
+			}
+			got, err := s.GetSongByID(ctx, strconv.Itoa(song.ID))
+			if err != nil {
+				t.Fatalf("GetSongByID: %v", err)
+			}
+			if got.ReleaseDate != tt.want {
+				t.Fatalf("GetSongByID release date = %q, want %q", got.ReleaseDate, tt.want)
+			}
+			info, err := s.GetInfo(ctx, song.Group, song.Song, model.MatchExact)
+			if err != nil {
+				t.Fatalf("GetInfo: %v", err)
+			}
+			if info.ReleaseDate != tt.want {
+				t.Fatalf("GetInfo release date = %q, want %q", info.ReleaseDate, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := s.AddSong(ctx, model.Song{Group: unique("Muse"), Song: "Starlight", ReleaseDate: "2006-02-31", Link: "l"}); err == nil {
+			t.Fatal("AddSong with an invalid release date succeeded")
+		}
+		song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Starlight", Link: "l"})
+		song.ReleaseDate = "someday"
+		if _, err := s.UpdateSong(ctx, song, 0); err == nil {
+			t.Fatal("UpdateSong with an invalid release date succeeded")
+		}
+	})
+}
+
+func testGetSongsPagination(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	var all []int
+	for i := 0; i < 5; i++ {
+		all = append(all, mustAdd(t, s, model.Song{Group: group, Song: strconv.Itoa(i), Link: "l"}).ID)
+	}
+	filter := model.SongFilter{Group: group}
+
+	tests := []struct {
+		name  string
+		after *model.SongCursor
+		limit int
+		want  []int
+	}{
+		{"first page", nil, 2, all[0:2]},
+		{"middle page", &model.SongCursor{ID: all[1]}, 2, all[2:4]},
+		{"last partial page", &model.SongCursor{ID: all[3]}, 2, all[4:]},
+		{"cursor at the end", &model.SongCursor{ID: all[4]}, 2, []int{}},
+		{"limit larger than the set", nil, 10, all},
+		{"limit one", &model.SongCursor{ID: all[2]}, 1, all[3:4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			songs, total, err := s.GetSongs(ctx, filter, tt.after, tt.limit)
+			if err != nil {
+				t.Fatalf("GetSongs: %v", err)
+			}
+			if got := ids(songs); !equalIDs(got, tt.want) {
+				t.Fatalf("GetSongs(after=%+v, limit=%d) IDs = %v, want %v", tt.after, tt.limit, got, tt.want)
+			}
+			if total != len(all) {
+				t.Errorf("total = %d, want %d", total, len(all))
+			}
+		})
+	}
+}
+
+// testGetSongsCursorStable pages through songs that change in between: nothing is repeated or skipped.
+func testGetSongsCursorStable(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	var all []int
+	for i := 0; i < 4; i++ {
+		all = append(all, mustAdd(t, s, model.Song{Group: group, Song: strconv.Itoa(i), Link: "l"}).ID)
+	}
+	filter := model.SongFilter{Group: group}
+
+	first, _, err := s.GetSongs(ctx, filter, nil, 2)
+	if err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	// an offset would now skip all[2] and then repeat the added song
+	if err = s.DeleteSong(ctx, strconv.Itoa(all[0]), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	added := mustAdd(t, s, model.Song{Group: group, Song: "added", Link: "l"})
+
+	after := &model.SongCursor{ID: first[len(first)-1].ID}
+	rest, total, err := s.GetSongs(ctx, filter, after, 10)
+	if err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	if want := []int{all[2], all[3], added.ID}; !equalIDs(ids(rest), want) {
+		t.Errorf("next page IDs = %v, want %v", ids(rest), want)
+	}
+	if total != 4 {
+		t.Errorf("total = %d, want 4", total)
+	}
+}
+
+func testGetSongsFields(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	old := mustAdd(t, s, model.Song{Group: group, Song: "Uprising", ReleaseDate: "2009-09-07", Text: "a", Link: "l"})
+	recent := mustAdd(t, s, model.Song{Group: group, Song: "Madness", ReleaseDate: "2012-08-20", Text: "b", Link: "l"})
+	undated := mustAdd(t, s, model.Song{Group: group, Song: "Dead Inside", Text: "c", Link: "l"})
+
+	// the cursor still gets the sort values of fields that were not asked for
+	sort := []model.SortField{{Field: model.SortReleaseDate, Desc: true}}
+	filter := model.SongFilter{Group: group, Sort: sort, Fields: []string{"song", "text"}}
+	var paged []model.Song
+	var after *model.SongCursor
+	for range 3 {
+		page, _, err := s.GetSongs(ctx, filter, after, 1)
+		if err != nil {
+			t.Fatalf("GetSongs(after=%+v): %v", after, err)
+		}
+		if len(page) != 1 {
+			t.Fatalf("GetSongs(after=%+v) = %d songs, want 1", after, len(page))
+		}
+		paged = append(paged, page[0])
+		cursor := model.CursorAfter(page[0], sort)
+		after = &cursor
+	}
+	want := []model.Song{recent, old, undated}
+	if !equalIDs(ids(paged), ids(want)) {
+		t.Fatalf("paged IDs = %v, want %v", ids(paged), ids(want))
+	}
+	for i, song := range paged {
+		if song.Song != want[i].Song || song.Text != want[i].Text {
+			t.Errorf("song %d = %q with text %q, want %q with text %q", song.ID, song.Song, song.Text, want[i].Song, want[i].Text)
+		}
+	}
+}
+
+func testGetSongByIDMissing(t *testing.T, s storage.IStorage) {
+	id := missingID(t, s)
+	_, err := s.GetSongByID(context.Background(), strconv.Itoa(id))
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSongByID error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testUpdateSong(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Text: "a", Link: "l", Enriched: true})
+
+	update := model.Song{ID: song.ID, Group: unique("Muse"), Song: "Resistance", ReleaseDate: "2009-09-14", Text: "b\n\nc", Link: "m"}
+	updated, err := s.UpdateSong(ctx, update, 0)
+	if err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	// a new group is a new artist
+	if updated.ArtistID == 0 || updated.ArtistID == song.ArtistID {
+		t.Errorf("UpdateSong artist = %d, want a new one, not %d", updated.ArtistID, song.ArtistID)
+	}
+	want := update
+	want.ArtistID = updated.ArtistID
+	want.Enriched = song.Enriched
+	want.VerseCount = 2
+	want.Version = song.Version + 1
+	if updated != want {
+		t.Fatalf("UpdateSong = %+v, want %+v", updated, want)
+	}
+
+	got, err := s.GetSongByID(ctx, strconv.Itoa(song.ID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got != want {
+		t.Fatalf("GetSongByID after update = %+v, want %+v", got, want)
+	}
+}
+
+func testUpdateSongMissing(t *testing.T, s storage.IStorage) {
+	id := missingID(t, s)
+	_, err := s.UpdateSong(context.Background(), model.Song{ID: id, Group: unique("Muse"), Song: "x", Link: "l"}, 0)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("UpdateSong error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testUpdateSongDuplicate(t *testing.T, s storage.IStorage) {
+	group := unique("Muse")
+	mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Link: "l"})
+	other := mustAdd(t, s, model.Song{Group: group, Song: "Resistance", Link: "l"})
+
+	other.Song = "Uprising"
+	if _, err := s.UpdateSong(context.Background(), other, 0); !errors.Is(err, storage.ErrSongExists) {
+		t.Fatalf("UpdateSong onto an existing group and song error = %v, want storage.ErrSongExists", err)
+	}
+}
+
+func testPatchSongDuplicate(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Link: "l"})
+	other := mustAdd(t, s, model.Song{Group: group, Song: "Resistance", Link: "l"})
+	moved := mustAdd(t, s, model.Song{Group: unique("Placebo"), Song: "Uprising", Link: "l"})
+
+	if _, err := s.PatchSong(ctx, other.ID, 0, model.SongPatch{Song: ptr("Uprising")}); !errors.Is(err, storage.ErrSongExists) {
+		t.Errorf("PatchSong rename onto an existing song error = %v, want storage.ErrSongExists", err)
+	}
+	if _, err := s.PatchSong(ctx, moved.ID, 0, model.SongPatch{Group: ptr(group)}); !errors.Is(err, storage.ErrSongExists) {
+		t.Errorf("PatchSong group change onto an existing song error = %v, want storage.ErrSongExists", err)
+	}
+	got, err := s.GetSongByID(ctx, strconv.Itoa(other.ID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.Song != "Resistance" || got.Version != other.Version {
+		t.Errorf("song after a rejected patch = %+v, want it unchanged", got)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func ptrInt(n int) *int {
+	return &n
+}
+
+func testPatchSong(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{
+		Group: unique("Muse"), Song: "Uprising", ReleaseDate: "2009-09-07", Text: "one\n\ntwo", Link: "l", Enriched: true,
+	})
+
+	patched, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{Link: ptr("https://example.com")})
+	if err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	want := song
+	want.Link = "https://example.com"
+	want.Version = 2
+	if patched != want {
+		t.Fatalf("PatchSong link only = %+v, want %+v", patched, want)
+	}
+
+	patched, err = s.PatchSong(ctx, song.ID, 0, model.SongPatch{ReleaseDate: ptr(""), Text: ptr("only verse")})
+	if err != nil {
+		t.Fatalf("PatchSong: %v", err)
+	}
+	want.ReleaseDate, want.Text, want.VerseCount, want.Version = "", "only verse", 1, 3
+	if patched != want {
+		t.Fatalf("PatchSong clearing date = %+v, want %+v", patched, want)
+	}
+	if verse, err := s.GetSongVerseByID(ctx, song.ID, 1); err != nil || verse != "only verse" {
+		t.Fatalf("GetSongVerseByID after patch = %q, %v", verse, err)
+	}
+
+	got, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{})
+	if err != nil {
+		t.Fatalf("empty PatchSong: %v", err)
+	}
+	if got != want {
+		t.Fatalf("empty PatchSong = %+v, want %+v", got, want)
+	}
+
+	other := mustAdd(t, s, model.Song{Group: song.Group, Song: "Resistance", Link: "l"})
+	if _, err = s.PatchSong(ctx, other.ID, 0, model.SongPatch{Song: ptr("Uprising")}); err == nil {
+		t.Fatal("PatchSong onto an existing group and song succeeded")
+	}
+}
+
+func testPatchSongMissing(t *testing.T, s storage.IStorage) {
+	id := missingID(t, s)
+	if _, err := s.PatchSong(context.Background(), id, 0, model.SongPatch{Link: ptr("l")}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("PatchSong error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.PatchSong(context.Background(), id, 0, model.SongPatch{}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("empty PatchSong error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testVersions(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	if song.Version != 1 {
+		t.Fatalf("new song version = %d, want 1", song.Version)
+	}
+
+	updated, err := s.UpdateSong(ctx, song, 1)
+	if err != nil {
+		t.Fatalf("UpdateSong with the current version: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("version after update = %d, want 2", updated.Version)
+	}
+
+	if _, err = s.UpdateSong(ctx, song, 1); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("UpdateSong with a stale version error = %v, want storage.ErrVersionMismatch", err)
+	}
+	if _, err = s.PatchSong(ctx, song.ID, 1, model.SongPatch{Link: ptr("x")}); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("PatchSong with a stale version error = %v, want storage.ErrVersionMismatch", err)
+	}
+	if _, err = s.PatchSong(ctx, song.ID, 1, model.SongPatch{}); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("empty PatchSong with a stale version error = %v, want storage.ErrVersionMismatch", err)
+	}
+	if err = s.DeleteSong(ctx, strconv.Itoa(song.ID), 1); !errors.Is(err, storage.ErrVersionMismatch) {
+		t.Fatalf("DeleteSong with a stale version error = %v, want storage.ErrVersionMismatch", err)
+	}
+
+	got, err := s.GetSongByID(ctx, strconv.Itoa(song.ID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got != updated {
+		t.Fatalf("stale writes changed the song: %+v, want %+v", got, updated)
+	}
+
+	patched, err := s.PatchSong(ctx, song.ID, 2, model.SongPatch{Link: ptr("x")})
+	if err != nil {
+		t.Fatalf("PatchSong with the current version: %v", err)
+	}
+	if patched.Version != 3 {
+		t.Fatalf("version after patch = %d, want 3", patched.Version)
+	}
+	if err = s.DeleteSong(ctx, strconv.Itoa(song.ID), 3); err != nil {
+		t.Fatalf("DeleteSong with the current version: %v", err)
+	}
+
+	// a missing song is reported as missing even when a version is given
+	if _, err = s.UpdateSong(ctx, song, 3); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("UpdateSong of a deleted song error = %v, want sql.ErrNoRows", err)
+	}
+	if err = s.DeleteSong(ctx, strconv.Itoa(song.ID), 3); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("DeleteSong of a deleted song error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testDeleteSong(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+
+	if err := s.DeleteSong(ctx, strconv.Itoa(song.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if _, err := s.GetSongByID(ctx, strconv.Itoa(song.ID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSongByID after delete error = %v, want sql.ErrNoRows", err)
+	}
+
+	// the name is free again
+	mustAdd(t, s, model.Song{Group: song.Group, Song: song.Song, Link: "l"})
+}
+
+func testDeleteSongMissing(t *testing.T, s storage.IStorage) {
+	id := missingID(t, s)
+	if err := s.DeleteSong(context.Background(), strconv.Itoa(id), 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("DeleteSong error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testGetSongVerseByID(t *testing.T, s storage.IStorage) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"blank line", "first line\nsecond line\n\nchorus", []string{"first line\nsecond line", "chorus"}},
+		{"crlf", "first line\r\nsecond line\r\n\r\nchorus\r\n", []string{"first line\nsecond line", "chorus"}},
+		{"several blank lines", "\n\nfirst\n\n\n\nsecond\n\n\n", []string{"first", "second"}},
+		{"whitespace only lines", "first  \n \t \nsecond\t\n   ", []string{"first", "second"}},
+		{"single verse", "only verse", []string{"only verse"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Text: tt.text, Link: "l"})
+			if song.VerseCount != len(tt.want) {
+				t.Fatalf("VerseCount = %d, want %d", song.VerseCount, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got, err := s.GetSongVerseByID(ctx, song.ID, i+1)
+				if err != nil {
+					t.Fatalf("GetSongVerseByID(%d): %v", i+1, err)
+				}
+				if got != want {
+					t.Fatalf("GetSongVerseByID(%d) = %q, want %q", i+1, got, want)
+				}
+			}
+			if _, err := s.GetSongVerseByID(ctx, song.ID, len(tt.want)+1); !errors.Is(err, storage.ErrVerseNotFound) {
+				t.Fatalf("GetSongVerseByID past the last verse error = %v, want storage.ErrVerseNotFound", err)
+			}
+		})
+	}
+}
+
+func testUpdateSongVerses(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Text: "one\n\ntwo\n\nthree", Link: "l"})
+
+	song.Text = "new one"
+	updated, err := s.UpdateSong(ctx, song, 0)
+	if err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if updated.VerseCount != 1 {
+		t.Fatalf("VerseCount after update = %d, want 1", updated.VerseCount)
+	}
+	if got, err := s.GetSongVerseByID(ctx, song.ID, 1); err != nil || got != "new one" {
+		t.Fatalf("GetSongVerseByID(1) = %q, %v, want %q", got, err, "new one")
+	}
+	if _, err = s.GetSongVerseByID(ctx, song.ID, 2); !errors.Is(err, storage.ErrVerseNotFound) {
+		t.Fatalf("stale verse 2 error = %v, want storage.ErrVerseNotFound", err)
+	}
+}
+
+func testGetSongVerseByIDOutOfRange(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Text: "one\n\ntwo", Link: "l"})
+
+	for _, verse := range []int{0, 3, 100} {
+		if _, err := s.GetSongVerseByID(ctx, song.ID, verse); !errors.Is(err, storage.ErrVerseNotFound) {
+			t.Fatalf("GetSongVerseByID(%d) error = %v, want storage.ErrVerseNotFound", verse, err)
+		}
+	}
+
+	id := missingID(t, s)
+	if _, err := s.GetSongVerseByID(ctx, id, 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSongVerseByID on a missing song error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testGetInfo(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", ReleaseDate: "2009-09-07", Text: "text", Link: "link"})
+
+	got, err := s.GetInfo(ctx, song.Group, song.Song, model.MatchExact)
+	if err != nil {
+		t.Fatalf("GetInfo: %v", err)
+	}
+	want := model.SongDetail{ReleaseDate: "2009-09-07", Text: "text", Link: "link"}
+	if got != want {
+		t.Fatalf("GetInfo = %+v, want %+v", got, want)
+	}
+
+	if _, err = s.GetInfo(ctx, song.Group, "Resistance", model.MatchExact); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetInfo on a missing song error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testGetSongVerses(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Text: "1\n\n2\n\n3\n\n4\n\n5", Link: "l"})
+
+	tests := []struct {
+		name          string
+		limit, offset int
+		want          []int
+	}{
+		{"first page", 2, 0, []int{1, 2}},
+		{"last partial page", 2, 4, []int{5}},
+		{"past the end", 2, 6, []int{}},
+		{"everything", 10, 0, []int{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verses, total, err := s.GetSongVerses(ctx, song.ID, "", false, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("GetSongVerses: %v", err)
+			}
+			if total != 5 {
+				t.Fatalf("total = %d, want 5", total)
+			}
+			if len(verses) != len(tt.want) {
+				t.Fatalf("got %d verses, want %d", len(verses), len(tt.want))
+			}
+			for i, n := range tt.want {
+				if verses[i].Number != n || verses[i].Text != strconv.Itoa(n) {
+					t.Fatalf("verse %d = %+v, want number and text %d", i, verses[i], n)
+				}
+			}
+		})
+	}
+
+	id := missingID(t, s)
+	if _, _, err := s.GetSongVerses(ctx, id, "", false, 2, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSongVerses on a missing song error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testGetSongVersesSections(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	text := "[Intro]\nooh\n\n[Verse 1]\nfirst\n[Chorus]\nla la\n\n" +
+		"second\n\n[Chorus]\nla la\n\n[Bridge]\n\nbridge\n\n[Chorus x2]\nla la la\n\n[Припев]\nla la"
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Text: text, Link: "l"})
+
+	all := []model.Verse{
+		{Number: 1, Type: model.SectionIntro, Label: "Intro", Text: "ooh"},
+		{Number: 2, Type: model.SectionVerse, Label: "Verse 1", Text: "first"},
+		{Number: 3, Type: model.SectionChorus, Label: "Chorus", Text: "la la"},
+		{Number: 4, Type: model.SectionVerse, Text: "second"},
+		{Number: 5, Type: model.SectionChorus, Label: "Chorus", Text: "la la"},
+		{Number: 6, Type: model.SectionBridge, Label: "Bridge", Text: "bridge"},
+		{Number: 7, Type: model.SectionChorus, Label: "Chorus x2", Text: "la la la"},
+		{Number: 8, Type: model.SectionChorus, Label: "Припев", Text: "la la"},
+	}
+	if song.VerseCount != len(all) {
+		t.Fatalf("VerseCount = %d, want %d", song.VerseCount, len(all))
+	}
+
+	tests := []struct {
+		name        string
+		sectionType string
+		collapse    bool
+		want        []model.Verse
+	}{
+		{"all sections", "", false, all},
+		{"chorus only", model.SectionChorus, false, []model.Verse{all[2], all[4], all[6], all[7]}},
+		{"collapsed", "", true, []model.Verse{all[0], all[1], all[2], all[3], all[5], all[6]}},
+		{"collapsed chorus only", model.SectionChorus, true, []model.Verse{all[2], all[6]}},
+		{"missing type", model.SectionOutro, false, []model.Verse{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verses, total, err := s.GetSongVerses(ctx, song.ID, tt.sectionType, tt.collapse, 100, 0)
+			if err != nil {
+				t.Fatalf("GetSongVerses: %v", err)
+			}
+			if total != len(tt.want) || len(verses) != len(tt.want) {
+				t.Fatalf("got %d verses of %d, want %d", len(verses), total, len(tt.want))
+			}
+			for i := range tt.want {
+				if verses[i] != tt.want[i] {
+					t.Fatalf("verse %d = %+v, want %+v", i, verses[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	verses, total, err := s.GetSongVerses(ctx, song.ID, model.SectionChorus, true, 1, 1)
+	if err != nil {
+		t.Fatalf("GetSongVerses: %v", err)
+	}
+	if total != 2 || len(verses) != 1 || verses[0] != all[6] {
+		t.Fatalf("second page of collapsed choruses = %+v of %d, want %+v of 2", verses, total, all[6])
+	}
+}
@@ -0,0 +1,156 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// findTag looks a tag up in the list of all tags.
+func findTag(t *testing.T, s storage.IStorage, name string) (model.Tag, bool) {
+	t.Helper()
+	tags, err := s.GetTags(context.Background(), 1000000, 0)
+	if err != nil {
+		t.Fatalf("GetTags: %v", err)
+	}
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag, true
+		}
+	}
+	return model.Tag{}, false
+}
+
+func testSongTags(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Nirvana"), Song: "Lithium", Link: "l"})
+	rock, grunge := unique("rock"), unique("grunge")
+
+	got, err := s.GetSongTags(ctx, song.ID)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("GetSongTags of a new song = %v, %v, want none", got, err)
+	}
+	if got, err = s.AddSongTags(ctx, song.ID, []string{rock, grunge}); err != nil || !slices.Equal(got, []string{grunge, rock}) {
+		t.Fatalf("AddSongTags = %v, %v, want %v", got, err, []string{grunge, rock})
+	}
+	// tags the song has already are kept once
+	if got, err = s.AddSongTags(ctx, song.ID, []string{rock}); err != nil || !slices.Equal(got, []string{grunge, rock}) {
+		t.Errorf("AddSongTags again = %v, %v, want %v", got, err, []string{grunge, rock})
+	}
+
+	if err = s.RemoveSongTag(ctx, song.ID, grunge); err != nil {
+		t.Fatalf("RemoveSongTag: %v", err)
+	}
+	if got, err = s.GetSongTags(ctx, song.ID); err != nil || !slices.Equal(got, []string{rock}) {
+		t.Errorf("GetSongTags after RemoveSongTag = %v, %v, want %v", got, err, []string{rock})
+	}
+	if err = s.RemoveSongTag(ctx, song.ID, grunge); !errors.Is(err, storage.ErrTagNotFound) {
+		t.Errorf("RemoveSongTag of a tag the song does not have: got %v, want storage.ErrTagNotFound", err)
+	}
+
+	missing := missingID(t, s)
+	if _, err = s.GetSongTags(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetSongTags of a missing song: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err = s.AddSongTags(ctx, missing, []string{rock}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("AddSongTags of a missing song: got %v, want sql.ErrNoRows", err)
+	}
+	if err = s.RemoveSongTag(ctx, missing, rock); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RemoveSongTag of a missing song: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testTags(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	rock, pop := unique("rock"), unique("pop")
+	first := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	second := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Resistance", Link: "l"})
+	gone := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Gone", Link: "l"})
+	for _, song := range []model.Song{first, second, gone} {
+		if _, err := s.AddSongTags(ctx, song.ID, []string{rock}); err != nil {
+			t.Fatalf("AddSongTags: %v", err)
+		}
+	}
+	if _, err := s.AddSongTags(ctx, first.ID, []string{pop}); err != nil {
+		t.Fatalf("AddSongTags: %v", err)
+	}
+
+	// songs in the trash are not counted
+	if err := s.DeleteSong(ctx, strconv.Itoa(gone.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if tag, ok := findTag(t, s, rock); !ok || tag.SongCount != 2 {
+		t.Errorf("GetTags lists %+v, want %q with 2 songs", tag, rock)
+	}
+	if tag, ok := findTag(t, s, pop); !ok || tag.SongCount != 1 {
+		t.Errorf("GetTags lists %+v, want %q with 1 song", tag, pop)
+	}
+
+	// the most used tags come first
+	tags, err := s.GetTags(ctx, 1000000, 0)
+	if err != nil {
+		t.Fatalf("GetTags: %v", err)
+	}
+	for i := 1; i < len(tags); i++ {
+		if tags[i].SongCount > tags[i-1].SongCount {
+			t.Fatalf("GetTags lists %+v after %+v", tags[i], tags[i-1])
+		}
+	}
+
+	// a tag no live song has is not listed
+	if err = s.RemoveSongTag(ctx, first.ID, pop); err != nil {
+		t.Fatalf("RemoveSongTag: %v", err)
+	}
+	if tag, ok := findTag(t, s, pop); ok {
+		t.Errorf("GetTags lists %+v, want no %q", tag, pop)
+	}
+}
+
+func testGetSongsTags(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	rock, nineties := unique("rock"), unique("90s")
+	both := mustAdd(t, s, model.Song{Group: unique("Nirvana"), Song: "Lithium", Link: "l"})
+	rockOnly := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Link: "l"})
+	ninetiesOnly := mustAdd(t, s, model.Song{Group: unique("Spice Girls"), Song: "Wannabe", Link: "l"})
+	mustAdd(t, s, model.Song{Group: unique("Adele"), Song: "Hello", Link: "l"})
+
+	tagged := []struct {
+		song model.Song
+		tags []string
+	}{
+		{both, []string{rock, nineties}},
+		{rockOnly, []string{rock}},
+		{ninetiesOnly, []string{nineties}},
+	}
+	for _, tt := range tagged {
+		if _, err := s.AddSongTags(ctx, tt.song.ID, tt.tags); err != nil {
+			t.Fatalf("AddSongTags: %v", err)
+		}
+	}
+
+	tests := []struct {
+		filter model.SongFilter
+		want   []int
+	}{
+		{model.SongFilter{Tags: []string{rock}}, []int{both.ID, rockOnly.ID}},
+		{model.SongFilter{Tags: []string{rock, nineties}}, []int{both.ID}},
+		{model.SongFilter{Tags: []string{rock, nineties}, TagMatch: model.TagMatchAll}, []int{both.ID}},
+		{model.SongFilter{Tags: []string{rock, nineties}, TagMatch: model.TagMatchAny}, []int{both.ID, rockOnly.ID, ninetiesOnly.ID}},
+		{model.SongFilter{Tags: []string{rock, nineties}, Song: "Uprising", TagMatch: model.TagMatchAny}, []int{rockOnly.ID}},
+		{model.SongFilter{Tags: []string{rock, unique("jazz")}}, []int{}},
+	}
+	for _, tt := range tests {
+		songs, total, err := s.GetSongs(ctx, tt.filter, nil, 100)
+		if err != nil {
+			t.Fatalf("GetSongs(%+v): %v", tt.filter, err)
+		}
+		if got := ids(songs); !equalIDs(got, tt.want) || total != len(tt.want) {
+			t.Errorf("GetSongs(%+v) = %v of %d, want %v", tt.filter, got, total, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"go_test_effective_mobile/internal/storage"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func findTrashed(t *testing.T, s storage.IStorage, id int) (model.TrashedSong, bool) {
+	t.Helper()
+	trash, err := s.GetTrash(context.Background(), 100, 0)
+	if err != nil {
+		t.Fatalf("GetTrash: %v", err)
+	}
+	for _, v := range trash {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return model.TrashedSong{}, false
+}
+
+func testTrash(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	song := mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Text: "one\n\ntwo", Link: "l"})
+	before := time.Now()
+	if err := s.DeleteSong(ctx, strconv.Itoa(song.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	if songs, _, err := s.GetSongs(ctx, model.SongFilter{Group: group}, nil, 10); err != nil || len(songs) != 0 {
+		t.Errorf("GetSongs after delete = %v, %v, want no songs", songs, err)
+	}
+	if _, err := s.GetSongByID(ctx, strconv.Itoa(song.ID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetSongByID of a trashed song error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.GetInfo(ctx, group, "Uprising", model.MatchExact); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetInfo of a trashed song error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.GetSongVerseByID(ctx, song.ID, 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetSongVerseByID of a trashed song error = %v, want sql.ErrNoRows", err)
+	}
+	if _, _, err := s.GetSongVerses(ctx, song.ID, "", false, 10, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetSongVerses of a trashed song error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.PatchSong(ctx, song.ID, 0, model.SongPatch{Link: ptr("x")}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("PatchSong of a trashed song error = %v, want sql.ErrNoRows", err)
+	}
+	if err := s.DeleteSong(ctx, strconv.Itoa(song.ID), 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second DeleteSong error = %v, want sql.ErrNoRows", err)
+	}
+
+	trashed, ok := findTrashed(t, s, song.ID)
+	if !ok {
+		t.Fatalf("song %d is not in the trash", song.ID)
+	}
+	if trashed.Song != song {
+		t.Errorf("trashed song = %+v, want %+v", trashed.Song, song)
+	}
+	if trashed.DeletedAt.Before(before.Add(-time.Second)) || trashed.DeletedAt.After(time.Now().Add(time.Second)) {
+		t.Errorf("deletedAt = %v, want about %v", trashed.DeletedAt, before)
+	}
+
+	restored, err := s.RestoreSong(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("RestoreSong: %v", err)
+	}
+	want := song
+	want.Version++
+	if restored != want {
+		t.Fatalf("RestoreSong = %+v, want %+v", restored, want)
+	}
+	if verse, err := s.GetSongVerseByID(ctx, song.ID, 2); err != nil || verse != "two" {
+		t.Errorf("verse 2 after restore = %q, %v, want \"two\"", verse, err)
+	}
+	if _, ok = findTrashed(t, s, song.ID); ok {
+		t.Errorf("restored song %d is still in the trash", song.ID)
+	}
+	if _, err = s.RestoreSong(ctx, song.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreSong of a live song error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testTrashNameReuse(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	group := unique("Muse")
+	old := mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Link: "old"})
+	if err := s.DeleteSong(ctx, strconv.Itoa(old.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	// a trashed song does not keep its name
+	current := mustAdd(t, s, model.Song{Group: group, Song: "Uprising", Link: "new"})
+	if current.ID == old.ID {
+		t.Fatalf("new song reused id %d", old.ID)
+	}
+	if _, err := s.RestoreSong(ctx, old.ID); !errors.Is(err, storage.ErrSongExists) {
+		t.Fatalf("RestoreSong over a taken name error = %v, want storage.ErrSongExists", err)
+	}
+	if _, err := s.RevertSong(ctx, old.ID, 1, 0); !errors.Is(err, storage.ErrSongExists) {
+		t.Fatalf("RevertSong over a taken name error = %v, want storage.ErrSongExists", err)
+	}
+
+	if err := s.DeleteSong(ctx, strconv.Itoa(current.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	restored, err := s.RestoreSong(ctx, old.ID)
+	if err != nil {
+		t.Fatalf("RestoreSong once the name is free: %v", err)
+	}
+	if restored.Link != "old" {
+		t.Fatalf("restored song = %+v, want the old one", restored)
+	}
+}
+
+func testPurgeTrash(t *testing.T, s storage.IStorage) {
+	ctx := context.Background()
+	song := mustAdd(t, s, model.Song{Group: unique("Muse"), Song: "Uprising", Text: "one", Link: "l"})
+	before := time.Now().Add(-time.Minute)
+	if err := s.DeleteSong(ctx, strconv.Itoa(song.ID), 0); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	if _, err := s.PurgeTrash(ctx, before); err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	if _, ok := findTrashed(t, s, song.ID); !ok {
+		t.Fatalf("song %d deleted after the purge cut-off was purged", song.ID)
+	}
+
+	purged, err := s.PurgeTrash(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	if purged < 1 {
+		t.Fatalf("PurgeTrash removed %d songs, want at least 1", purged)
+	}
+	if _, ok := findTrashed(t, s, song.ID); ok {
+		t.Fatalf("song %d is still in the trash after the purge", song.ID)
+	}
+	if _, err = s.RestoreSong(ctx, song.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("RestoreSong of a purged song error = %v, want sql.ErrNoRows", err)
+	}
+
+	// the history survives the purge and can bring the song back
+	revisions, err := s.GetSongRevisions(ctx, song.ID)
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("GetSongRevisions after purge = %d revisions, %v, want 2", len(revisions), err)
+	}
+	recreated, err := s.RevertSong(ctx, song.ID, 1, 0)
+	if err != nil {
+		t.Fatalf("RevertSong of a purged song: %v", err)
+	}
+	if recreated.ID != song.ID || recreated.Version != 2 || recreated.VerseCount != 1 {
+		t.Fatalf("recreated song = %+v, want id %d, version 2 and 1 verse", recreated, song.ID)
+	}
+}
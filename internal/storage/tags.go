@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"go_test_effective_mobile/internal/model"
+	"slices"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// taggedExpr matches the songs with all the tags or, with model.TagMatchAny, with one of them.
+func taggedExpr(tags []string, match string) squirrel.Sqlizer {
+	tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+	query := squirrel.Select("st.song_id").From("song_tags st").
+		Join("tags t ON t.id = st.tag_id").
+		Where(squirrel.Eq{"t.name": tags})
+	if match != model.TagMatchAny {
+		query = query.GroupBy("st.song_id").Having("COUNT(*) = ?", len(tags))
+	}
+	return squirrel.Expr("id IN (?)", query)
+}
+
+// GetTags lists the tags of live songs, the most used first.
+func (s *Storage) GetTags(ctx context.Context, limit, offset int) ([]model.Tag, error) {
+	s.logger.Debugw("Fetching tags", "limit", limit, "offset", offset)
+
+	sqlString, args, err := squirrel.Select("t.name", "COUNT(*) AS song_count").From("tags t").
+		Join("song_tags st ON st.tag_id = t.id").
+		Join("songs s ON s.id = st.song_id").
+		Where(squirrel.Eq{"s.deleted_at": nil}).
+		GroupBy("t.id", "t.name").
+		OrderBy("song_count DESC", s.sortExpr(model.SortGroup, "t.name")).
+		Limit(uint64(limit)).Offset(uint64(offset)).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]model.Tag, 0)
+	for rows.Next() {
+		var tag model.Tag
+		if err = rows.Scan(&tag.Name, &tag.SongCount); err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetSongTags returns the tags of a live song by name.
+func (s *Storage) GetSongTags(ctx context.Context, id int) ([]string, error) {
+	s.logger.Debug("Fetching tags of song:", id)
+
+	live, err := s.songExists(ctx, s.db, id)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	if !live {
+		return nil, sql.ErrNoRows
+	}
+	tags, err := s.songTags(ctx, s.db, id)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return tags, err
+}
+
+// AddSongTags tags a live song, adding the tags there are none of. It returns all the tags of the song.
+func (s *Storage) AddSongTags(ctx context.Context, id int, tags []string) ([]string, error) {
+	s.logger.Debugw("Tagging song", "id", id, "tags", tags)
+
+	var res []string
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		live, err := s.songExists(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !live {
+			return sql.ErrNoRows
+		}
+		for _, tag := range tags {
+			sqlString, args, err := squirrel.Insert("tags").Columns("name").Values(tag).
+				Suffix("ON CONFLICT (name) DO NOTHING").
+				PlaceholderFormat(s.placeholder).ToSql()
+			if err != nil {
+				return err
+			}
+			if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+				return err
+			}
+			sqlString, args, err = squirrel.Insert("song_tags").Columns("song_id", "tag_id").
+				Values(id, squirrel.Expr("(SELECT id FROM tags WHERE name = ?)", tag)).
+				Suffix("ON CONFLICT DO NOTHING").
+				PlaceholderFormat(s.placeholder).ToSql()
+			if err != nil {
+				return err
+			}
+			if _, err = tx.ExecContext(ctx, sqlString, args...); err != nil {
+				return err
+			}
+		}
+		res, err = s.songTags(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	return res, nil
+}
+
+// RemoveSongTag takes a tag off a live song, ErrTagNotFound is returned when the song does not have it.
+func (s *Storage) RemoveSongTag(ctx context.Context, id int, tag string) error {
+	s.logger.Debugw("Untagging song", "id", id, "tag", tag)
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		live, err := s.songExists(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !live {
+			return sql.ErrNoRows
+		}
+		sqlString, args, err := squirrel.Delete("song_tags").
+			Where(squirrel.Eq{"song_id": id}).
+			Where("tag_id IN (SELECT id FROM tags WHERE name = ?)", tag).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, sqlString, args...)
+		if err != nil {
+			return err
+		}
+		removed, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return ErrTagNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+	}
+	return err
+}
+
+// songTags reads the tag names of a song in order with q, a database or a transaction.
+func (s *Storage) songTags(ctx context.Context, q querier, id int) ([]string, error) {
+	sqlString, args, err := squirrel.Select("t.name").From("song_tags st").
+		Join("tags t ON t.id = st.tag_id").
+		Where(squirrel.Eq{"st.song_id": id}).
+		OrderBy(s.sortExpr(model.SortGroup, "t.name")).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := q.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]string, 0)
+	for rows.Next() {
+		var tag string
+		if err = rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
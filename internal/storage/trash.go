@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go_test_effective_mobile/internal/model"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// GetTrash returns the deleted songs that were not purged yet, most recently deleted first.
+func (s *Storage) GetTrash(ctx context.Context, limit, offset int) ([]model.TrashedSong, error) {
+	s.logger.Debugw("Fetching trash", "limit", limit, "offset", offset)
+
+	query := squirrel.Select(songColumns+", deleted_at").From("songs").Where(squirrel.NotEq{"deleted_at": nil}).
+		OrderBy("deleted_at DESC", "id").Limit(uint64(limit)).Offset(uint64(offset))
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	songs := make([]model.TrashedSong, 0)
+	for rows.Next() {
+		var song model.Song
+		var deletedAt time.Time
+		if err = rows.Scan(append(songDests(&song, songColumnList), &deletedAt)...); err != nil {
+			s.logger.Info(zap.Error(err))
+			return nil, err
+		}
+		songs = append(songs, model.TrashedSong{Song: song, DeletedAt: deletedAt})
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Info(zap.Error(err))
+		return nil, err
+	}
+	return songs, nil
+}
+
+// RestoreSong takes a song out of the trash as a new version. It fails with ErrSongExists
+// when another song with the same group and name was added in the meantime.
+func (s *Storage) RestoreSong(ctx context.Context, id int) (model.Song, error) {
+	s.logger.Debug("Restoring song by ID:", id)
+
+	query := squirrel.Update("songs").
+		Set("deleted_at", nil).
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{"id": id}).Where(squirrel.NotEq{"deleted_at": nil}).
+		Suffix("RETURNING " + songColumns)
+	sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Song{}, err
+	}
+	s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+	var restored model.Song
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		taken, err := s.trashedNameTaken(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrSongExists
+		}
+		row := tx.QueryRowContext(ctx, sqlString, args...)
+		if err = row.Scan(songDests(&restored, songColumnList)...); err != nil {
+			return err
+		}
+		return s.writeRevision(ctx, tx, model.RevisionRestore, restored)
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return model.Song{}, err
+	}
+	s.logger.Debug("Restored song:", restored)
+
+	return restored, nil
+}
+
+// PurgeTrash permanently removes the songs deleted before the given time and returns how many were removed.
+// Their revisions are kept, the covers of the songs removed no longer point at them. Artists left without
+// songs, albums and credits are removed along with them.
+func (s *Storage) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	s.logger.Debug("Purging songs deleted before:", before)
+
+	var purged int64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		err := s.rewriteSongs(ctx, tx, squirrel.Update("songs").
+			Set("cover_of", nil).
+			Where(squirrel.Expr("cover_of IN (SELECT id FROM songs WHERE deleted_at < ?)", before.UTC())))
+		if err != nil {
+			return err
+		}
+
+		// the credits of the songs go with them
+		artists, err := s.artistIDs(ctx, tx, squirrel.Select("artist_id").From("songs").
+			Where(squirrel.Lt{"deleted_at": before.UTC()}).
+			Suffix("UNION SELECT artist_id FROM song_artists WHERE song_id IN (SELECT id FROM songs WHERE deleted_at < ?)", before.UTC()))
+		if err != nil {
+			return err
+		}
+
+		query := squirrel.Delete("songs").Where(squirrel.Lt{"deleted_at": before.UTC()})
+		sqlString, args, err := query.PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("Generated SQL:", sqlString, "args:", args)
+
+		res, err := tx.ExecContext(ctx, sqlString, args...)
+		if err != nil {
+			return err
+		}
+		if purged, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		return s.deleteOrphanArtists(ctx, tx, artists)
+	})
+	if err != nil {
+		s.logger.Info(zap.Error(err))
+		return 0, err
+	}
+	s.logger.Debug("Purged songs:", purged)
+
+	return int(purged), nil
+}
+
+// nameTaken reports whether a song other than exceptID, and not in the trash, has the given group and name.
+func (s *Storage) nameTaken(ctx context.Context, tx *sql.Tx, group, song string, exceptID int) (bool, error) {
+	sqlString, args, err := squirrel.Select("count(*)").From("songs").
+		Where(squirrel.Eq{"group_name": group, "song": song, "deleted_at": nil}).
+		Where(squirrel.NotEq{"id": exceptID}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return false, err
+	}
+	var count int
+	err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&count)
+	return count > 0, err
+}
+
+// trashedNameTaken reports whether the group and name of a trashed song were taken by another song.
+func (s *Storage) trashedNameTaken(ctx context.Context, tx *sql.Tx, id int) (bool, error) {
+	sqlString, args, err := squirrel.Select("group_name", "song").From("songs").
+		Where(squirrel.Eq{"id": id}).Where(squirrel.NotEq{"deleted_at": nil}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return false, err
+	}
+	var group, song string
+	err = tx.QueryRowContext(ctx, sqlString, args...).Scan(&group, &song)
+	if errors.Is(err, sql.ErrNoRows) {
+		// not in the trash, the restore itself reports it
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return s.nameTaken(ctx, tx, group, song, id)
+}
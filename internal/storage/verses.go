@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"go_test_effective_mobile/internal/model"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var (
+	lineTrailingSpace = regexp.MustCompile(`[ \t]+\n`)
+	sectionHeader     = regexp.MustCompile(`^\s*\[([^\[\]]+)\]\s*$`)
+)
+
+// sectionPrefixes maps the beginning of a section label to its type, more specific prefixes go first.
+var sectionPrefixes = []struct {
+	prefix string
+	kind   string
+}{
+	{"pre-chorus", model.SectionPreChorus},
+	{"prechorus", model.SectionPreChorus},
+	{"pre chorus", model.SectionPreChorus},
+	{"предприпев", model.SectionPreChorus},
+	{"chorus", model.SectionChorus},
+	{"refrain", model.SectionChorus},
+	{"припев", model.SectionChorus},
+	{"verse", model.SectionVerse},
+	{"куплет", model.SectionVerse},
+	{"bridge", model.SectionBridge},
+	{"бридж", model.SectionBridge},
+	{"intro", model.SectionIntro},
+	{"вступление", model.SectionIntro},
+	{"outro", model.SectionOutro},
+	{"концовка", model.SectionOutro},
+	{"аутро", model.SectionOutro},
+	{"hook", model.SectionHook},
+}
+
+// splitVerses breaks lyrics into sections separated by blank lines or "[Chorus]"-style headers.
+// CRLF line endings and whitespace-only lines are treated the same as plain blank lines.
+// A header names the block that follows it, blocks without a header are plain verses.
+func splitVerses(text string) []model.Verse {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = lineTrailingSpace.ReplaceAllString(text+"\n", "\n")
+
+	var (
+		verses []model.Verse
+		label  string
+		lines  []string
+	)
+	flush := func() {
+		if len(lines) == 0 {
+			return
+		}
+		verses = append(verses, model.Verse{
+			Number: len(verses) + 1,
+			Type:   sectionType(label),
+			Label:  label,
+			Text:   strings.Join(lines, "\n"),
+		})
+		label, lines = "", nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			flush()
+			continue
+		}
+		if m := sectionHeader.FindStringSubmatch(line); m != nil {
+			flush()
+			label = strings.TrimSpace(m[1])
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return verses
+}
+
+// resplitStaleVerses splits the lyrics of the songs flagged verses_stale by the migrations again,
+// so that songs stored before section headers were parsed have the same verses as newly saved ones.
+func (s *Storage) resplitStaleVerses(ctx context.Context) error {
+	sqlString, args, err := squirrel.Select("id", "text").From("songs").Where(squirrel.Eq{"verses_stale": true}).
+		PlaceholderFormat(s.placeholder).ToSql()
+	if err != nil {
+		return err
+	}
+	rows, err := s.db.QueryContext(ctx, sqlString, args...)
+	if err != nil {
+		return err
+	}
+	// the songs are read up front, SQLite has a single connection for the reads and the writes
+	texts := make(map[int]string)
+	for rows.Next() {
+		var (
+			id   int
+			text sql.NullString
+		)
+		if err = rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return err
+		}
+		texts[id] = text.String
+	}
+	if err = rows.Close(); err != nil {
+		return err
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	s.logger.Infow("Splitting verses of songs stored by an older version", "songs", len(texts))
+	for id, text := range texts {
+		verses := splitVerses(text)
+		sqlString, args, err := squirrel.Update("songs").
+			Set("verse_count", len(verses)).
+			Set("verses_stale", false).
+			Where(squirrel.Eq{"id": id}).
+			PlaceholderFormat(s.placeholder).ToSql()
+		if err != nil {
+			return err
+		}
+		err = s.inTx(ctx, func(tx *sql.Tx) error {
+			if err := s.writeVerses(ctx, tx, id, verses); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, sqlString, args...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sectionType(label string) string {
+	if label == "" {
+		return model.SectionVerse
+	}
+	label = strings.ToLower(label)
+	for _, p := range sectionPrefixes {
+		if strings.HasPrefix(label, p.prefix) {
+			return p.kind
+		}
+	}
+	return model.SectionOther
+}
+
+// collapseRepeats drops choruses that repeat the text of an earlier chorus.
+func collapseRepeats(verses []model.Verse) []model.Verse {
+	seen := make(map[string]bool)
+	res := make([]model.Verse, 0, len(verses))
+	for _, v := range verses {
+		if v.Type == model.SectionChorus {
+			if seen[v.Text] {
+				continue
+			}
+			seen[v.Text] = true
+		}
+		res = append(res, v)
+	}
+	return res
+}